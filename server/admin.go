@@ -0,0 +1,120 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// AdminConfigPath is the path of the admin handler's listener
+// configuration resources.
+const AdminConfigPath = "/config"
+
+// NewAdminHandler returns an http.Handler with which the configuration
+// of the TestServer's listeners may be read and changed while the
+// server runs:
+//
+//	GET /config       the configuration of every listener, by ID
+//	PUT /config       changes the configuration of every listener
+//	GET /config/<id>  the configuration of the listener with the ID
+//	PUT /config/<id>  changes the configuration of the listener
+//
+// Configurations are JSON objects, as encoded by Config's MarshalJSON.
+// Keys omitted from a PUT request's body are left unchanged. PUT
+// requests respond with the resulting configurations, or status 400
+// if any would be invalid, in which case none are changed. Unknown
+// listener IDs result in status 404.
+func NewAdminHandler(ts *TestServer) http.Handler {
+	return &adminHandler{ts}
+}
+
+type adminHandler struct {
+	ts *TestServer
+}
+
+func (ah *adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var ids []string
+	switch {
+	case r.URL.Path == AdminConfigPath:
+		ids = ah.ts.listenerIDs
+
+	case strings.HasPrefix(r.URL.Path, AdminConfigPath+"/"):
+		id := strings.TrimPrefix(r.URL.Path, AdminConfigPath+"/")
+		if _, ok := ah.ts.configs[id]; !ok {
+			http.Error(w, fmt.Sprintf("unknown listener ID %q", id), http.StatusNotFound)
+			return
+		}
+		ids = []string{id}
+
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case "GET", "HEAD":
+	case "PUT":
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := ah.update(ids, body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var result interface{}
+	if r.URL.Path == AdminConfigPath {
+		configs := make(map[string]Config, len(ids))
+		for _, id := range ids {
+			configs[id] = *ah.ts.config(id)
+		}
+		result = configs
+	} else {
+		result = *ah.ts.config(ids[0])
+	}
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(data, '\n'))
+}
+
+// update applies the JSON-encoded changes to the configuration of each
+// of the listeners, provided the result is valid for all of them.
+func (ah *adminHandler) update(ids []string, changes []byte) error {
+	return ah.ts.updateConfigs(ids, func(c *Config) error {
+		if err := json.Unmarshal(changes, c); err != nil {
+			return fmt.Errorf("invalid configuration: %v", err)
+		}
+		return nil
+	})
+}
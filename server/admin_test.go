@@ -0,0 +1,106 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/turbinelabs/test/assert"
+)
+
+func admin(ts *TestServer, method, path, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	NewAdminHandler(ts).ServeHTTP(w, r)
+	return w
+}
+
+func TestAdminHandlerGet(t *testing.T) {
+	ts, err := NewTestServer([]string{"1234", "1235"}, 5.0, 0, 0, false, nil)
+	assert.Nil(t, err)
+
+	w := admin(ts, "GET", "/config", "")
+	assert.Equal(t, w.Code, 200)
+	assert.Equal(t, w.Header().Get("Content-Type"), "application/json")
+
+	configs := map[string]Config{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &configs))
+	assert.Equal(t, len(configs), 2)
+	assert.Equal(t, configs[":1234"].ErrorRate, 5.0)
+	assert.Equal(t, configs[":1235"].ErrorStatus, DefaultErrorStatus)
+
+	w = admin(ts, "GET", "/config/:1235", "")
+	assert.Equal(t, w.Code, 200)
+	var c Config
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &c))
+	assert.Equal(t, c.ErrorRate, 5.0)
+
+	w = admin(ts, "GET", "/config/:9999", "")
+	assert.Equal(t, w.Code, 404)
+	assert.StringContains(t, w.Body.String(), `unknown listener ID ":9999"`)
+
+	assert.Equal(t, admin(ts, "GET", "/other", "").Code, 404)
+
+	w = admin(ts, "POST", "/config", "")
+	assert.Equal(t, w.Code, 405)
+	assert.Equal(t, w.Header().Get("Allow"), "GET, HEAD, PUT")
+}
+
+func TestAdminHandlerPut(t *testing.T) {
+	ts, err := NewTestServer([]string{"1234", "1235"}, 5.0, 0, 0, false, nil)
+	assert.Nil(t, err)
+
+	w := admin(ts, "PUT", "/config/:1234", `{"error_rate": 50, "fault_rates": {"hang": 1}}`)
+	assert.Equal(t, w.Code, 200)
+	var c Config
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &c))
+	assert.Equal(t, c.ErrorRate, 50.0)
+	assert.MapEqual(t, c.FaultRates, map[Fault]float64{HangFault: 1})
+
+	c, err = ts.Config(":1234")
+	assert.Nil(t, err)
+	assert.Equal(t, c.ErrorRate, 50.0)
+	assert.Equal(t, c.ErrorStatus, DefaultErrorStatus)
+	c, err = ts.Config(":1235")
+	assert.Nil(t, err)
+	assert.Equal(t, c.ErrorRate, 5.0)
+
+	w = admin(ts, "PUT", "/config", `{"error_status": 500}`)
+	assert.Equal(t, w.Code, 200)
+	configs := map[string]Config{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &configs))
+	assert.Equal(t, configs[":1234"].ErrorStatus, 500)
+	assert.Equal(t, configs[":1234"].ErrorRate, 50.0)
+	assert.Equal(t, configs[":1235"].ErrorStatus, 500)
+
+	// invalid for one listener: none are changed
+	w = admin(ts, "PUT", "/config", `{"fault_rates": {"reset": 99.5}}`)
+	assert.Equal(t, w.Code, 400)
+	assert.StringContains(t, w.Body.String(), "listener :1234: total fault rate 100.5 exceeds 100")
+	c, err = ts.Config(":1235")
+	assert.Nil(t, err)
+	assert.Equal(t, len(c.FaultRates), 0)
+
+	w = admin(ts, "PUT", "/config/:1234", `{"error_rate":`)
+	assert.Equal(t, w.Code, 400)
+	assert.StringContains(t, w.Body.String(), "invalid configuration")
+
+	assert.Equal(t, admin(ts, "PUT", "/config/:9999", `{}`).Code, 404)
+}
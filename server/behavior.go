@@ -0,0 +1,231 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// A Behavior writes the TestServer's response to a request that the
+// server's error rate has not failed. The status code is the one
+// forced by the TestServerForceResponseCode query parameter, or zero,
+// in which case the Behavior chooses (usually 200).
+type Behavior func(w http.ResponseWriter, r *http.Request, status int)
+
+// RequestData describes a request: it is the data given to the
+// templates of TemplateBody and the JSON written by EchoJSON.
+type RequestData struct {
+	Method     string              `json:"method"`
+	Proto      string              `json:"proto"`
+	Host       string              `json:"host"`
+	Path       string              `json:"path"`
+	Query      map[string][]string `json:"query"`
+	Header     map[string][]string `json:"header"`
+	Body       string              `json:"body"`
+	RemoteAddr string              `json:"remoteAddr"`
+}
+
+// NewRequestData reads the request, including its body, into a
+// RequestData.
+func NewRequestData(r *http.Request) (RequestData, error) {
+	data := RequestData{
+		Method:     r.Method,
+		Proto:      r.Proto,
+		Host:       r.Host,
+		Path:       r.URL.Path,
+		Query:      r.URL.Query(),
+		Header:     r.Header,
+		RemoteAddr: r.RemoteAddr,
+	}
+
+	if r.Body != nil {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return RequestData{}, err
+		}
+		data.Body = string(body)
+	}
+
+	return data, nil
+}
+
+func statusOr(status, defaultStatus int) int {
+	if status == 0 {
+		return defaultStatus
+	}
+	return status
+}
+
+// DefaultBehavior is used for requests matching no route. It
+// responds "Hi there, I love <path>", followed, if the
+// TestServerEchoHeadersWithPrefix query parameter is given, by the
+// request headers with the given prefixes.
+func DefaultBehavior(w http.ResponseWriter, r *http.Request, status int) {
+	w.WriteHeader(statusOr(status, http.StatusOK))
+	fmt.Fprintf(w, "Hi there, I love %s\n", r.URL.Path[1:])
+
+	if prefixes, ok := r.URL.Query()[TestServerEchoHeadersWithPrefix]; ok {
+		if len(prefixes) >= 1 {
+			for k, v := range r.Header {
+				for _, prefix := range prefixes {
+					if strings.HasPrefix(strings.ToLower(k), strings.ToLower(prefix)) {
+						fmt.Fprintf(w, "Header %s = %s\n", k, strings.Join(v, ", "))
+					}
+				}
+			}
+		}
+	}
+}
+
+// StaticBody responds with the given body and content type.
+func StaticBody(contentType, body string) Behavior {
+	return func(w http.ResponseWriter, r *http.Request, status int) {
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(statusOr(status, http.StatusOK))
+		w.Write([]byte(body))
+	}
+}
+
+// FileBody responds with the contents of the named file, read anew for
+// each request, with a content type determined by its extension. If
+// the file cannot be read, the response is a 500 error.
+func FileBody(filename string) Behavior {
+	contentType := mime.TypeByExtension(filepath.Ext(filename))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	return func(w http.ResponseWriter, r *http.Request, status int) {
+		body, err := ioutil.ReadFile(filename)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(statusOr(status, http.StatusOK))
+		w.Write(body)
+	}
+}
+
+// TemplateBody responds with the given text/template, executed with
+// the request's RequestData, and content type. Returns an error if the
+// template cannot be parsed. If the template cannot be executed, the
+// response is a 500 error.
+func TemplateBody(contentType, text string) (Behavior, error) {
+	tmpl, err := template.New("body").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(w http.ResponseWriter, r *http.Request, status int) {
+		data, err := NewRequestData(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		body := &bytes.Buffer{}
+		if err := tmpl.Execute(body, data); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(statusOr(status, http.StatusOK))
+		w.Write(body.Bytes())
+	}, nil
+}
+
+// EchoJSON responds with the request's RequestData as JSON.
+func EchoJSON(w http.ResponseWriter, r *http.Request, status int) {
+	data, err := NewRequestData(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	body, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusOr(status, http.StatusOK))
+	w.Write(append(body, '\n'))
+}
+
+// Redirect redirects to the given URL, with the given status code
+// (e.g. 302) unless another is forced.
+func Redirect(url string, code int) Behavior {
+	return func(w http.ResponseWriter, r *http.Request, status int) {
+		http.Redirect(w, r, url, statusOr(status, code))
+	}
+}
+
+// Chunked responds with the given chunks of the body, flushing each to
+// the client and waiting the given interval before the next, so that
+// the body is streamed (using chunked transfer encoding, for HTTP/1.1
+// clients). Streaming stops if the client goes away.
+func Chunked(contentType string, chunks []string, interval time.Duration) Behavior {
+	return func(w http.ResponseWriter, r *http.Request, status int) {
+		flusher, _ := w.(http.Flusher)
+
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(statusOr(status, http.StatusOK))
+		for i, chunk := range chunks {
+			if i > 0 && interval > 0 {
+				select {
+				case <-time.After(interval):
+				case <-r.Context().Done():
+					return
+				}
+			}
+
+			w.Write([]byte(chunk))
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// WithStatus modifies a Behavior to respond with the given status
+// code, unless another is forced.
+func WithStatus(code int, b Behavior) Behavior {
+	return func(w http.ResponseWriter, r *http.Request, status int) {
+		b(w, r, statusOr(status, code))
+	}
+}
+
+// WithHeader modifies a Behavior to add the given response header.
+func WithHeader(name, value string, b Behavior) Behavior {
+	return func(w http.ResponseWriter, r *http.Request, status int) {
+		w.Header().Add(name, value)
+		b(w, r, status)
+	}
+}
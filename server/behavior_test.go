@@ -0,0 +1,162 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/turbinelabs/test/assert"
+	"github.com/turbinelabs/test/tempfile"
+)
+
+func respond(b Behavior, r *http.Request, status int) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	b(w, r, status)
+	return w
+}
+
+func TestStaticBody(t *testing.T) {
+	b := StaticBody("text/plain", "hello")
+
+	w := respond(b, httptest.NewRequest("GET", "/foo", nil), 0)
+	assert.Equal(t, w.Code, 200)
+	assert.Equal(t, w.Header().Get("Content-Type"), "text/plain")
+	assert.Equal(t, w.Body.String(), "hello")
+
+	w = respond(b, httptest.NewRequest("GET", "/foo", nil), 418)
+	assert.Equal(t, w.Code, 418)
+	assert.Equal(t, w.Body.String(), "hello")
+}
+
+func TestFileBody(t *testing.T) {
+	dir := tempfile.TempDir(t, "server-file-body")
+	defer dir.Cleanup()
+
+	filename := filepath.Join(dir.Path(), "body.json")
+	assert.Nil(t, ioutil.WriteFile(filename, []byte(`{"a":1}`), 0644))
+
+	b := FileBody(filename)
+	w := respond(b, httptest.NewRequest("GET", "/foo", nil), 0)
+	assert.Equal(t, w.Code, 200)
+	assert.Equal(t, w.Header().Get("Content-Type"), "application/json")
+	assert.Equal(t, w.Body.String(), `{"a":1}`)
+
+	// the file is read for each request
+	assert.Nil(t, ioutil.WriteFile(filename, []byte(`{"a":2}`), 0644))
+	w = respond(b, httptest.NewRequest("GET", "/foo", nil), 0)
+	assert.Equal(t, w.Body.String(), `{"a":2}`)
+
+	w = respond(FileBody(filepath.Join(dir.Path(), "missing")), httptest.NewRequest("GET", "/", nil), 0)
+	assert.Equal(t, w.Code, 500)
+	assert.Equal(t, w.Header().Get("Content-Type"), "text/plain; charset=utf-8")
+}
+
+func TestTemplateBody(t *testing.T) {
+	b, err := TemplateBody(
+		"text/plain",
+		`{{.Method}} {{.Path}} q={{index .Query "q" 0}} h={{index .Header "X-Foo" 0}} {{.Body}}`,
+	)
+	assert.Nil(t, err)
+
+	r := httptest.NewRequest("POST", "/foo?q=bar", strings.NewReader("payload"))
+	r.Header.Set("X-Foo", "baz")
+	w := respond(b, r, 0)
+	assert.Equal(t, w.Code, 200)
+	assert.Equal(t, w.Body.String(), "POST /foo q=bar h=baz payload")
+
+	// execution errors
+	b, err = TemplateBody("text/plain", "{{index .Query \"q\" 0}}")
+	assert.Nil(t, err)
+	w = respond(b, httptest.NewRequest("GET", "/foo", nil), 0)
+	assert.Equal(t, w.Code, 500)
+
+	_, err = TemplateBody("text/plain", "{{.Method")
+	assert.NonNil(t, err)
+}
+
+func TestEchoJSON(t *testing.T) {
+	r := httptest.NewRequest("PUT", "http://example.com/foo?a=1&a=2", strings.NewReader("body"))
+	r.Header.Set("X-Foo", "bar")
+
+	w := respond(EchoJSON, r, 201)
+	assert.Equal(t, w.Code, 201)
+	assert.Equal(t, w.Header().Get("Content-Type"), "application/json")
+
+	var data RequestData
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &data))
+	assert.DeepEqual(t, data, RequestData{
+		Method:     "PUT",
+		Proto:      "HTTP/1.1",
+		Host:       "example.com",
+		Path:       "/foo",
+		Query:      map[string][]string{"a": {"1", "2"}},
+		Header:     map[string][]string{"X-Foo": {"bar"}},
+		Body:       "body",
+		RemoteAddr: "192.0.2.1:1234",
+	})
+}
+
+func TestRedirect(t *testing.T) {
+	b := Redirect("/elsewhere", http.StatusFound)
+
+	w := respond(b, httptest.NewRequest("GET", "/foo", nil), 0)
+	assert.Equal(t, w.Code, http.StatusFound)
+	assert.Equal(t, w.Header().Get("Location"), "/elsewhere")
+
+	w = respond(b, httptest.NewRequest("GET", "/foo", nil), http.StatusMovedPermanently)
+	assert.Equal(t, w.Code, http.StatusMovedPermanently)
+}
+
+func TestChunked(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Chunked("text/plain", []string{"a", "b", "c"}, 10*time.Millisecond)(w, r, 0)
+	}))
+	defer server.Close()
+
+	start := time.Now()
+	resp, err := http.Get(server.URL)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, resp.StatusCode, 200)
+	assert.ArrayEqual(t, resp.TransferEncoding, []string{"chunked"})
+
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, string(body), "abc")
+	assert.GreaterThanEqual(t, time.Since(start), 20*time.Millisecond)
+}
+
+func TestWithStatusAndHeader(t *testing.T) {
+	b := WithHeader("X-Foo", "bar", WithStatus(202, StaticBody("text/plain", "ok")))
+
+	w := respond(b, httptest.NewRequest("GET", "/foo", nil), 0)
+	assert.Equal(t, w.Code, 202)
+	assert.Equal(t, w.Header().Get("X-Foo"), "bar")
+	assert.Equal(t, w.Body.String(), "ok")
+
+	// a forced status wins
+	w = respond(b, httptest.NewRequest("GET", "/foo", nil), 500)
+	assert.Equal(t, w.Code, 500)
+}
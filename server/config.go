@@ -0,0 +1,288 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Config is the latency and fault configuration of one of a
+// TestServer's listeners.
+type Config struct {
+	// ErrorRate is the percentage of requests answered with
+	// ErrorStatus.
+	ErrorRate float64
+
+	// ErrorStatus is the status code of error responses.
+	ErrorStatus int
+
+	// LatencyMean and LatencyStdDev describe the normal distribution
+	// of the latency added to each request.
+	LatencyMean   time.Duration
+	LatencyStdDev time.Duration
+
+	// FaultRates is the percentage of successful responses into
+	// which each Fault is injected.
+	FaultRates map[Fault]float64
+
+	// DripDuration is the time over which a DripFault sends the
+	// response body.
+	DripDuration time.Duration
+}
+
+// configJSON is the JSON form of a Config. Durations are expressed in
+// milliseconds, and faults by name.
+type configJSON struct {
+	ErrorRate       float64            `json:"error_rate"`
+	ErrorStatus     int                `json:"error_status"`
+	LatencyMeanMs   float64            `json:"latency_mean_ms"`
+	LatencyStdDevMs float64            `json:"latency_stddev_ms"`
+	FaultRates      map[string]float64 `json:"fault_rates"`
+	DripDurationMs  float64            `json:"drip_duration_ms"`
+}
+
+func toMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func fromMs(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// MarshalJSON encodes the Config as a JSON object, e.g.:
+//
+//	{
+//	  "error_rate": 1,
+//	  "error_status": 503,
+//	  "latency_mean_ms": 4,
+//	  "latency_stddev_ms": 1,
+//	  "fault_rates": {"reset": 0.5, "drip": 2},
+//	  "drip_duration_ms": 1000
+//	}
+func (c Config) MarshalJSON() ([]byte, error) {
+	cj := configJSON{
+		ErrorRate:       c.ErrorRate,
+		ErrorStatus:     c.ErrorStatus,
+		LatencyMeanMs:   toMs(c.LatencyMean),
+		LatencyStdDevMs: toMs(c.LatencyStdDev),
+		FaultRates:      map[string]float64{},
+		DripDurationMs:  toMs(c.DripDuration),
+	}
+	for f, rate := range c.FaultRates {
+		cj.FaultRates[f.String()] = rate
+	}
+	return json.Marshal(cj)
+}
+
+// UnmarshalJSON decodes a JSON object, as produced by MarshalJSON,
+// into the Config. Keys missing from the object, including fault
+// names missing from its fault rates, leave the Config's existing
+// values unchanged.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	cj := configJSON{
+		ErrorRate:       c.ErrorRate,
+		ErrorStatus:     c.ErrorStatus,
+		LatencyMeanMs:   toMs(c.LatencyMean),
+		LatencyStdDevMs: toMs(c.LatencyStdDev),
+		DripDurationMs:  toMs(c.DripDuration),
+	}
+	if err := json.Unmarshal(data, &cj); err != nil {
+		return err
+	}
+
+	faultRates := make(map[Fault]float64, len(c.FaultRates))
+	for f, rate := range c.FaultRates {
+		faultRates[f] = rate
+	}
+
+	names := make([]string, 0, len(cj.FaultRates))
+	for name := range cj.FaultRates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f, err := ParseFault(name)
+		if err != nil {
+			return err
+		}
+		faultRates[f] = cj.FaultRates[name]
+	}
+
+	*c = Config{
+		ErrorRate:     cj.ErrorRate,
+		ErrorStatus:   cj.ErrorStatus,
+		LatencyMean:   fromMs(cj.LatencyMeanMs),
+		LatencyStdDev: fromMs(cj.LatencyStdDevMs),
+		FaultRates:    faultRates,
+		DripDuration:  fromMs(cj.DripDurationMs),
+	}
+	return nil
+}
+
+// Validate returns an error describing the first problem found with
+// the Config, if any.
+func (c Config) Validate() error {
+	if c.ErrorRate < 0 || c.ErrorRate > 100 {
+		return fmt.Errorf("error rate must be between 0 and 100")
+	}
+	if err := checkErrorStatus(c.ErrorStatus); err != nil {
+		return err
+	}
+	if c.LatencyMean < 0 || c.LatencyStdDev < 0 {
+		return fmt.Errorf("latency must not be negative")
+	}
+	if err := checkFaultRates(c.FaultRates); err != nil {
+		return err
+	}
+	return checkDripDuration(c.DripDuration)
+}
+
+func (c Config) clone() *Config {
+	cc := c
+	cc.FaultRates = make(map[Fault]float64, len(c.FaultRates))
+	for f, rate := range c.FaultRates {
+		cc.FaultRates[f] = rate
+	}
+	return &cc
+}
+
+// defaultConfig returns the configuration with which the TestServer
+// was created.
+func (ts *TestServer) defaultConfig() *Config {
+	return &Config{
+		ErrorRate:     ts.errorRate,
+		ErrorStatus:   ts.errorStatus,
+		LatencyMean:   ts.latencyMean,
+		LatencyStdDev: ts.latencyStdDev,
+		FaultRates:    ts.faultRates,
+		DripDuration:  ts.dripDuration,
+	}
+}
+
+// config returns the current configuration of the listener with the
+// given ID, which must not be modified. Until the listener's
+// configuration is set, or the server is started, it is the server's
+// default configuration.
+func (ts *TestServer) config(listenerID string) *Config {
+	if v := ts.configs[listenerID]; v != nil {
+		if c, ok := v.Load().(*Config); ok {
+			return c
+		}
+	}
+	return ts.defaultConfig()
+}
+
+// initConfigs sets the configuration of each listener not yet
+// configured to the server's default configuration.
+func (ts *TestServer) initConfigs() {
+	ts.configMutex.Lock()
+	defer ts.configMutex.Unlock()
+
+	for _, v := range ts.configs {
+		if v.Load() == nil {
+			v.Store(ts.defaultConfig().clone())
+		}
+	}
+	ts.started = true
+}
+
+// Config returns the current configuration of the listener with the
+// given ID. It is safe to call while the server is running.
+func (ts *TestServer) Config(listenerID string) (Config, error) {
+	if _, ok := ts.configs[listenerID]; !ok {
+		return Config{}, fmt.Errorf("unknown listener ID %q", listenerID)
+	}
+	return *ts.config(listenerID).clone(), nil
+}
+
+// SetConfig replaces the configuration of the listener with the given
+// ID. It is safe to call while the server is running: each request is
+// handled using either the old or the new configuration. Before
+// ServeAsync, the TestServer's other setters only change the
+// configuration of listeners that have not been configured by
+// SetConfig. After, they change the configuration of every listener.
+func (ts *TestServer) SetConfig(listenerID string, c Config) error {
+	return ts.updateConfigs([]string{listenerID}, func(current *Config) error {
+		*current = *c.clone()
+		return nil
+	})
+}
+
+// updateDefaultConfig applies f to a copy of the server's default
+// configuration and, if f succeeds, makes the result the new default.
+// Once the server is started, f is instead applied to the
+// configuration of every listener, as by updateConfigs.
+func (ts *TestServer) updateDefaultConfig(f func(*Config) error) error {
+	ts.configMutex.Lock()
+	defer ts.configMutex.Unlock()
+
+	if ts.started {
+		return ts.updateConfigsLocked(ts.listenerIDs, f)
+	}
+
+	c := ts.defaultConfig().clone()
+	if err := f(c); err != nil {
+		return err
+	}
+
+	ts.errorRate = c.ErrorRate
+	ts.errorStatus = c.ErrorStatus
+	ts.latencyMean = c.LatencyMean
+	ts.latencyStdDev = c.LatencyStdDev
+	ts.faultRates = c.FaultRates
+	ts.dripDuration = c.DripDuration
+	return nil
+}
+
+// updateConfigs replaces the configuration of each of the listeners
+// with the given IDs with the result of applying f to a copy of its
+// current configuration, provided every result is valid. Otherwise,
+// none are changed.
+func (ts *TestServer) updateConfigs(listenerIDs []string, f func(*Config) error) error {
+	ts.configMutex.Lock()
+	defer ts.configMutex.Unlock()
+
+	return ts.updateConfigsLocked(listenerIDs, f)
+}
+
+// updateConfigsLocked is updateConfigs, for callers holding the
+// configMutex.
+func (ts *TestServer) updateConfigsLocked(listenerIDs []string, f func(*Config) error) error {
+	updated := make([]*Config, len(listenerIDs))
+	for i, id := range listenerIDs {
+		if _, ok := ts.configs[id]; !ok {
+			return fmt.Errorf("unknown listener ID %q", id)
+		}
+
+		c := ts.config(id).clone()
+		if err := f(c); err != nil {
+			return fmt.Errorf("listener %s: %v", id, err)
+		}
+		if err := c.Validate(); err != nil {
+			return fmt.Errorf("listener %s: %v", id, err)
+		}
+		updated[i] = c
+	}
+
+	for i, id := range listenerIDs {
+		ts.configs[id].Store(updated[i])
+	}
+	return nil
+}
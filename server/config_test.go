@@ -0,0 +1,215 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/turbinelabs/test/assert"
+)
+
+func TestConfigJSON(t *testing.T) {
+	c := Config{
+		ErrorRate:     1.5,
+		ErrorStatus:   503,
+		LatencyMean:   4 * time.Millisecond,
+		LatencyStdDev: 500 * time.Microsecond,
+		FaultRates:    map[Fault]float64{ResetFault: 0.5, DripFault: 2},
+		DripDuration:  time.Second,
+	}
+
+	data, err := json.Marshal(c)
+	assert.Nil(t, err)
+	assert.Equal(
+		t,
+		string(data),
+		`{"error_rate":1.5,"error_status":503,"latency_mean_ms":4,"latency_stddev_ms":0.5,`+
+			`"fault_rates":{"drip":2,"reset":0.5},"drip_duration_ms":1000}`,
+	)
+
+	var decoded Config
+	assert.Nil(t, json.Unmarshal(data, &decoded))
+	assert.DeepEqual(t, decoded, c)
+
+	// missing keys are unchanged
+	assert.Nil(t, json.Unmarshal([]byte(`{"error_rate":50,"fault_rates":{"hang":1}}`), &decoded))
+	assert.Equal(t, decoded.ErrorRate, 50.0)
+	assert.Equal(t, decoded.LatencyMean, 4*time.Millisecond)
+	assert.MapEqual(
+		t,
+		decoded.FaultRates,
+		map[Fault]float64{ResetFault: 0.5, DripFault: 2, HangFault: 1},
+	)
+	assert.MapEqual(t, c.FaultRates, map[Fault]float64{ResetFault: 0.5, DripFault: 2})
+
+	assert.ErrorContains(
+		t,
+		json.Unmarshal([]byte(`{"fault_rates":{"explode":1}}`), &decoded),
+		`unknown fault "explode"`,
+	)
+	assert.NonNil(t, json.Unmarshal([]byte(`{"error_rate":"x"}`), &decoded))
+}
+
+func TestConfigValidate(t *testing.T) {
+	valid := Config{ErrorStatus: 503, DripDuration: time.Second}
+	assert.Nil(t, valid.Validate())
+
+	c := valid
+	c.ErrorRate = 101
+	assert.ErrorContains(t, c.Validate(), "error rate must be between 0 and 100")
+
+	c = valid
+	c.ErrorStatus = 200
+	assert.ErrorContains(t, c.Validate(), "status code 200: out of range")
+
+	c = valid
+	c.LatencyStdDev = -1
+	assert.ErrorContains(t, c.Validate(), "latency must not be negative")
+
+	c = valid
+	c.FaultRates = map[Fault]float64{HangFault: 60, ResetFault: 60}
+	assert.ErrorContains(t, c.Validate(), "total fault rate 120 exceeds 100")
+
+	c = valid
+	c.DripDuration = 0
+	assert.ErrorContains(t, c.Validate(), "must be positive")
+}
+
+func TestSetConfig(t *testing.T) {
+	ts, err := NewTestServerWithDynamicPorts([]string{"a", "b"}, 10.0, 0, 0, false, nil)
+	assert.Nil(t, err)
+
+	_, err = ts.Config("c")
+	assert.ErrorContains(t, err, `unknown listener ID "c"`)
+	assert.ErrorContains(t, ts.SetConfig("c", Config{}), `unknown listener ID "c"`)
+
+	c, err := ts.Config("a")
+	assert.Nil(t, err)
+	assert.Equal(t, c.ErrorRate, 10.0)
+	assert.Equal(t, c.ErrorStatus, DefaultErrorStatus)
+
+	c.ErrorRate = 100
+	c.ErrorStatus = 500
+	assert.Nil(t, ts.SetConfig("a", c))
+	assert.Nil(t, ts.SetErrorStatus(599))
+
+	c.ErrorStatus = 0
+	assert.ErrorContains(t, ts.SetConfig("a", c), "out of range")
+
+	tsc := ts.ServeAsync()
+	defer tsc.Stop()
+
+	// the setter changes only listeners not already configured
+	c, err = ts.Config("a")
+	assert.Nil(t, err)
+	assert.Equal(t, c.ErrorStatus, 500)
+	c, err = ts.Config("b")
+	assert.Nil(t, err)
+	assert.Equal(t, c.ErrorStatus, 599)
+
+	get := func(id string) int {
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/", tsc.IDPortMap()[id]))
+		if !assert.Nil(t, err) {
+			return 0
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, get("a"), 500)
+
+	c.ErrorRate = 0
+	assert.Nil(t, ts.SetConfig("a", c))
+	assert.Equal(t, get("a"), 200)
+}
+
+func TestSettersWhileServing(t *testing.T) {
+	ts, err := NewTestServerWithDynamicPorts([]string{"a", "b"}, 100.0, 0, 0, false, nil)
+	assert.Nil(t, err)
+
+	c, err := ts.Config("a")
+	assert.Nil(t, err)
+	c.ErrorStatus = 500
+	assert.Nil(t, ts.SetConfig("a", c))
+
+	tsc := ts.ServeAsync()
+	defer tsc.Stop()
+
+	get := func(id string) int {
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/", tsc.IDPortMap()[id]))
+		if !assert.Nil(t, err) {
+			return 0
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, get("a"), 500)
+	assert.Equal(t, get("b"), DefaultErrorStatus)
+
+	// once serving, the setters change every listener
+	assert.Nil(t, ts.SetErrorStatus(418))
+	assert.Equal(t, get("a"), 418)
+	assert.Equal(t, get("b"), 418)
+
+	assert.Nil(t, ts.SetDripDuration(2*time.Second))
+	assert.Nil(t, ts.SetFaultRate(DripFault, 60))
+	assert.ErrorContains(t, ts.SetFaultRate(HangFault, 50), "total fault rate 110 exceeds 100")
+
+	for _, id := range []string{"a", "b"} {
+		c, err := ts.Config(id)
+		assert.Nil(t, err)
+		assert.Equal(t, c.DripDuration, 2*time.Second)
+		assert.DeepEqual(t, c.FaultRates, map[Fault]float64{DripFault: 60})
+	}
+}
+
+func TestSetConfigWhileServing(t *testing.T) {
+	ts, err := NewTestServerWithDynamicPorts([]string{"a"}, 0.0, 0, 0, false, nil)
+	assert.Nil(t, err)
+
+	tsc := ts.ServeAsync()
+	defer tsc.Stop()
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/", tsc.IDPortMap()["a"])
+
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			resp, err := http.Get(url)
+			if assert.Nil(t, err) {
+				resp.Body.Close()
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			c, _ := ts.Config("a")
+			c.ErrorRate = float64(i % 2)
+			assert.Nil(t, ts.SetConfig("a", c))
+		}
+	}()
+	wg.Wait()
+}
@@ -0,0 +1,309 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultDripDuration is the time over which a DripFault sends the
+// response body.
+const DefaultDripDuration = time.Second
+
+// A Fault is a way for the TestServer to misbehave while responding to
+// a request, other than by returning an error status.
+type Fault int
+
+const (
+	// NoFault responds normally.
+	NoFault Fault = iota
+
+	// ResetFault sends the response headers and half of the body,
+	// then resets the TCP connection.
+	ResetFault
+
+	// HangFault accepts the request but never responds. The request
+	// is abandoned when the client goes away.
+	HangFault
+
+	// TruncateFault sends the response headers, with the body's full
+	// Content-Length, and half of the body, then closes the
+	// connection.
+	TruncateFault
+
+	// DripFault sends the response body one byte at a time, spread
+	// evenly over the drip duration.
+	DripFault
+
+	// ContentLengthFault sends the whole response body with a
+	// Content-Length of half its length, then closes the connection.
+	ContentLengthFault
+)
+
+// faults lists the faults that may be injected, in the order their
+// rates are applied.
+var faults = []Fault{ResetFault, HangFault, TruncateFault, DripFault, ContentLengthFault}
+
+var faultNames = map[Fault]string{
+	NoFault:            "none",
+	ResetFault:         "reset",
+	HangFault:          "hang",
+	TruncateFault:      "truncate",
+	DripFault:          "drip",
+	ContentLengthFault: "content-length",
+}
+
+func (f Fault) String() string {
+	if name, ok := faultNames[f]; ok {
+		return name
+	}
+	return fmt.Sprintf("Fault(%d)", int(f))
+}
+
+// ParseFault returns the Fault with the given name, as used in the
+// TestServerForceFault query parameter: one of "none", "reset",
+// "hang", "truncate", "drip" or "content-length".
+func ParseFault(name string) (Fault, error) {
+	for f, n := range faultNames {
+		if n == name {
+			return f, nil
+		}
+	}
+
+	names := make([]string, 0, len(faultNames))
+	for _, f := range append([]Fault{NoFault}, faults...) {
+		names = append(names, faultNames[f])
+	}
+	return NoFault, fmt.Errorf(
+		"unknown fault %q: must be one of %s",
+		name,
+		strings.Join(names, ", "),
+	)
+}
+
+// SetFaultRate configures the rate at which the server injects the
+// given fault into its responses. The rate is expressed as a
+// percentage and must be between 0 and 100, inclusive. A request
+// receives at most one fault, so the total of the fault rates may not
+// exceed 100. Faults apply only to requests that succeed, given the
+// server's error rate.
+func (ts *TestServer) SetFaultRate(f Fault, rate float64) error {
+	return ts.updateDefaultConfig(func(c *Config) error {
+		c.FaultRates[f] = rate
+		return checkFaultRates(c.FaultRates)
+	})
+}
+
+func checkFaultRates(faultRates map[Fault]float64) error {
+	total := 0.0
+	for f, rate := range faultRates {
+		if _, ok := faultNames[f]; !ok || f == NoFault {
+			return fmt.Errorf("fault %s: cannot be injected", f)
+		}
+		if rate < 0 || rate > 100 {
+			return fmt.Errorf("fault %s: rate must be between 0 and 100", f)
+		}
+		total += rate
+	}
+	if total > 100 {
+		return fmt.Errorf("total fault rate %g exceeds 100", total)
+	}
+	return nil
+}
+
+// SetDripDuration configures the time over which a DripFault sends
+// the response body. It defaults to DefaultDripDuration.
+func (ts *TestServer) SetDripDuration(d time.Duration) error {
+	return ts.updateDefaultConfig(func(c *Config) error {
+		c.DripDuration = d
+		return checkDripDuration(d)
+	})
+}
+
+func checkDripDuration(d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("drip duration %s: must be positive", d)
+	}
+	return nil
+}
+
+// fault selects the fault to inject into the response to the request:
+// the one forced by the TestServerForceFault query parameter, if any,
+// or else one chosen according to the given fault rates.
+func (ts *TestServer) fault(r *http.Request, faultRates map[Fault]float64) Fault {
+	if va, ok := r.URL.Query()[TestServerForceFault]; ok && len(va) >= 1 {
+		f, err := ParseFault(va[0])
+		if err == nil {
+			return f
+		}
+		ts.logf("Could not parse %v arg %q", TestServerForceFault, va[0])
+	}
+
+	if len(faultRates) == 0 {
+		return NoFault
+	}
+
+	x := ts.rand.Float64() * 100.0
+	for _, f := range faults {
+		rate := faultRates[f]
+		if x < rate {
+			return f
+		}
+		x -= rate
+	}
+	return NoFault
+}
+
+// injectFault responds to the request using the Behavior, but with
+// the given fault. The Behavior's response is buffered, so that it
+// may be sent to the client incorrectly. Dripped bodies are sent over
+// the given duration.
+func (ts *TestServer) injectFault(
+	f Fault,
+	w http.ResponseWriter,
+	r *http.Request,
+	b Behavior,
+	status int,
+	dripDuration time.Duration,
+) {
+	if f == HangFault {
+		<-r.Context().Done()
+		return
+	}
+
+	resp := &bufferedResponse{header: cloneHeader(w.Header())}
+	b(resp, r, status)
+	if resp.status == 0 {
+		resp.status = http.StatusOK
+	}
+
+	if f == DripFault {
+		drip(w, r, resp, dripDuration)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		// HTTP/2 connections cannot be hijacked: aborting the handler
+		// resets the stream instead.
+		panic(http.ErrAbortHandler)
+	}
+
+	conn, buf, err := hijacker.Hijack()
+	if err != nil {
+		ts.logf("failed to hijack connection for %s fault: %v", f, err)
+		return
+	}
+	defer func() { conn.Close() }()
+
+	body := resp.body.Bytes()
+	length, sent := len(body), len(body)/2
+	if f == ContentLengthFault {
+		length, sent = len(body)/2, len(body)
+	}
+
+	resp.header.Del("Transfer-Encoding")
+	resp.header.Set("Content-Length", strconv.Itoa(length))
+	resp.header.Set("Connection", "close")
+
+	fmt.Fprintf(buf, "HTTP/1.1 %d %s\r\n", resp.status, http.StatusText(resp.status))
+	resp.header.Write(buf)
+	buf.WriteString("\r\n")
+	buf.Write(body[:sent])
+	if err := buf.Flush(); err != nil {
+		ts.verbosef("failed to write %s fault response: %v", f, err)
+	}
+
+	if f == ResetFault {
+		// discarding unsent data on close causes a reset. Closing the
+		// connection underlying a TLS connection skips the close_notify
+		// alert, which the client would otherwise see first.
+		conn = ts.underlyingConn(conn)
+		if lingerer, ok := conn.(interface{ SetLinger(int) error }); ok {
+			lingerer.SetLinger(0)
+		}
+	}
+}
+
+// drip sends the buffered response, flushing the body to the client
+// one byte at a time, spread evenly over the given duration. Dripping
+// stops if the client goes away.
+func drip(w http.ResponseWriter, r *http.Request, resp *bufferedResponse, d time.Duration) {
+	if d <= 0 {
+		d = DefaultDripDuration
+	}
+
+	flusher, _ := w.(http.Flusher)
+
+	body := resp.body.Bytes()
+	for name, values := range resp.header {
+		w.Header()[name] = values
+	}
+	w.Header().Del("Transfer-Encoding")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(resp.status)
+	if len(body) == 0 {
+		return
+	}
+
+	interval := d / time.Duration(len(body))
+	for i := range body {
+		if i > 0 && interval > 0 {
+			select {
+			case <-time.After(interval):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		w.Write(body[i : i+1])
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// bufferedResponse is an http.ResponseWriter that holds the response
+// in memory.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (br *bufferedResponse) Header() http.Header {
+	return br.header
+}
+
+func (br *bufferedResponse) WriteHeader(status int) {
+	if br.status == 0 {
+		br.status = status
+	}
+}
+
+func (br *bufferedResponse) Write(b []byte) (int, error) {
+	br.WriteHeader(http.StatusOK)
+	return br.body.Write(b)
+}
+
+// Flush does nothing: the response is sent once the Behavior returns.
+func (br *bufferedResponse) Flush() {}
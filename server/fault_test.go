@@ -0,0 +1,133 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/turbinelabs/test/assert"
+)
+
+func TestParseFault(t *testing.T) {
+	for _, f := range append([]Fault{NoFault}, faults...) {
+		parsed, err := ParseFault(f.String())
+		assert.Nil(t, err)
+		assert.Equal(t, parsed, f)
+	}
+
+	_, err := ParseFault("explode")
+	assert.ErrorContains(t, err, `unknown fault "explode": must be one of none, reset, hang`)
+	assert.Equal(t, Fault(99).String(), "Fault(99)")
+}
+
+func TestSetFaultRate(t *testing.T) {
+	ts := &TestServer{}
+	assert.ErrorContains(t, ts.SetFaultRate(NoFault, 1), "cannot be injected")
+	assert.ErrorContains(t, ts.SetFaultRate(Fault(99), 1), "cannot be injected")
+	assert.ErrorContains(t, ts.SetFaultRate(HangFault, -1), "between 0 and 100")
+	assert.ErrorContains(t, ts.SetFaultRate(HangFault, 101), "between 0 and 100")
+
+	assert.Nil(t, ts.SetFaultRate(HangFault, 60))
+	assert.ErrorContains(t, ts.SetFaultRate(DripFault, 50), "total fault rate 110 exceeds 100")
+	assert.Nil(t, ts.SetFaultRate(DripFault, 40))
+	assert.Nil(t, ts.SetFaultRate(HangFault, 10))
+	assert.MapEqual(t, ts.faultRates, map[Fault]float64{HangFault: 10, DripFault: 40})
+
+	assert.ErrorContains(t, ts.SetDripDuration(0), "must be positive")
+	assert.Nil(t, ts.SetDripDuration(time.Minute))
+	assert.Equal(t, ts.dripDuration, time.Minute)
+}
+
+func TestFaultSelection(t *testing.T) {
+	ts := &TestServer{rand: rand.New(rand.NewSource(1))}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	assert.Equal(t, ts.fault(r, ts.faultRates), NoFault)
+
+	assert.Nil(t, ts.SetFaultRate(ResetFault, 50))
+	assert.Nil(t, ts.SetFaultRate(TruncateFault, 50))
+	seen := map[Fault]int{}
+	for i := 0; i < 100; i++ {
+		seen[ts.fault(r, ts.faultRates)]++
+	}
+	assert.Equal(t, len(seen), 2)
+	assert.NotEqual(t, seen[ResetFault], 0)
+	assert.NotEqual(t, seen[TruncateFault], 0)
+
+	r = httptest.NewRequest("GET", "/?force-fault=none", nil)
+	assert.Equal(t, ts.fault(r, ts.faultRates), NoFault)
+	r = httptest.NewRequest("GET", "/?force-fault=drip", nil)
+	assert.Equal(t, ts.fault(r, ts.faultRates), DripFault)
+	r = httptest.NewRequest("GET", "/?force-fault=bogus", nil)
+	assert.NotEqual(t, ts.fault(r, ts.faultRates), NoFault)
+}
+
+func getWithFault(t *testing.T, ts *TestServer, fault string) (*http.Response, []byte, error) {
+	server := httptest.NewServer(TestHandler{ts, "id"})
+	defer server.Close()
+
+	client := &http.Client{Timeout: time.Second}
+	resp, err := client.Get(fmt.Sprintf("%s/faulty?%s=%s", server.URL, TestServerForceFault, fault))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	return resp, body, err
+}
+
+func TestInjectFault(t *testing.T) {
+	ts := &TestServer{errorStatus: DefaultErrorStatus, rand: rand.New(rand.NewSource(1))}
+	want := "Hi there, I love faulty\n"
+
+	resp, body, err := getWithFault(t, ts, "none")
+	assert.Nil(t, err)
+	assert.Equal(t, string(body), want)
+
+	resp, body, err = getWithFault(t, ts, "truncate")
+	assert.ErrorContains(t, err, "unexpected EOF")
+	assert.Equal(t, resp.StatusCode, 200)
+	assert.Equal(t, resp.Header.Get(TestServerIDHeader), "id")
+	assert.Equal(t, string(body), want[:len(want)/2])
+
+	resp, body, err = getWithFault(t, ts, "content-length")
+	assert.Nil(t, err)
+	assert.Equal(t, resp.ContentLength, int64(len(want)/2))
+	assert.Equal(t, string(body), want[:len(want)/2])
+
+	_, body, err = getWithFault(t, ts, "reset")
+	assert.NonNil(t, err)
+	assert.True(t, len(body) < len(want))
+
+	_, _, err = getWithFault(t, ts, "hang")
+	assert.ErrorContains(t, err, "Client.Timeout exceeded")
+
+	assert.Nil(t, ts.SetDripDuration(50*time.Millisecond))
+	start := time.Now()
+	resp, body, err = getWithFault(t, ts, "drip")
+	assert.Nil(t, err)
+	assert.Equal(t, resp.ContentLength, int64(len(want)))
+	assert.Equal(t, string(body), want)
+	assert.GreaterThanEqual(t, time.Since(start), 40*time.Millisecond)
+}
@@ -0,0 +1,28 @@
+//go:build !go1.24
+// +build !go1.24
+
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import "net/http"
+
+// h2cSupported is true if listeners without TLS can accept HTTP/2
+// with prior knowledge. Before Go 1.24, net/http cannot.
+const h2cSupported = false
+
+func enableH2C(server *http.Server) {}
@@ -17,11 +17,9 @@ limitations under the License.
 package server
 
 import (
-	"fmt"
 	"log"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 )
 
@@ -40,6 +38,12 @@ const (
 	// given prefix (the parameter's value) to be echoed in the
 	// response. May be repeated to render multiple prefixes.
 	TestServerEchoHeadersWithPrefix = "echo-headers-with-prefix"
+
+	// TestServerForceFault is the name of an HTTP query parameter
+	// naming the Fault to inject into the response to the request
+	// (see ParseFault), regardless of the server's fault rates. The
+	// value "none" prevents any fault.
+	TestServerForceFault = "force-fault"
 )
 
 // TestHandler is an http.Handler that implements the TestServer.
@@ -52,14 +56,19 @@ func (th TestHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set(TestServerIDHeader, th.ID)
 
 	ts := th.TestServer
+	if ts.requests.enabled() {
+		ts.requests.record(newRecordedRequest(th.ID, r))
+	}
+
 	if ts.handlerOverride != nil {
 		ts.handlerOverride(w, r)
 		return
 	}
 
-	if ts.latencyMean > 0 {
+	config := ts.config(th.ID)
+	if config.LatencyMean > 0 {
 		normLatency := time.Duration(
-			(ts.rand.NormFloat64() * float64(ts.latencyStdDev)) + float64(ts.latencyMean),
+			(ts.rand.NormFloat64() * float64(config.LatencyStdDev)) + float64(config.LatencyMean),
 		)
 		if normLatency > 0 {
 			ts.verbosef("sleeping for %s", normLatency)
@@ -67,7 +76,7 @@ func (th TestHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	respCode := -1
+	respCode := 0
 	values := r.URL.Query()
 
 	if va, ok := values[TestServerForceResponseCode]; ok {
@@ -81,32 +90,18 @@ func (th TestHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	respCodeOrDefault := func(defaultRespCode int) int {
-		if respCode == -1 {
-			return defaultRespCode
-		}
-		return respCode
+	if config.ErrorRate > 0.0 && ts.rand.Float64()*100.0 < config.ErrorRate {
+		ts.verbosef("failing")
+		http.Error(w, "oopsies", statusOr(respCode, config.ErrorStatus))
+		return
 	}
 
-	if ts.errorRate > 0.0 && ts.rand.Float64()*100.0 < ts.errorRate {
-		ts.verbosef("failing")
-		http.Error(w, "oopsies", respCodeOrDefault(ts.errorStatus))
+	if f := ts.fault(r, config.FaultRates); f != NoFault {
+		ts.verbosef("injecting %s fault", f)
+		ts.injectFault(f, w, r, ts.behavior(r), respCode, config.DripDuration)
 		return
 	}
 
 	ts.verbosef("succeeding")
-	w.WriteHeader(respCodeOrDefault(200))
-	fmt.Fprintf(w, "Hi there, I love %s\n", r.URL.Path[1:])
-
-	if prefixes, ok := values[TestServerEchoHeadersWithPrefix]; ok {
-		if len(prefixes) >= 1 {
-			for k, v := range r.Header {
-				for _, prefix := range prefixes {
-					if strings.HasPrefix(strings.ToLower(k), strings.ToLower(prefix)) {
-						fmt.Fprintf(w, "Header %s = %s\n", k, strings.Join(v, ", "))
-					}
-				}
-			}
-		}
-	}
+	ts.behavior(r)(w, r, respCode)
 }
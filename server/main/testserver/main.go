@@ -18,12 +18,15 @@ package main
 
 import (
 	"bytes"
+	"crypto/x509"
 	"errors"
 	"flag"
 	"fmt"
 	"go/doc"
 	"io"
 	"io/ioutil"
+	"net"
+	"net/http"
 	"os"
 	"strings"
 	"time"
@@ -51,7 +54,29 @@ response.
 If the query parameter "` + server.TestServerEchoHeadersWithPrefix + `" is set,
 then success responses contain additional payload data displaying the name and
 value of each HTTP request header that starts with the specified prefix. The
-query parameter may be repeated to display headers with multiple prefixes.`
+query parameter may be repeated to display headers with multiple prefixes.
+
+Successful responses may also be faulty, at the configured fault rates: the
+connection may be reset mid-response ("reset"), the request may never be
+answered ("hang"), the connection may be closed part way through the body
+("truncate"), the body may be sent a byte at a time over the drip duration
+("drip"), or the Content-Length may be wrong ("content-length"). If the query
+parameter "` + server.TestServerForceFault + `" is set to one of these names, that
+fault is injected regardless of the fault rates. The value "none" prevents any
+fault.
+
+With --tls, the listeners serve TLS using a certificate issued by a newly
+generated certificate authority, whose certificate may be written to a file for
+clients to trust. With --http2, the listeners also accept HTTP/2: negotiated via
+ALPN with TLS, or with prior knowledge (h2c) without. Serving h2c requires a
+testserver built with Go 1.24 or later.
+
+With --admin-port, an admin listener on the given port allows the error rate,
+error status, latency and fault configuration of each listener to be read and
+changed while the server runs. "GET ` + server.AdminConfigPath + `" returns the configuration of every
+listener, by listener ID, as a JSON object, and "GET ` + server.AdminConfigPath + `/<listener ID>" that
+of a single listener. A PUT request to either changes the configuration, setting
+the keys present in the JSON object in the request body.`
 )
 
 var (
@@ -61,6 +86,17 @@ var (
 	errorRate       float64
 	latencyMeanMs   float64
 	latencyStdDevMs float64
+	resetRate       float64
+	hangRate        float64
+	truncateRate    float64
+	dripRate        float64
+	dripDurationMs  float64
+	contentLenRate  float64
+	useTLS          bool
+	useHTTP2        bool
+	caFile          string
+	clientCAFile    string
+	adminPort       string
 	verbose         bool
 	help            bool
 
@@ -92,7 +128,7 @@ func wrap(indent int, s string, args ...interface{}) {
 	indentStr := strings.Repeat(" ", indent)
 	buffer := &bytes.Buffer{}
 	doc.ToText(buffer, str, indentStr, "", 80)
-	stderr(buffer.String())
+	stderr("%s", buffer.String())
 }
 
 func usage(fs *flag.FlagSet, err error) int {
@@ -105,7 +141,7 @@ func usage(fs *flag.FlagSet, err error) int {
 		if u.text == "" {
 			stderr("\n")
 		} else {
-			wrap(u.indent, u.text+"\n")
+			wrap(u.indent, "%s\n", u.text)
 		}
 	}
 
@@ -119,7 +155,7 @@ func usage(fs *flag.FlagSet, err error) int {
 		if f.DefValue != "" {
 			wrap(8, "(default: %s)", f.DefValue)
 		}
-		wrap(8, usage)
+		wrap(8, "%s", usage)
 		stderr("\n")
 	})
 
@@ -163,6 +199,83 @@ func configureFlags() *flag.FlagSet {
 		"The test server's standard deviation from its mean latency in `milliseconds`.",
 	)
 
+	fs.Float64Var(
+		&resetRate,
+		"reset-rate",
+		0.0,
+		"The `percentage` of responses for which the test server resets the connection after sending half of the response.",
+	)
+
+	fs.Float64Var(
+		&hangRate,
+		"hang-rate",
+		0.0,
+		"The `percentage` of requests the test server accepts but never answers.",
+	)
+
+	fs.Float64Var(
+		&truncateRate,
+		"truncate-rate",
+		0.0,
+		"The `percentage` of responses for which the test server closes the connection after sending half of the body.",
+	)
+
+	fs.Float64Var(
+		&dripRate,
+		"drip-rate",
+		0.0,
+		"The `percentage` of responses whose body the test server sends one byte at a time, over the drip duration.",
+	)
+
+	fs.Float64Var(
+		&dripDurationMs,
+		"drip-duration",
+		float64(server.DefaultDripDuration/time.Millisecond),
+		"The time in `milliseconds` over which the test server drips a response body.",
+	)
+
+	fs.Float64Var(
+		&contentLenRate,
+		"content-length-rate",
+		0.0,
+		"The `percentage` of responses the test server sends with a Content-Length shorter than the body.",
+	)
+
+	fs.BoolVar(
+		&useTLS,
+		"tls",
+		false,
+		"Serve TLS, using a certificate issued by a generated certificate authority.",
+	)
+
+	fs.BoolVar(
+		&useHTTP2,
+		"http2",
+		false,
+		"Accept HTTP/2 as well as HTTP/1.1: via ALPN with TLS, otherwise as h2c.",
+	)
+
+	fs.StringVar(
+		&caFile,
+		"ca-file",
+		"",
+		"Write the generated certificate authority's PEM-encoded certificate to this `file`. Requires --tls.",
+	)
+
+	fs.StringVar(
+		&clientCAFile,
+		"client-ca-file",
+		"",
+		"Require client certificates issued by one of the PEM-encoded certificate authorities in this `file`. Requires --tls.",
+	)
+
+	fs.StringVar(
+		&adminPort,
+		"admin-port",
+		"",
+		"The `port` of an admin listener with which the test server's configuration may be changed while it runs. Disabled if empty.",
+	)
+
 	fs.BoolVar(
 		&verbose,
 		"verbose",
@@ -231,11 +344,92 @@ func run(fs *flag.FlagSet) int {
 		return usage(fs, err)
 	}
 
+	faultRates := []struct {
+		fault server.Fault
+		rate  float64
+	}{
+		{server.ResetFault, resetRate},
+		{server.HangFault, hangRate},
+		{server.TruncateFault, truncateRate},
+		{server.DripFault, dripRate},
+		{server.ContentLengthFault, contentLenRate},
+	}
+	for _, fr := range faultRates {
+		if err := ts.SetFaultRate(fr.fault, fr.rate); err != nil {
+			return usage(fs, err)
+		}
+	}
+
+	if err := ts.SetDripDuration(
+		time.Duration(dripDurationMs * float64(time.Millisecond)),
+	); err != nil {
+		return usage(fs, err)
+	}
+
+	if err := configureTLS(ts); err != nil {
+		return usage(fs, err)
+	}
+
+	if useHTTP2 {
+		if err := ts.EnableHTTP2(); err != nil {
+			return usage(fs, err)
+		}
+	}
+
+	tsc := ts.ServeAsync()
+	if caFile != "" {
+		err := ioutil.WriteFile(caFile, tsc.CertificateAuthority().CertificatePEM, 0644)
+		if err != nil {
+			tsc.Stop()
+			return usage(fs, err)
+		}
+	}
+
+	if adminPort != "" {
+		listener, err := net.Listen("tcp", ":"+adminPort)
+		if err != nil {
+			tsc.Stop()
+			return usage(fs, err)
+		}
+		go http.Serve(listener, server.NewAdminHandler(ts))
+	}
+
 	// Blocks forever since there's no way to stop the server.
-	ts.ServeAsync().Await()
+	tsc.Await()
 	return 0
 }
 
+func configureTLS(ts *server.TestServer) error {
+	if !useTLS {
+		if caFile != "" || clientCAFile != "" {
+			return errors.New("--ca-file and --client-ca-file require --tls")
+		}
+		return nil
+	}
+
+	if err := ts.EnableTLS(); err != nil {
+		return err
+	}
+
+	if clientCAFile != "" {
+		data, err := ioutil.ReadFile(clientCAFile)
+		if err != nil {
+			return err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return fmt.Errorf("%s: no PEM-encoded certificates found", clientCAFile)
+		}
+
+		if err := ts.RequireClientCertificates(pool); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func main() {
 	fs := configureFlags()
 	rc := parseFlags(fs, os.Args[1:])
@@ -39,6 +39,17 @@ func resetFlags() {
 	errorRate = 0
 	latencyMeanMs = 0
 	latencyStdDevMs = 0
+	resetRate = 0
+	hangRate = 0
+	truncateRate = 0
+	dripRate = 0
+	dripDurationMs = 0
+	contentLenRate = 0
+	useTLS = false
+	useHTTP2 = false
+	caFile = ""
+	clientCAFile = ""
+	adminPort = ""
 	verbose = false
 	help = false
 }
@@ -122,3 +133,41 @@ func TestRunError(t *testing.T) {
 
 	assert.StringContains(t, output, "error rate must be between")
 }
+
+func TestRunFaultRateError(t *testing.T) {
+	output := withTrappedOutput(func() {
+		testRun(t, []string{"--hang-rate=60", "--drip-rate=60"}, func(rc int) {
+			assert.NotEqual(t, rc, 0)
+		})
+	})
+
+	assert.StringContains(t, output, "total fault rate 120 exceeds 100")
+}
+
+func TestRunDripDurationError(t *testing.T) {
+	output := withTrappedOutput(func() {
+		testRun(t, []string{"--drip-duration=0"}, func(rc int) {
+			assert.NotEqual(t, rc, 0)
+		})
+	})
+
+	assert.StringContains(t, output, "drip duration 0s: must be positive")
+}
+
+func TestRunTLSError(t *testing.T) {
+	output := withTrappedOutput(func() {
+		testRun(t, []string{"--ca-file=ca.pem"}, func(rc int) {
+			assert.NotEqual(t, rc, 0)
+		})
+	})
+
+	assert.StringContains(t, output, "require --tls")
+
+	output = withTrappedOutput(func() {
+		testRun(t, []string{"--tls", "--client-ca-file=/nonexistent/ca.pem"}, func(rc int) {
+			assert.NotEqual(t, rc, 0)
+		})
+	})
+
+	assert.StringContains(t, output, "/nonexistent/ca.pem")
+}
@@ -0,0 +1,304 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/turbinelabs/test/matcher"
+)
+
+// RecordedRequest is a request received by one of a TestServer's
+// listeners.
+type RecordedRequest struct {
+	ListenerID string
+	Time       time.Time
+	Method     string
+	Proto      string
+	Path       string
+	RawQuery   string
+	Header     http.Header
+	Body       []byte
+}
+
+// newRecordedRequest records the request, reading its body and
+// replacing it so that the request's Behavior may read it again.
+func newRecordedRequest(listenerID string, r *http.Request) RecordedRequest {
+	rr := RecordedRequest{
+		ListenerID: listenerID,
+		Time:       time.Now(),
+		Method:     r.Method,
+		Proto:      r.Proto,
+		Path:       r.URL.Path,
+		RawQuery:   r.URL.RawQuery,
+		Header:     cloneHeader(r.Header),
+	}
+
+	if r.Body != nil {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			log.Printf("failed to record request body: %v", err)
+		}
+		r.Body.Close()
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		rr.Body = body
+	}
+
+	return rr
+}
+
+func cloneHeader(h http.Header) http.Header {
+	c := make(http.Header, len(h))
+	for name, values := range h {
+		c[name] = append([]string(nil), values...)
+	}
+	return c
+}
+
+// A requestLog holds the most recently received requests. It is safe
+// for concurrent use. A nil requestLog records nothing.
+type requestLog struct {
+	mutex    sync.Mutex
+	size     int
+	requests []RecordedRequest
+	changed  chan struct{} // closed and replaced when requests changes
+}
+
+func newRequestLog(size int) *requestLog {
+	return &requestLog{size: size, changed: make(chan struct{})}
+}
+
+func (l *requestLog) setSize(size int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.size = size
+	l.trim()
+}
+
+// trim discards the oldest requests in excess of the log's size. The
+// caller must hold the mutex.
+func (l *requestLog) trim() {
+	if excess := len(l.requests) - l.size; excess > 0 {
+		n := copy(l.requests, l.requests[excess:])
+		for i := n; i < len(l.requests); i++ {
+			l.requests[i] = RecordedRequest{}
+		}
+		l.requests = l.requests[:n]
+	}
+}
+
+func (l *requestLog) enabled() bool {
+	if l == nil {
+		return false
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.size > 0
+}
+
+func (l *requestLog) record(rr RecordedRequest) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.requests = append(l.requests, rr)
+	l.trim()
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+func (l *requestLog) clear() {
+	if l == nil {
+		return
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.requests = nil
+}
+
+// matching returns the recorded requests matching m (or all of them,
+// if m is nil), and a channel closed when another request is
+// recorded.
+func (l *requestLog) matching(m matcher.Matcher) ([]RecordedRequest, <-chan struct{}) {
+	if l == nil {
+		return nil, nil
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	result := []RecordedRequest{}
+	for _, rr := range l.requests {
+		if m == nil || m.Matches(rr) {
+			result = append(result, rr)
+		}
+	}
+	return result, l.changed
+}
+
+// SetRequestLogSize configures the number of requests the TestServer
+// records, after which the oldest requests are discarded. Requests are
+// not recorded until a positive size is set, and a size of 0 disables
+// recording again. Recording reads each request's body in full before
+// the request is handled.
+func (ts *TestServer) SetRequestLogSize(size int) error {
+	if size < 0 {
+		return fmt.Errorf("request log size %d: must not be negative", size)
+	}
+
+	if ts.requests == nil {
+		ts.requests = newRequestLog(size)
+	} else {
+		ts.requests.setSize(size)
+	}
+	return nil
+}
+
+// Requests returns the requests recorded by the TestServer's
+// listeners, oldest first.
+func (tsc *TestServerControl) Requests() []RecordedRequest {
+	requests, _ := tsc.requests.matching(nil)
+	return requests
+}
+
+// MatchingRequests returns the recorded requests matching m, oldest
+// first. The matcher is passed each RecordedRequest in turn.
+func (tsc *TestServerControl) MatchingRequests(m matcher.Matcher) []RecordedRequest {
+	requests, _ := tsc.requests.matching(m)
+	return requests
+}
+
+// ClearRequests discards the recorded requests.
+func (tsc *TestServerControl) ClearRequests() {
+	tsc.requests.clear()
+}
+
+// AwaitRequests waits until at least n recorded requests match m, and
+// returns them. If the timeout expires first, the requests matched so
+// far are returned with an error. Only requests still held in the
+// request log are considered.
+func (tsc *TestServerControl) AwaitRequests(
+	m matcher.Matcher,
+	n int,
+	timeout time.Duration,
+) ([]RecordedRequest, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		requests, changed := tsc.requests.matching(m)
+		if len(requests) >= n {
+			return requests, nil
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return requests, fmt.Errorf(
+				"timed out after %s waiting for %d requests that %s: got %d",
+				timeout,
+				n,
+				describe(m),
+				len(requests),
+			)
+		}
+	}
+}
+
+func describe(m matcher.Matcher) string {
+	if m == nil {
+		return "match anything"
+	}
+	return m.String()
+}
+
+// RequestMethod matches a RecordedRequest with the given method.
+func RequestMethod(method string) matcher.Matcher {
+	return &requestMatcher{
+		func(rr RecordedRequest) bool { return rr.Method == method },
+		fmt.Sprintf("is a request with method %s", method),
+	}
+}
+
+// RequestPath matches a RecordedRequest with the given path.
+func RequestPath(path string) matcher.Matcher {
+	return &requestMatcher{
+		func(rr RecordedRequest) bool { return rr.Path == path },
+		fmt.Sprintf("is a request with path %q", path),
+	}
+}
+
+// RequestHeader matches a RecordedRequest with a header of the given
+// name having the given value among its values.
+func RequestHeader(name, value string) matcher.Matcher {
+	name = http.CanonicalHeaderKey(name)
+	return &requestMatcher{
+		func(rr RecordedRequest) bool {
+			for _, v := range rr.Header[name] {
+				if v == value {
+					return true
+				}
+			}
+			return false
+		},
+		fmt.Sprintf("is a request with header %s: %q", name, value),
+	}
+}
+
+// RequestBody matches a RecordedRequest with the given body.
+func RequestBody(body []byte) matcher.Matcher {
+	return &requestMatcher{
+		func(rr RecordedRequest) bool { return bytes.Equal(rr.Body, body) },
+		fmt.Sprintf("is a request with body %q", string(body)),
+	}
+}
+
+// RequestListener matches a RecordedRequest received by the listener
+// with the given ID.
+func RequestListener(listenerID string) matcher.Matcher {
+	return &requestMatcher{
+		func(rr RecordedRequest) bool { return rr.ListenerID == listenerID },
+		fmt.Sprintf("is a request received by listener %q", listenerID),
+	}
+}
+
+type requestMatcher struct {
+	test        func(RecordedRequest) bool
+	description string
+}
+
+func (rm *requestMatcher) Matches(i interface{}) bool {
+	switch rr := i.(type) {
+	case RecordedRequest:
+		return rm.test(rr)
+	case *RecordedRequest:
+		return rr != nil && rm.test(*rr)
+	}
+	return false
+}
+
+func (rm *requestMatcher) String() string {
+	return rm.description
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/turbinelabs/test/assert"
+	"github.com/turbinelabs/test/matcher"
+)
+
+func TestHandlerRecordsRequests(t *testing.T) {
+	ts := &TestServer{errorStatus: DefaultErrorStatus, requests: newRequestLog(10)}
+	ts.AddRoute("/echo", EchoJSON)
+	th := TestHandler{TestServer: ts, ID: "handler-id"}
+
+	r := httptest.NewRequest("POST", "/echo?x=1", strings.NewReader("payload"))
+	r.Header.Set("X-Test", "yes")
+	w := httptest.NewRecorder()
+
+	before := time.Now()
+	th.ServeHTTP(w, r)
+
+	// the behavior still sees the body
+	assert.StringContains(t, w.Body.String(), `"body": "payload"`)
+
+	tsc := &TestServerControl{requests: ts.requests}
+	requests := tsc.Requests()
+	if assert.Equal(t, len(requests), 1) {
+		rr := requests[0]
+		assert.Equal(t, rr.ListenerID, "handler-id")
+		assert.Equal(t, rr.Method, "POST")
+		assert.Equal(t, rr.Path, "/echo")
+		assert.Equal(t, rr.RawQuery, "x=1")
+		assert.Equal(t, rr.Header.Get("X-Test"), "yes")
+		assert.Equal(t, string(rr.Body), "payload")
+		assert.False(t, rr.Time.Before(before))
+	}
+
+	tsc.ClearRequests()
+	assert.Equal(t, len(tsc.Requests()), 0)
+}
+
+func TestHandlerWithoutRequestLog(t *testing.T) {
+	th := TestHandler{TestServer: &TestServer{errorStatus: DefaultErrorStatus}, ID: "id"}
+	th.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	tsc := &TestServerControl{}
+	assert.Equal(t, len(tsc.Requests()), 0)
+	tsc.ClearRequests()
+}
+
+func TestRequestLogSize(t *testing.T) {
+	ts := &TestServer{}
+	assert.ErrorContains(t, ts.SetRequestLogSize(-1), "must not be negative")
+	assert.Nil(t, ts.SetRequestLogSize(3))
+
+	for i := 0; i < 5; i++ {
+		ts.requests.record(RecordedRequest{Path: fmt.Sprintf("/%d", i)})
+	}
+
+	tsc := &TestServerControl{requests: ts.requests}
+	paths := []string{}
+	for _, rr := range tsc.Requests() {
+		paths = append(paths, rr.Path)
+	}
+	assert.ArrayEqual(t, paths, []string{"/2", "/3", "/4"})
+
+	assert.Nil(t, ts.SetRequestLogSize(1))
+	assert.Equal(t, tsc.Requests()[0].Path, "/4")
+
+	assert.Nil(t, ts.SetRequestLogSize(0))
+	assert.False(t, ts.requests.enabled())
+	assert.Equal(t, len(tsc.Requests()), 0)
+}
+
+func TestRequestMatchers(t *testing.T) {
+	rr := RecordedRequest{
+		ListenerID: "a",
+		Method:     "PUT",
+		Path:       "/x",
+		Header:     http.Header{"X-Test": {"1", "2"}},
+		Body:       []byte("body"),
+	}
+
+	assert.True(t, RequestMethod("PUT").Matches(rr))
+	assert.False(t, RequestMethod("GET").Matches(rr))
+	assert.True(t, RequestPath("/x").Matches(&rr))
+	assert.False(t, RequestPath("/y").Matches(rr))
+	assert.True(t, RequestHeader("x-test", "2").Matches(rr))
+	assert.False(t, RequestHeader("x-test", "3").Matches(rr))
+	assert.True(t, RequestBody([]byte("body")).Matches(rr))
+	assert.False(t, RequestBody(nil).Matches(rr))
+	assert.True(t, RequestListener("a").Matches(rr))
+	assert.False(t, RequestListener("b").Matches(rr))
+	assert.False(t, RequestMethod("PUT").Matches("PUT"))
+	assert.False(t, RequestMethod("PUT").Matches((*RecordedRequest)(nil)))
+
+	assert.Equal(t, RequestHeader("x-test", "2").String(), `is a request with header X-Test: "2"`)
+}
+
+func TestAwaitRequests(t *testing.T) {
+	tsc := &TestServerControl{requests: newRequestLog(10)}
+	m := matcher.And(RequestMethod("GET"), RequestPath("/a"))
+
+	go func() {
+		for _, p := range []string{"/a", "/b", "/a"} {
+			time.Sleep(5 * time.Millisecond)
+			tsc.requests.record(RecordedRequest{Method: "GET", Path: p})
+		}
+	}()
+
+	requests, err := tsc.AwaitRequests(m, 2, 5*time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, len(requests), 2)
+	assert.Equal(t, len(tsc.MatchingRequests(RequestPath("/b"))), 1)
+
+	requests, err = tsc.AwaitRequests(m, 3, 10*time.Millisecond)
+	assert.ErrorContains(t, err, "waiting for 3 requests that")
+	assert.ErrorContains(t, err, "got 2")
+	assert.Equal(t, len(requests), 2)
+}
+
+func TestTestServerRecordsRequests(t *testing.T) {
+	ts, err := NewTestServerWithDynamicPorts([]string{"a", "b"}, 0.0, 0, 0, false, nil)
+	assert.Nil(t, err)
+
+	tsc := ts.ServeAsync()
+	defer tsc.Stop()
+
+	ports := tsc.IDPortMap()
+	get := func(id string) {
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/p", ports[id]))
+		if assert.Nil(t, err) {
+			resp.Body.Close()
+		}
+	}
+
+	// recording is off by default
+	get("a")
+	assert.Equal(t, len(tsc.Requests()), 0)
+
+	assert.Nil(t, ts.SetRequestLogSize(10))
+	for _, id := range []string{"a", "b", "a"} {
+		resp, err := http.Post(
+			fmt.Sprintf("http://127.0.0.1:%d/p", ports[id]),
+			"text/plain",
+			strings.NewReader(id),
+		)
+		if assert.Nil(t, err) {
+			resp.Body.Close()
+		}
+	}
+
+	requests, err := tsc.AwaitRequests(RequestListener("a"), 2, time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, len(requests), 2)
+	for _, rr := range requests {
+		assert.Equal(t, rr.Method, "POST")
+		assert.Equal(t, string(rr.Body), "a")
+	}
+	assert.Equal(t, len(tsc.Requests()), 3)
+}
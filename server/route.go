@@ -0,0 +1,119 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// A route selects the Behavior used to respond to matching requests.
+type route struct {
+	method   string // empty matches any method
+	pattern  string
+	subtree  bool // the pattern ends with a slash
+	behavior Behavior
+}
+
+// newRoute parses a route pattern: an optional method, followed by a
+// space, and a path pattern, e.g. "GET /api/*/status". Path patterns
+// are matched as by path.Match, so that "*" matches a single path
+// segment. A pattern ending with a slash also matches every path
+// below it.
+func newRoute(pattern string, behavior Behavior) (route, error) {
+	if behavior == nil {
+		return route{}, fmt.Errorf("route %q: no behavior", pattern)
+	}
+
+	r := route{pattern: pattern, behavior: behavior}
+	if i := strings.Index(pattern, " "); i >= 0 {
+		r.method, r.pattern = pattern[:i], strings.TrimSpace(pattern[i+1:])
+	}
+
+	if !strings.HasPrefix(r.pattern, "/") {
+		return route{}, fmt.Errorf("route %q: path must begin with a slash", pattern)
+	}
+	if _, err := path.Match(r.pattern, ""); err != nil {
+		return route{}, fmt.Errorf("route %q: %v", pattern, err)
+	}
+
+	if r.pattern != "/" && strings.HasSuffix(r.pattern, "/") {
+		r.subtree = true
+		r.pattern = strings.TrimSuffix(r.pattern, "/")
+	}
+	if r.pattern == "/" {
+		r.subtree, r.pattern = true, ""
+	}
+
+	return r, nil
+}
+
+func (rt route) matches(r *http.Request) bool {
+	if rt.method != "" && rt.method != r.Method {
+		return false
+	}
+
+	p := r.URL.Path
+	if ok, _ := path.Match(rt.pattern, p); ok {
+		return true
+	}
+	if !rt.subtree {
+		return false
+	}
+
+	// match the pattern against each ancestor of the path
+	for i := len(p) - 1; i >= 0; i-- {
+		if p[i] != '/' {
+			continue
+		}
+		if ok, _ := path.Match(rt.pattern, p[:i]); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// AddRoute adds a route to the TestServer: requests matching the
+// pattern are answered by the given Behavior. The pattern is an
+// optional method and a space, followed by a path pattern, as
+// understood by path.Match (e.g. "GET /api/*/status"). A path pattern
+// ending with a slash also matches every path below it. Routes are
+// tried in the order they were added, and requests matching none are
+// answered by DefaultBehavior. The server's latency and error rate
+// apply to every route. Routes must be added before ServeAsync is
+// called.
+func (ts *TestServer) AddRoute(pattern string, behavior Behavior) error {
+	r, err := newRoute(pattern, behavior)
+	if err != nil {
+		return err
+	}
+	ts.routes = append(ts.routes, r)
+	return nil
+}
+
+// behavior returns the Behavior of the first route matching the
+// request, or DefaultBehavior.
+func (ts *TestServer) behavior(r *http.Request) Behavior {
+	for _, rt := range ts.routes {
+		if rt.matches(r) {
+			return rt.behavior
+		}
+	}
+	return DefaultBehavior
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/turbinelabs/test/assert"
+)
+
+func TestNewRoute(t *testing.T) {
+	b := StaticBody("text/plain", "x")
+
+	r, err := newRoute("GET /api/*/status", b)
+	assert.Nil(t, err)
+	assert.Equal(t, r.method, "GET")
+	assert.Equal(t, r.pattern, "/api/*/status")
+	assert.False(t, r.subtree)
+
+	r, err = newRoute("/static/", b)
+	assert.Nil(t, err)
+	assert.Equal(t, r.method, "")
+	assert.Equal(t, r.pattern, "/static")
+	assert.True(t, r.subtree)
+
+	_, err = newRoute("GET api", b)
+	assert.ErrorContains(t, err, "path must begin with a slash")
+
+	_, err = newRoute("/[", b)
+	assert.ErrorContains(t, err, "syntax error in pattern")
+
+	_, err = newRoute("/foo", nil)
+	assert.ErrorContains(t, err, "no behavior")
+}
+
+func TestRouteMatches(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		method  string
+		path    string
+		matches bool
+	}{
+		{"/foo", "GET", "/foo", true},
+		{"/foo", "GET", "/foo/bar", false},
+		{"/foo", "GET", "/foobar", false},
+		{"GET /foo", "POST", "/foo", false},
+		{"POST /foo", "POST", "/foo", true},
+		{"/api/*/status", "GET", "/api/a/status", true},
+		{"/api/*/status", "GET", "/api/a/b/status", false},
+		{"/api/", "GET", "/api", true},
+		{"/api/", "GET", "/api/a/b", true},
+		{"/api/", "GET", "/apix", false},
+		{"/api/*/", "GET", "/api/a/b/c", true},
+		{"/", "GET", "/", true},
+		{"/", "GET", "/anything/at/all", true},
+	} {
+		r, err := newRoute(tc.pattern, DefaultBehavior)
+		assert.Nil(t, err)
+		assert.Equal(t, r.matches(httptest.NewRequest(tc.method, tc.path, nil)), tc.matches)
+	}
+}
+
+func TestAddRoute(t *testing.T) {
+	ts := &TestServer{errorStatus: DefaultErrorStatus}
+	assert.Nil(t, ts.AddRoute("/api/v1/", StaticBody("text/plain", "v1")))
+	assert.Nil(t, ts.AddRoute("/api/", StaticBody("text/plain", "api")))
+	assert.ErrorContains(t, ts.AddRoute("api", DefaultBehavior), "path must begin")
+	assert.Equal(t, len(ts.routes), 2)
+
+	th := TestHandler{TestServer: ts, ID: "routed"}
+	for path, body := range map[string]string{
+		"/api/v1/users": "v1",
+		"/api/v2/users": "api",
+		"/other":        "Hi there, I love other\n",
+	} {
+		w := httptest.NewRecorder()
+		th.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, w.Body.String(), body)
+		assert.Equal(t, w.Header().Get(TestServerIDHeader), "routed")
+	}
+
+	// forced response codes apply to routes
+	w := httptest.NewRecorder()
+	th.ServeHTTP(w, httptest.NewRequest("GET", "/api/x?force-response-code=404", nil))
+	assert.Equal(t, w.Code, 404)
+	assert.Equal(t, w.Body.String(), "api")
+}
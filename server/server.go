@@ -27,6 +27,7 @@ import (
 	"net/http"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -47,15 +48,27 @@ type TestServer struct {
 	verbose         bool
 	rand            *rand.Rand
 	handlerOverride http.HandlerFunc
+	routes          []route
+	requests        *requestLog
+	faultRates      map[Fault]float64
+	dripDuration    time.Duration
+	tls             *tlsSettings
+	http2           bool
+	configs         map[string]*atomic.Value // by listener ID
+	configMutex     sync.Mutex
+	started         bool // guarded by configMutex
 }
 
 // TestServerControl provides the ability to control a TestServer. It
 // provides a mechanism for stopping the server and awaiting the
-// termination of all listeners.
+// termination of all listeners, and access to the requests they
+// received.
 type TestServerControl struct {
 	idPortMap map[string]int
 	closer    closerChan
 	waitgroup *sync.WaitGroup
+	requests  *requestLog
+	ca        *CertificateAuthority
 }
 
 // TestServer functions
@@ -83,10 +96,17 @@ func (ts *TestServer) serveListener(
 
 	serveMux := http.NewServeMux()
 	server := http.Server{Addr: addr, Handler: serveMux}
+	ts.configureProtocols(&server)
 	th := TestHandler{ts, listenerID}
 	serveMux.Handle("/", th)
 
-	err := server.Serve(listener)
+	var err error
+	if ts.tls != nil {
+		server.TLSConfig = ts.tls.config()
+		err = server.ServeTLS(ts.tls.track(listener), "", "")
+	} else {
+		err = server.Serve(listener)
+	}
 	if err != nil {
 		ts.logf("failed to serve HTTP for %s: %v", addr, err)
 	}
@@ -106,11 +126,16 @@ func (ts *TestServer) closeListenerOnMessage(closer closerChan, listener net.Lis
 // error rate is non-zero. It defaults to 503 (service
 // unavailable). The value must be at least 500 and less than 600.
 func (ts *TestServer) SetErrorStatus(code int) error {
+	return ts.updateDefaultConfig(func(c *Config) error {
+		c.ErrorStatus = code
+		return checkErrorStatus(code)
+	})
+}
+
+func checkErrorStatus(code int) error {
 	if code < 400 || code >= 600 {
 		return fmt.Errorf("status code %d: out of range", code)
 	}
-
-	ts.errorStatus = code
 	return nil
 }
 
@@ -123,6 +148,8 @@ func (ts *TestServer) ServeAsync() *TestServerControl {
 		panic("failed invariant: list of ports and listener IDs must be the same length")
 	}
 
+	ts.initConfigs()
+
 	closer := closerChan(make(chan struct{}))
 
 	wg := &sync.WaitGroup{}
@@ -152,7 +179,18 @@ func (ts *TestServer) ServeAsync() *TestServerControl {
 	}
 	ts.logf("servers started")
 
-	return &TestServerControl{idPortMap, closer, wg}
+	var ca *CertificateAuthority
+	if ts.tls != nil {
+		ca = ts.tls.ca
+	}
+
+	return &TestServerControl{
+		idPortMap: idPortMap,
+		closer:    closer,
+		waitgroup: wg,
+		requests:  ts.requests,
+		ca:        ca,
+	}
 }
 
 // TestServerControl functions
@@ -208,15 +246,18 @@ func NewTestServer(
 	}
 
 	ts := TestServer{
-		ports,
-		listenerIDs,
-		DefaultErrorStatus,
-		errorRate,
-		latencyMean,
-		latencyStdDev,
-		verbose,
-		mkRand(),
-		override,
+		ports:           ports,
+		listenerIDs:     listenerIDs,
+		errorStatus:     DefaultErrorStatus,
+		errorRate:       errorRate,
+		latencyMean:     latencyMean,
+		latencyStdDev:   latencyStdDev,
+		verbose:         verbose,
+		rand:            mkRand(),
+		handlerOverride: override,
+		requests:        newRequestLog(0),
+		dripDuration:    DefaultDripDuration,
+		configs:         newConfigs(listenerIDs),
 	}
 
 	return &ts, nil
@@ -260,20 +301,31 @@ func NewTestServerWithDynamicPorts(
 	}
 
 	ts := TestServer{
-		ports,
-		listenerIDs,
-		DefaultErrorStatus,
-		errorRate,
-		latencyMean,
-		latencyStdDev,
-		verbose,
-		mkRand(),
-		override,
+		ports:           ports,
+		listenerIDs:     listenerIDs,
+		errorStatus:     DefaultErrorStatus,
+		errorRate:       errorRate,
+		latencyMean:     latencyMean,
+		latencyStdDev:   latencyStdDev,
+		verbose:         verbose,
+		rand:            mkRand(),
+		handlerOverride: override,
+		requests:        newRequestLog(0),
+		dripDuration:    DefaultDripDuration,
+		configs:         newConfigs(listenerIDs),
 	}
 
 	return &ts, nil
 }
 
+func newConfigs(listenerIDs []string) map[string]*atomic.Value {
+	configs := make(map[string]*atomic.Value, len(listenerIDs))
+	for _, id := range listenerIDs {
+		configs[id] = &atomic.Value{}
+	}
+	return configs
+}
+
 func mkRand() *rand.Rand {
 	return rand.New(rand.NewSource(time.Now().UnixNano() ^ (int64(os.Getpid()) << 30)))
 }
@@ -0,0 +1,58 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package servertest provides assertions on the requests recorded by
+// a server.TestServer.
+package servertest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/turbinelabs/test/assert"
+	"github.com/turbinelabs/test/matcher"
+	"github.com/turbinelabs/test/server"
+)
+
+// AssertRequests asserts that exactly n requests recorded by the
+// TestServer match m (or any requests, if m is nil), waiting up to
+// timeout for them to arrive, and returns the matching requests. The
+// TestServer must be recording requests (see
+// TestServer.SetRequestLogSize).
+func AssertRequests(
+	t testing.TB,
+	tsc *server.TestServerControl,
+	m matcher.Matcher,
+	n int,
+	timeout time.Duration,
+) []server.RecordedRequest {
+	requests, err := tsc.AwaitRequests(m, n, timeout)
+	if err != nil {
+		assert.Tracing(t).Error(err.Error())
+	} else if len(requests) != n {
+		description := "match anything"
+		if m != nil {
+			description = m.String()
+		}
+		assert.Tracing(t).Errorf(
+			"got %d requests that %s, want %d",
+			len(requests),
+			description,
+			n,
+		)
+	}
+	return requests
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servertest
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/turbinelabs/test/assert"
+	"github.com/turbinelabs/test/server"
+)
+
+func TestAssertRequests(t *testing.T) {
+	ts, err := server.NewTestServerWithDynamicPorts([]string{"a"}, 0.0, 0, 0, false, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, ts.SetRequestLogSize(10))
+
+	tsc := ts.ServeAsync()
+	defer tsc.Stop()
+
+	for i := 0; i < 2; i++ {
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/", tsc.IDPortMap()["a"]))
+		if assert.Nil(t, err) {
+			resp.Body.Close()
+		}
+	}
+
+	mockT := &assert.MockT{}
+	requests := AssertRequests(mockT, tsc, server.RequestMethod("GET"), 2, time.Second)
+	assert.Equal(t, len(requests), 2)
+	mockT.CheckSuccess(t)
+
+	mockT = &assert.MockT{}
+	AssertRequests(mockT, tsc, server.RequestMethod("GET"), 1, time.Millisecond)
+	mockT.CheckPredicates(
+		t,
+		assert.Match(assert.ErrorOp(), assert.ArgsContain("got 2 requests that is a request")),
+	)
+
+	mockT = &assert.MockT{}
+	AssertRequests(mockT, tsc, nil, 3, time.Millisecond)
+	mockT.CheckPredicates(
+		t,
+		assert.Match(assert.ErrorOp(), assert.ArgsContain("requests that match anything: got 2")),
+	)
+}
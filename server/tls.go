@@ -0,0 +1,320 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// certificateLifetime is the validity period of generated certificates.
+const certificateLifetime = 365 * 24 * time.Hour
+
+// serverHosts are the names and addresses in a TestServer's
+// certificate.
+var serverHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+// CertificateAuthority is a self-signed certificate authority, used to
+// issue certificates for a TestServer and its clients.
+type CertificateAuthority struct {
+	// Certificate is the authority's certificate.
+	Certificate *x509.Certificate
+
+	// CertificatePEM is the authority's certificate, PEM-encoded.
+	CertificatePEM []byte
+
+	key crypto.Signer
+}
+
+// NewCertificateAuthority generates a new CertificateAuthority with
+// the given common name.
+func NewCertificateAuthority(commonName string) (*CertificateAuthority, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := certificateTemplate(commonName)
+	if err != nil {
+		return nil, err
+	}
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CertificateAuthority{
+		Certificate:    cert,
+		CertificatePEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		key:            key,
+	}, nil
+}
+
+// CertPool returns a pool containing the authority's certificate, for
+// use as a tls.Config's RootCAs (to trust servers) or ClientCAs (to
+// verify clients).
+func (ca *CertificateAuthority) CertPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Certificate)
+	return pool
+}
+
+// IssueServerCertificate issues a server certificate for the given
+// host names and IP addresses.
+func (ca *CertificateAuthority) IssueServerCertificate(hosts ...string) (tls.Certificate, error) {
+	if len(hosts) == 0 {
+		return tls.Certificate{}, errors.New("server certificate requires at least one host")
+	}
+
+	template, err := certificateTemplate(hosts[0])
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	return ca.issue(template)
+}
+
+// IssueClientCertificate issues a client certificate with the given
+// common name, for use with a TestServer requiring client
+// certificates.
+func (ca *CertificateAuthority) IssueClientCertificate(commonName string) (tls.Certificate, error) {
+	template, err := certificateTemplate(commonName)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+
+	return ca.issue(template)
+}
+
+func (ca *CertificateAuthority) issue(template *x509.Certificate) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Certificate, key.Public(), ca.key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{
+		Certificate: [][]byte{der, ca.Certificate.Raw},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}, nil
+}
+
+func certificateTemplate(commonName string) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{"testserver"}},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(certificateLifetime),
+	}, nil
+}
+
+// tlsSettings holds a TestServer's TLS configuration.
+type tlsSettings struct {
+	ca          *CertificateAuthority
+	certificate tls.Certificate
+	clientCAs   *x509.CertPool
+	conns       sync.Map // of open *trackedConns, by connKey
+}
+
+func (s *tlsSettings) config() *tls.Config {
+	config := &tls.Config{Certificates: []tls.Certificate{s.certificate}}
+	if s.clientCAs != nil {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+		config.ClientCAs = s.clientCAs
+	}
+	return config
+}
+
+// EnableTLS configures the TestServer's listeners to serve TLS, using
+// a certificate for localhost (and its loopback addresses) issued by
+// a newly generated CertificateAuthority. Clients may trust the
+// server using the authority's CertPool, available from the
+// TestServerControl. Must be called before ServeAsync.
+func (ts *TestServer) EnableTLS() error {
+	ca, err := NewCertificateAuthority("testserver CA")
+	if err != nil {
+		return fmt.Errorf("could not generate certificate authority: %v", err)
+	}
+
+	cert, err := ca.IssueServerCertificate(serverHosts...)
+	if err != nil {
+		return fmt.Errorf("could not issue server certificate: %v", err)
+	}
+
+	ts.tls = &tlsSettings{ca: ca, certificate: cert}
+	return nil
+}
+
+// RequireClientCertificates configures the TestServer to require
+// clients to present a certificate issued by one of the authorities
+// in the given pool. If the pool is nil, client certificates must be
+// issued by the server's own CertificateAuthority. TLS must be
+// enabled first.
+func (ts *TestServer) RequireClientCertificates(pool *x509.CertPool) error {
+	if ts.tls == nil {
+		return errors.New("client certificates require TLS to be enabled")
+	}
+
+	if pool == nil {
+		pool = ts.tls.ca.CertPool()
+	}
+	ts.tls.clientCAs = pool
+	return nil
+}
+
+// EnableHTTP2 configures the TestServer's listeners to accept HTTP/2
+// as well as HTTP/1.1. With TLS, HTTP/2 is negotiated via ALPN.
+// Without TLS, the listeners accept HTTP/2 with prior knowledge (h2c),
+// which requires Go 1.24 or later: when built with an earlier version,
+// TLS must be enabled first. Must be called before ServeAsync.
+func (ts *TestServer) EnableHTTP2() error {
+	if ts.tls == nil && !h2cSupported {
+		return errors.New("HTTP/2 without TLS (h2c) requires Go 1.24 or later")
+	}
+
+	ts.http2 = true
+	return nil
+}
+
+// configureProtocols configures the protocols served by one of the
+// TestServer's listeners.
+func (ts *TestServer) configureProtocols(server *http.Server) {
+	if ts.tls == nil {
+		if ts.http2 {
+			enableH2C(server)
+		}
+		return
+	}
+
+	if !ts.http2 {
+		// ServeTLS enables HTTP/2 unless TLSNextProto is non-nil
+		server.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+	}
+}
+
+// track returns a listener that records the connections accepted by
+// the given listener until they are closed, so that the connection
+// underlying a TLS connection may be found.
+func (s *tlsSettings) track(listener net.Listener) net.Listener {
+	return trackingListener{listener, &s.conns}
+}
+
+// underlyingConn returns the connection underlying a connection
+// accepted by one of the TestServer's listeners: for a TLS connection,
+// the connection it encrypts; otherwise, the connection itself.
+func (ts *TestServer) underlyingConn(conn net.Conn) net.Conn {
+	if ts.tls != nil {
+		if c, ok := ts.tls.conns.Load(connKey(conn)); ok {
+			return c.(*trackedConn)
+		}
+	}
+	return conn
+}
+
+// connKey identifies a connection by its local and remote addresses,
+// which a TLS connection shares with its underlying connection.
+func connKey(conn net.Conn) string {
+	return conn.LocalAddr().String() + " " + conn.RemoteAddr().String()
+}
+
+type trackingListener struct {
+	net.Listener
+	conns *sync.Map
+}
+
+func (l trackingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	tc := &trackedConn{conn, connKey(conn), l.conns}
+	l.conns.Store(tc.key, tc)
+	return tc, nil
+}
+
+type trackedConn struct {
+	net.Conn
+	key   string
+	conns *sync.Map
+}
+
+func (c *trackedConn) Close() error {
+	c.conns.Delete(c.key)
+	return c.Conn.Close()
+}
+
+func (c *trackedConn) SetLinger(sec int) error {
+	if tcpConn, ok := c.Conn.(*net.TCPConn); ok {
+		return tcpConn.SetLinger(sec)
+	}
+	return nil
+}
+
+// CertificateAuthority returns the authority that issued the
+// TestServer's certificate, or nil if TLS is not enabled.
+func (tsc *TestServerControl) CertificateAuthority() *CertificateAuthority {
+	return tsc.ca
+}
@@ -0,0 +1,264 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/turbinelabs/test/assert"
+)
+
+func TestCertificateAuthority(t *testing.T) {
+	ca, err := NewCertificateAuthority("test CA")
+	assert.Nil(t, err)
+	assert.True(t, ca.Certificate.IsCA)
+	assert.HasPrefix(t, string(ca.CertificatePEM), "-----BEGIN CERTIFICATE-----")
+
+	_, err = ca.IssueServerCertificate()
+	assert.ErrorContains(t, err, "at least one host")
+
+	cert, err := ca.IssueServerCertificate("localhost", "127.0.0.1")
+	assert.Nil(t, err)
+	assert.ArrayEqual(t, cert.Leaf.DNSNames, []string{"localhost"})
+	assert.Equal(t, len(cert.Leaf.IPAddresses), 1)
+	_, err = cert.Leaf.Verify(x509.VerifyOptions{
+		DNSName: "127.0.0.1",
+		Roots:   ca.CertPool(),
+	})
+	assert.Nil(t, err)
+
+	cert, err = ca.IssueClientCertificate("client")
+	assert.Nil(t, err)
+	assert.Equal(t, cert.Leaf.Subject.CommonName, "client")
+	_, err = cert.Leaf.Verify(x509.VerifyOptions{
+		Roots:     ca.CertPool(),
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	assert.Nil(t, err)
+
+	other, err := NewCertificateAuthority("other CA")
+	assert.Nil(t, err)
+	_, err = cert.Leaf.Verify(x509.VerifyOptions{
+		Roots:     other.CertPool(),
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	assert.NonNil(t, err)
+}
+
+func serveTLSTest(t *testing.T, configure func(ts *TestServer)) (*TestServerControl, string) {
+	ts, err := NewTestServerWithDynamicPorts([]string{"a"}, 0.0, 0, 0, false, nil)
+	assert.Nil(t, err)
+	configure(ts)
+
+	tsc := ts.ServeAsync()
+	return tsc, fmt.Sprintf("127.0.0.1:%d", tsc.IDPortMap()["a"])
+}
+
+func get(t *testing.T, transport *http.Transport, url string) (*http.Response, error) {
+	client := &http.Client{Transport: transport}
+	defer transport.CloseIdleConnections()
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, string(body), "Hi there, I love tls\n")
+	return resp, nil
+}
+
+// negotiatedProtocol returns the protocol negotiated via ALPN by a TLS
+// client offering HTTP/2 and HTTP/1.1.
+func negotiatedProtocol(t *testing.T, addr string, ca *CertificateAuthority) string {
+	conn, err := tls.Dial(
+		"tcp",
+		addr,
+		&tls.Config{RootCAs: ca.CertPool(), NextProtos: []string{"h2", "http/1.1"}},
+	)
+	if !assert.Nil(t, err) {
+		return ""
+	}
+	defer conn.Close()
+
+	return conn.ConnectionState().NegotiatedProtocol
+}
+
+// http2FrameType sends the HTTP/2 connection preface, with an empty
+// SETTINGS frame, and returns the type of the first frame received in
+// response. An HTTP/2 server responds with its own SETTINGS frame.
+func http2FrameType(t *testing.T, conn net.Conn) byte {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	_, err := io.WriteString(conn, "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n\x00\x00\x00\x04\x00\x00\x00\x00\x00")
+	if !assert.Nil(t, err) {
+		return 0
+	}
+
+	header := make([]byte, 9)
+	if _, err := io.ReadFull(conn, header); !assert.Nil(t, err) {
+		return 0
+	}
+	return header[3]
+}
+
+const http2SettingsFrame = 0x4
+
+func TestTestServerTLS(t *testing.T) {
+	tsc, addr := serveTLSTest(t, func(ts *TestServer) {
+		assert.Nil(t, ts.EnableTLS())
+		assert.Nil(t, ts.SetRequestLogSize(10))
+	})
+	defer tsc.Stop()
+
+	ca := tsc.CertificateAuthority()
+	if !assert.NonNil(t, ca) {
+		return
+	}
+
+	resp, err := get(
+		t,
+		&http.Transport{TLSClientConfig: &tls.Config{RootCAs: ca.CertPool()}},
+		"https://"+addr+"/tls",
+	)
+	if assert.Nil(t, err) {
+		assert.Equal(t, resp.Proto, "HTTP/1.1")
+	}
+
+	// HTTP/2 is not offered
+	assert.Equal(t, negotiatedProtocol(t, addr, ca), "http/1.1")
+
+	_, err = get(t, &http.Transport{}, "https://"+addr+"/tls")
+	assert.ErrorContains(t, err, "certificate")
+
+	requests := tsc.Requests()
+	assert.Equal(t, len(requests), 1)
+}
+
+func TestTestServerTLSWithHTTP2(t *testing.T) {
+	tsc, addr := serveTLSTest(t, func(ts *TestServer) {
+		assert.Nil(t, ts.EnableTLS())
+		assert.Nil(t, ts.EnableHTTP2())
+	})
+	defer tsc.Stop()
+
+	ca := tsc.CertificateAuthority()
+	assert.Equal(t, negotiatedProtocol(t, addr, ca), "h2")
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{RootCAs: ca.CertPool(), NextProtos: []string{"h2"}})
+	if assert.Nil(t, err) {
+		assert.Equal(t, http2FrameType(t, conn), byte(http2SettingsFrame))
+	}
+
+	// HTTP/1.1 is still served
+	resp, err := get(
+		t,
+		&http.Transport{TLSClientConfig: &tls.Config{RootCAs: ca.CertPool()}},
+		"https://"+addr+"/tls",
+	)
+	if assert.Nil(t, err) {
+		assert.Equal(t, resp.Proto, "HTTP/1.1")
+	}
+}
+
+func TestTestServerH2C(t *testing.T) {
+	if !h2cSupported {
+		ts, err := NewTestServerWithDynamicPorts([]string{"a"}, 0.0, 0, 0, false, nil)
+		assert.Nil(t, err)
+		assert.ErrorContains(t, ts.EnableHTTP2(), "requires Go 1.24")
+		return
+	}
+
+	tsc, addr := serveTLSTest(t, func(ts *TestServer) { assert.Nil(t, ts.EnableHTTP2()) })
+	defer tsc.Stop()
+
+	assert.Nil(t, tsc.CertificateAuthority())
+
+	conn, err := net.Dial("tcp", addr)
+	if assert.Nil(t, err) {
+		assert.Equal(t, http2FrameType(t, conn), byte(http2SettingsFrame))
+	}
+
+	// HTTP/1.1 is still served
+	resp, err := get(t, &http.Transport{}, "http://"+addr+"/tls")
+	if assert.Nil(t, err) {
+		assert.Equal(t, resp.Proto, "HTTP/1.1")
+	}
+}
+
+func TestTestServerTLSResetFault(t *testing.T) {
+	tsc, addr := serveTLSTest(t, func(ts *TestServer) { assert.Nil(t, ts.EnableTLS()) })
+	defer tsc.Stop()
+
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: tsc.CertificateAuthority().CertPool()},
+	}
+	defer transport.CloseIdleConnections()
+
+	client := &http.Client{Transport: transport, Timeout: 5 * time.Second}
+	resp, err := client.Get(fmt.Sprintf("https://%s/tls?%s=reset", addr, TestServerForceFault))
+	if err == nil {
+		_, err = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	assert.ErrorContains(t, err, "reset")
+}
+
+func TestTestServerClientCertificates(t *testing.T) {
+	ts, err := NewTestServerWithDynamicPorts([]string{"a"}, 0.0, 0, 0, false, nil)
+	assert.Nil(t, err)
+	assert.ErrorContains(t, ts.RequireClientCertificates(nil), "require TLS")
+
+	tsc, addr := serveTLSTest(t, func(ts *TestServer) {
+		assert.Nil(t, ts.EnableTLS())
+		assert.Nil(t, ts.RequireClientCertificates(nil))
+	})
+	defer tsc.Stop()
+
+	ca := tsc.CertificateAuthority()
+	_, err = get(
+		t,
+		&http.Transport{TLSClientConfig: &tls.Config{RootCAs: ca.CertPool()}},
+		"https://"+addr+"/tls",
+	)
+	assert.NonNil(t, err)
+
+	cert, err := ca.IssueClientCertificate("client")
+	assert.Nil(t, err)
+	_, err = get(
+		t,
+		&http.Transport{
+			TLSClientConfig: &tls.Config{
+				RootCAs:      ca.CertPool(),
+				Certificates: []tls.Certificate{cert},
+			},
+		},
+		"https://"+addr+"/tls",
+	)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/turbinelabs/test/testrunner/compare"
+	"github.com/turbinelabs/test/testrunner/junit"
+	"github.com/turbinelabs/test/testrunner/report"
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+// getBenchTolerance returns the configured benchmark tolerance, or a
+// negative tolerance if benchmarks are not compared with a baseline.
+func getBenchTolerance() (float64, error) {
+	if BenchTolerance == "" {
+		return -1, nil
+	}
+
+	tolerance, err := strconv.ParseFloat(BenchTolerance, 64)
+	if err != nil || tolerance < 0 {
+		return 0, invalidSetting(ENV_BENCH_TOLERANCE, BenchTolerance, "is not a non-negative percentage")
+	}
+	return tolerance, nil
+}
+
+// checkBenchmarks compares each package's benchmarks with those in
+// the package's baseline report, if any, given the tolerance. Each
+// regression is recorded as a failed test and fails the package.
+func checkBenchmarks(pkgs []*results.TestPackage, tolerance float64) {
+	if tolerance < 0 {
+		return
+	}
+
+	baselineDir := BenchBaseline
+	if baselineDir == "" {
+		baselineDir = TestOutput
+	}
+
+	for _, pkg := range pkgs {
+		if len(pkg.Benchmarks) == 0 {
+			continue
+		}
+
+		baseline := readBaselineBenchmarks(baselineDir, pkg.Name)
+		for _, r := range compare.Benchmarks(baseline, pkg.Benchmarks, tolerance) {
+			t := &results.Test{
+				Name:   fmt.Sprintf("[regression] %s %s", r.Benchmark, r.Metric),
+				Result: results.Failed,
+			}
+			t.Failure.WriteString(r.String())
+			t.Failure.WriteString("\n")
+
+			pkg.Tests = append(pkg.Tests, t)
+			pkg.Result = results.Failed
+		}
+	}
+}
+
+// readBaselineBenchmarks reads the named package's benchmark results
+// from its report in the given directory. A missing or unreadable
+// report produces no benchmarks.
+func readBaselineBenchmarks(dir, pkgName string) []*results.Benchmark {
+	f, err := os.Open(filepath.Join(dir, reportFileName(pkgName, report.JUnitReporter.Extension())))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	suites, err := junit.ReadReport(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "testrunner: ignoring baseline for %s: %v\n", pkgName, err)
+		return nil
+	}
+
+	benchmarks := []*results.Benchmark{}
+	for _, suite := range suites.Suites {
+		if suite.Name == pkgName {
+			benchmarks = append(benchmarks, suite.Benchmarks()...)
+		}
+	}
+	return benchmarks
+}
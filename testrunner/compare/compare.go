@@ -0,0 +1,180 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package compare detects benchmark regressions by comparing
+// benchmark results with those of a baseline run.
+package compare
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+// Metrics lists the benchmark metrics that are compared. For each, a
+// larger value is worse.
+var Metrics = []string{"ns/op", "B/op", "allocs/op"}
+
+// Stats summarizes the samples of a benchmark metric.
+type Stats struct {
+	N      int
+	Mean   float64
+	StdDev float64
+}
+
+// String formats the stats as mean ± relative standard deviation.
+func (s Stats) String() string {
+	if s.N < 2 || s.Mean == 0 {
+		return formatFloat(s.Mean)
+	}
+	return fmt.Sprintf("%s ±%.0f%%", formatFloat(s.Mean), 100*s.StdDev/s.Mean)
+}
+
+// Regression describes a benchmark metric that is worse than its
+// baseline by more than the allowed tolerance.
+type Regression struct {
+	Benchmark string // the benchmark's full name, e.g. "BenchmarkFoo-8"
+	Metric    string // e.g. "ns/op"
+	Baseline  Stats
+	Current   Stats
+	Tolerance float64 // percent
+}
+
+// Delta returns the change from baseline to current as a percentage.
+func (r Regression) Delta() float64 {
+	return 100 * (r.Current.Mean - r.Baseline.Mean) / r.Baseline.Mean
+}
+
+func (r Regression) String() string {
+	return fmt.Sprintf(
+		"%s %s: %s -> %s (%+.2f%%, tolerance %s%%)",
+		r.Benchmark,
+		r.Metric,
+		r.Baseline,
+		r.Current,
+		r.Delta(),
+		formatFloat(r.Tolerance),
+	)
+}
+
+// Benchmarks compares current benchmark results with baseline
+// results, returning a Regression for each metric whose mean
+// increased by more than tolerance percent. Benchmarks are matched by
+// full name; repeated runs of a benchmark (e.g. via -test.count) are
+// treated as samples. When both sides have multiple samples, a metric
+// only regresses if the means differ by more than a standard
+// deviation on each side, to avoid reporting noise. Benchmarks absent
+// from either side are ignored. Regressions are sorted by benchmark
+// name and metric.
+func Benchmarks(
+	baseline []*results.Benchmark,
+	current []*results.Benchmark,
+	tolerance float64,
+) []Regression {
+	baseSamples := samples(baseline)
+	currentSamples := samples(current)
+
+	names := make([]string, 0, len(currentSamples))
+	for name := range currentSamples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	regressions := []Regression{}
+	for _, name := range names {
+		base, ok := baseSamples[name]
+		if !ok {
+			continue
+		}
+
+		for _, metric := range Metrics {
+			b := computeStats(base[metric])
+			c := computeStats(currentSamples[name][metric])
+			if b.N == 0 || c.N == 0 || b.Mean <= 0 {
+				continue
+			}
+
+			if c.Mean <= b.Mean*(1+tolerance/100) {
+				continue
+			}
+
+			if b.N > 1 && c.N > 1 && c.Mean-c.StdDev <= b.Mean+b.StdDev {
+				continue
+			}
+
+			regressions = append(
+				regressions,
+				Regression{
+					Benchmark: name,
+					Metric:    metric,
+					Baseline:  b,
+					Current:   c,
+					Tolerance: tolerance,
+				},
+			)
+		}
+	}
+
+	return regressions
+}
+
+// samples groups benchmark metrics by benchmark name and metric.
+func samples(benchmarks []*results.Benchmark) map[string]map[string][]float64 {
+	result := map[string]map[string][]float64{}
+	for _, b := range benchmarks {
+		name := b.FullName()
+		s, ok := result[name]
+		if !ok {
+			s = map[string][]float64{}
+			result[name] = s
+		}
+
+		s["ns/op"] = append(s["ns/op"], b.NsPerOp)
+		if b.HasMemStats {
+			s["B/op"] = append(s["B/op"], b.BytesPerOp)
+			s["allocs/op"] = append(s["allocs/op"], b.AllocsPerOp)
+		}
+	}
+	return result
+}
+
+func computeStats(values []float64) Stats {
+	s := Stats{N: len(values)}
+	if s.N == 0 {
+		return s
+	}
+
+	for _, v := range values {
+		s.Mean += v
+	}
+	s.Mean /= float64(s.N)
+
+	if s.N > 1 {
+		sumSq := 0.0
+		for _, v := range values {
+			sumSq += (v - s.Mean) * (v - s.Mean)
+		}
+		s.StdDev = math.Sqrt(sumSq / float64(s.N-1))
+	}
+
+	return s
+}
+
+func formatFloat(f float64) string {
+	return fmt.Sprintf("%.4g", f)
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compare
+
+import (
+	"testing"
+
+	"github.com/turbinelabs/test/assert"
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+func bench(name string, procs int, nsPerOp float64) *results.Benchmark {
+	return &results.Benchmark{Name: name, Procs: procs, NsPerOp: nsPerOp}
+}
+
+func benchMem(name string, nsPerOp, bytesPerOp, allocsPerOp float64) *results.Benchmark {
+	return &results.Benchmark{
+		Name:        name,
+		NsPerOp:     nsPerOp,
+		HasMemStats: true,
+		BytesPerOp:  bytesPerOp,
+		AllocsPerOp: allocsPerOp,
+	}
+}
+
+func TestBenchmarksSingleSample(t *testing.T) {
+	baseline := []*results.Benchmark{
+		bench("BenchmarkA", 8, 100),
+		bench("BenchmarkB", 8, 100),
+		bench("BenchmarkC", 8, 100),
+	}
+	current := []*results.Benchmark{
+		bench("BenchmarkA", 8, 110), // within tolerance
+		bench("BenchmarkB", 8, 125), // regression
+		bench("BenchmarkC", 4, 200), // different procs: no baseline
+		bench("BenchmarkD", 8, 200), // no baseline
+	}
+
+	regressions := Benchmarks(baseline, current, 10)
+	assert.Equal(t, len(regressions), 1)
+
+	r := regressions[0]
+	assert.Equal(t, r.Benchmark, "BenchmarkB-8")
+	assert.Equal(t, r.Metric, "ns/op")
+	assert.Equal(t, r.Baseline, Stats{N: 1, Mean: 100})
+	assert.Equal(t, r.Current, Stats{N: 1, Mean: 125})
+	assert.Equal(t, r.Delta(), 25.0)
+	assert.Equal(t, r.String(), "BenchmarkB-8 ns/op: 100 -> 125 (+25.00%, tolerance 10%)")
+}
+
+func TestBenchmarksMemStats(t *testing.T) {
+	baseline := []*results.Benchmark{benchMem("BenchmarkA", 100, 64, 2)}
+	current := []*results.Benchmark{benchMem("BenchmarkA", 100, 128, 2)}
+
+	regressions := Benchmarks(baseline, current, 10)
+	assert.Equal(t, len(regressions), 1)
+	assert.Equal(t, regressions[0].Metric, "B/op")
+
+	// no memory stats in the baseline
+	regressions = Benchmarks([]*results.Benchmark{bench("BenchmarkA", 0, 100)}, current, 10)
+	assert.Equal(t, len(regressions), 0)
+}
+
+func TestBenchmarksMultipleSamples(t *testing.T) {
+	baseline := []*results.Benchmark{
+		bench("BenchmarkA", 0, 90),
+		bench("BenchmarkA", 0, 110),
+		bench("BenchmarkB", 0, 99),
+		bench("BenchmarkB", 0, 101),
+	}
+	current := []*results.Benchmark{
+		// mean is 20% higher, but too noisy to call
+		bench("BenchmarkA", 0, 100),
+		bench("BenchmarkA", 0, 140),
+		// mean is 20% higher, with little noise
+		bench("BenchmarkB", 0, 119),
+		bench("BenchmarkB", 0, 121),
+	}
+
+	regressions := Benchmarks(baseline, current, 10)
+	assert.Equal(t, len(regressions), 1)
+
+	r := regressions[0]
+	assert.Equal(t, r.Benchmark, "BenchmarkB")
+	assert.Equal(t, r.Baseline.N, 2)
+	assert.Equal(t, r.Baseline.Mean, 100.0)
+	assert.Equal(t, r.Current.Mean, 120.0)
+	assert.Equal(t, r.String(), "BenchmarkB ns/op: 100 ±1% -> 120 ±1% (+20.00%, tolerance 10%)")
+}
+
+func TestBenchmarksNoBaseline(t *testing.T) {
+	current := []*results.Benchmark{bench("BenchmarkA", 0, 100)}
+	assert.Equal(t, len(Benchmarks(nil, current, 0)), 0)
+}
@@ -0,0 +1,258 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ConfigFileName is the name of testrunner's configuration file.
+	// The file in the working directory, or in its closest ancestor
+	// containing one, is used.
+	ConfigFileName = ".testrunner.json"
+
+	// ConfigVersion is the version of the configuration file format.
+	ConfigVersion = 1
+
+	configVersionKey = "version"
+	envPrefix        = "TEST_RUNNER_"
+)
+
+// configSettings lists the environment variables that may also be set
+// in the configuration file.
+var configSettings = []string{
+	ENV_ROOT_PACKAGE,
+	ENV_OUTPUT_DIR,
+	ENV_PARSER,
+	ENV_FORMATS,
+	ENV_PROPERTIES,
+	ENV_BENCH_TOLERANCE,
+	ENV_BENCH_BASELINE,
+	ENV_TIMEOUT,
+	ENV_TEST_TIMEOUT,
+	ENV_RETRIES,
+	ENV_COVERAGE,
+	ENV_COBERTURA,
+	ENV_SHARD_INDEX,
+	ENV_SHARD_COUNT,
+	ENV_PROGRESS,
+	ENV_SLOW_TEST,
+	ENV_HISTORY,
+}
+
+// pathSettings are the settings naming directories. In the
+// configuration file, relative paths are relative to the file's
+// directory.
+var pathSettings = map[string]bool{
+	ENV_OUTPUT_DIR:     true,
+	ENV_BENCH_BASELINE: true,
+}
+
+// configFile holds the settings read from a configuration file, by
+// environment variable name.
+type configFile struct {
+	path     string
+	settings map[string]string
+}
+
+// The configuration file, if any, and any problems reading it.
+var config, configErrs = loadConfig()
+
+func loadConfig() (*configFile, []error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, nil
+	}
+
+	path, ok := findConfigFile(wd)
+	if !ok {
+		return nil, nil
+	}
+
+	return readConfigFile(path)
+}
+
+// findConfigFile returns the path of the configuration file in the
+// given directory or its closest ancestor containing one.
+func findConfigFile(dir string) (string, bool) {
+	for {
+		path := filepath.Join(dir, ConfigFileName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// readConfigFile reads a configuration file: a JSON object containing
+// the format version and any of the settings in configSettings, keyed
+// by configKey. Values may be strings, numbers, booleans or (for
+// comma-separated lists) arrays of strings. Returns every problem
+// found.
+func readConfigFile(path string) (*configFile, []error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, []error{fmt.Errorf("%s: %v", path, err)}
+	}
+
+	version, ok := raw[configVersionKey]
+	if !ok {
+		return nil, []error{fmt.Errorf("%s: missing key %q", path, configVersionKey)}
+	}
+	if v := string(bytes.TrimSpace(version)); v != strconv.Itoa(ConfigVersion) {
+		return nil, []error{
+			fmt.Errorf(
+				"%s: %s=%s is not a supported version (expected %d)",
+				path,
+				configVersionKey,
+				v,
+				ConfigVersion,
+			),
+		}
+	}
+
+	names := map[string]string{}
+	for _, name := range configSettings {
+		names[configKey(name)] = name
+	}
+
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		if key != configVersionKey {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	c := &configFile{path: path, settings: map[string]string{}}
+	errs := []error{}
+	for _, key := range keys {
+		name, ok := names[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown key %q", path, key))
+			continue
+		}
+
+		value, ok := configValue(raw[key])
+		if !ok {
+			errs = append(
+				errs,
+				fmt.Errorf(
+					"%s: %s=%s is not a string, number, boolean or array of strings",
+					path,
+					key,
+					bytes.TrimSpace(raw[key]),
+				),
+			)
+			continue
+		}
+
+		if pathSettings[name] && value != "" && !filepath.IsAbs(value) {
+			value = filepath.Join(filepath.Dir(path), value)
+		}
+		c.settings[name] = value
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return c, nil
+}
+
+// configKey returns the configuration file key of the setting with
+// the given environment variable name: the name, without its
+// "TEST_RUNNER_" prefix, in lower case (e.g. "shard_count").
+func configKey(name string) string {
+	return strings.ToLower(strings.TrimPrefix(name, envPrefix))
+}
+
+// configValue converts a configuration file value to the form of the
+// corresponding environment variable.
+func configValue(raw json.RawMessage) (string, bool) {
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", false
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case float64:
+		// as written, e.g. "2" rather than "2e+00"
+		return string(bytes.TrimSpace(raw)), true
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return "", false
+			}
+			items[i] = s
+		}
+		return strings.Join(items, ","), true
+	default:
+		return "", false
+	}
+}
+
+// getEnv returns the value of the named setting: the environment
+// variable, if set, or else the configuration file's value, if any,
+// or else the default.
+func getEnv(name, defaultValue string) string {
+	if value, present := os.LookupEnv(name); present {
+		return value
+	}
+	if config != nil {
+		if value, present := config.settings[name]; present {
+			return value
+		}
+	}
+	return defaultValue
+}
+
+// invalidSetting returns an error describing a problem with the value
+// of the named setting, naming the configuration file key it was read
+// from or, otherwise, the environment variable.
+func invalidSetting(name, value, problem string) error {
+	if _, present := os.LookupEnv(name); !present && config != nil {
+		if _, present := config.settings[name]; present {
+			return fmt.Errorf("%s: %s=%s %s", config.path, configKey(name), value, problem)
+		}
+	}
+	return fmt.Errorf("Env var %s=%s %s", name, value, problem)
+}
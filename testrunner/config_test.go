@@ -0,0 +1,140 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/turbinelabs/test/assert"
+	"github.com/turbinelabs/test/tempfile"
+)
+
+func writeConfigFile(t *testing.T, dir, contents string) string {
+	path := filepath.Join(dir, ConfigFileName)
+	assert.Nil(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	return path
+}
+
+func TestFindConfigFile(t *testing.T) {
+	dir := tempfile.TempDir(t, "testrunner-config")
+	defer dir.Cleanup()
+
+	nested := filepath.Join(dir.Path(), "a", "b")
+	assert.Nil(t, os.MkdirAll(nested, 0755))
+
+	path := writeConfigFile(t, dir.Path(), `{"version": 1}`)
+	found, ok := findConfigFile(nested)
+	assert.True(t, ok)
+	assert.Equal(t, found, path)
+
+	// the closest wins
+	path = writeConfigFile(t, filepath.Join(dir.Path(), "a"), `{"version": 1}`)
+	found, ok = findConfigFile(nested)
+	assert.True(t, ok)
+	assert.Equal(t, found, path)
+}
+
+func TestReadConfigFile(t *testing.T) {
+	dir := tempfile.TempDir(t, "testrunner-config")
+	defer dir.Cleanup()
+
+	path := writeConfigFile(t, dir.Path(), `{
+	"version": 1,
+	"output": "results",
+	"bench_baseline": "/baseline",
+	"formats": ["junit", "json"],
+	"retries": 2,
+	"coverage": true,
+	"timeout": "10m"
+}`)
+
+	c, errs := readConfigFile(path)
+	assert.Equal(t, len(errs), 0)
+	assert.Equal(t, c.path, path)
+	assert.MapEqual(t, c.settings, map[string]string{
+		ENV_OUTPUT_DIR:     filepath.Join(dir.Path(), "results"),
+		ENV_BENCH_BASELINE: "/baseline",
+		ENV_FORMATS:        "junit,json",
+		ENV_RETRIES:        "2",
+		ENV_COVERAGE:       "true",
+		ENV_TIMEOUT:        "10m",
+	})
+}
+
+func TestReadConfigFileErrors(t *testing.T) {
+	dir := tempfile.TempDir(t, "testrunner-config")
+	defer dir.Cleanup()
+
+	for contents, expected := range map[string][]string{
+		`{"version": 1,`: {"unexpected end of JSON input"},
+		`{"retries": 2}`: {`missing key "version"`},
+		`{"version": 2}`: {"version=2 is not a supported version (expected 1)"},
+		`{"version": 1, "retry": 2, "formats": ["junit", 1], "parser": null}`: {
+			"formats=[\"junit\", 1] is not a string, number, boolean or array of strings",
+			"parser=null is not a string, number, boolean or array of strings",
+			`unknown key "retry"`,
+		},
+	} {
+		path := writeConfigFile(t, dir.Path(), contents)
+		c, errs := readConfigFile(path)
+		assert.Nil(t, c)
+		assert.Equal(t, len(errs), len(expected))
+		for i, err := range errs {
+			assert.ErrorContains(t, err, path+": ")
+			assert.ErrorContains(t, err, expected[i])
+		}
+	}
+}
+
+func TestConfigKey(t *testing.T) {
+	assert.Equal(t, configKey(ENV_ROOT_PACKAGE), "root_package")
+	assert.Equal(t, configKey(ENV_SHARD_COUNT), "shard_count")
+}
+
+func TestGetEnvAndInvalidSetting(t *testing.T) {
+	saved := config
+	defer func() {
+		config = saved
+	}()
+
+	const name = "TEST_RUNNER_CONFIG_TEST"
+	savedValue, present := os.LookupEnv(name)
+	defer func() {
+		if present {
+			os.Setenv(name, savedValue)
+		} else {
+			os.Unsetenv(name)
+		}
+	}()
+	os.Unsetenv(name)
+
+	config = nil
+	assert.Equal(t, getEnv(name, "default"), "default")
+	assert.ErrorContains(t, invalidSetting(name, "x", "is bad"), "Env var TEST_RUNNER_CONFIG_TEST=x is bad")
+
+	config = &configFile{path: "/a/.testrunner.json", settings: map[string]string{name: "config"}}
+	assert.Equal(t, getEnv(name, "default"), "config")
+	assert.ErrorContains(t, invalidSetting(name, "config", "is bad"), "/a/.testrunner.json: config_test=config is bad")
+
+	// the environment overrides the configuration file
+	os.Setenv(name, "env")
+	assert.Equal(t, getEnv(name, "default"), "env")
+	assert.ErrorContains(t, invalidSetting(name, "env", "is bad"), "Env var TEST_RUNNER_CONFIG_TEST=env is bad")
+}
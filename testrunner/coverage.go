@@ -0,0 +1,185 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/turbinelabs/test/testrunner/coverage"
+	"github.com/turbinelabs/test/testrunner/parser"
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+// CoverProfileExtension is the extension of the coverage profiles
+// saved in the output directory.
+const CoverProfileExtension = ".cover"
+
+// coverProfile is a coverage profile requested from a test run.
+type coverProfile struct {
+	path      string
+	temporary bool // created by testrunner and removed once read
+}
+
+// getCoverage returns whether coverage profiles are captured.
+// Requesting a Cobertura report implies capturing them.
+func getCoverage() (bool, error) {
+	enabled, err := strconv.ParseBool(Coverage)
+	if err != nil {
+		return false, invalidSetting(ENV_COVERAGE, Coverage, "is not a boolean")
+	}
+	return enabled || Cobertura != "", nil
+}
+
+// newCoverProfile returns the coverage profile requested by the given
+// flag (e.g. "-test.coverprofile") in args or, if there is none, a
+// new temporary file. A relative profile path is resolved against the
+// directory given by outputDirFlag, if present.
+func newCoverProfile(args []string, flag, outputDirFlag string) coverProfile {
+	if path, ok := flagValue(args, flag); ok {
+		if outputDir, ok := flagValue(args, outputDirFlag); ok && !filepath.IsAbs(path) {
+			path = filepath.Join(outputDir, path)
+		}
+		return coverProfile{path: path}
+	}
+
+	f, err := ioutil.TempFile("", "testrunner-cover-")
+	if err != nil {
+		panic(err)
+	}
+	f.Close()
+
+	return coverProfile{path: f.Name(), temporary: true}
+}
+
+// withCoverProfile returns a copy of the parser whose FlagFn adds a
+// -test.coverprofile flag naming the given profile, unless it was
+// requested explicitly.
+func withCoverProfile(testParser parser.Parser, profile coverProfile) parser.Parser {
+	if !profile.temporary {
+		return testParser
+	}
+
+	flagFn := testParser.FlagFn
+	testParser.FlagFn = func(args []string) []string {
+		withFlag := make([]string, 0, len(args)+1)
+		withFlag = append(withFlag, args...)
+		return flagFn(append(withFlag, "-test.coverprofile="+profile.path))
+	}
+	return testParser
+}
+
+// recordCoverage reads the coverage profile, attaching a summary of
+// each package's coverage to the package, and saves it in the output
+// directory under the given package name. A missing or empty profile
+// (e.g. because the test executable crashed) is ignored.
+func recordCoverage(pkgName string, pkgs []*results.TestPackage, profile coverProfile) {
+	if profile.temporary {
+		defer os.Remove(profile.path)
+	}
+
+	if info, err := os.Stat(profile.path); err != nil || info.Size() == 0 {
+		return
+	}
+
+	p, err := coverage.ReadProfile(profile.path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "testrunner: ignoring coverage profile: %v\n", err)
+		return
+	}
+
+	for _, pkg := range pkgs {
+		pkgProfile := p.Package(pkg.Name)
+		if len(pkgProfile.Blocks) == 0 && len(pkgs) == 1 {
+			// the package name is derived from the test
+			// executable's path, and may not be its import path
+			pkgProfile = p
+		}
+		pkg.Coverage = pkgProfile.Summary()
+	}
+
+	f := openFile(reportFileName(pkgName, CoverProfileExtension))
+	defer f.Close()
+
+	if err := p.Write(f); err != nil {
+		panic(err)
+	}
+}
+
+// writeCobertura merges the coverage profiles saved in the output
+// directory, including those of previous runs, into a Cobertura
+// report.
+func writeCobertura() {
+	filenames, err := filepath.Glob(filepath.Join(TestOutput, "*"+CoverProfileExtension))
+	if err != nil {
+		panic(err)
+	}
+
+	var merged *coverage.Profile
+	for _, filename := range filenames {
+		p, err := coverage.ReadProfile(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "testrunner: ignoring coverage profile %s: %v\n", filename, err)
+			continue
+		}
+
+		if merged == nil {
+			merged = coverage.NewProfile(p.Mode)
+		}
+		merged.Merge(p)
+	}
+
+	if merged == nil {
+		return
+	}
+
+	f := createFile(coberturaPath())
+	defer f.Close()
+
+	if err := coverage.WriteCobertura(f, merged, time.Now()); err != nil {
+		panic(err)
+	}
+}
+
+// coberturaPath returns the path of the Cobertura report: the value of
+// TEST_RUNNER_COBERTURA, if absolute, or else that path relative to
+// the output directory.
+func coberturaPath() string {
+	if filepath.IsAbs(Cobertura) {
+		return Cobertura
+	}
+	return filepath.Join(TestOutput, Cobertura)
+}
+
+// flagValue returns the value of the named flag in args, given as
+// either "-flag=value" or "-flag value".
+func flagValue(args []string, flag string) (string, bool) {
+	for i, arg := range args {
+		switch {
+		case arg == flag && i+1 < len(args):
+			return args[i+1], true
+		case strings.HasPrefix(arg, flag+"="):
+			return arg[len(flag)+1:], true
+		}
+	}
+	return "", false
+}
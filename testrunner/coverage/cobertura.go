@@ -0,0 +1,160 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package coverage
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"time"
+)
+
+type CoberturaCoverage struct {
+	XMLName    xml.Name           `xml:"coverage"`
+	LineRate   string             `xml:"line-rate,attr"`
+	BranchRate string             `xml:"branch-rate,attr"`
+	Lines      int                `xml:"lines-valid,attr"`
+	Covered    int                `xml:"lines-covered,attr"`
+	Version    string             `xml:"version,attr"`
+	Timestamp  int64              `xml:"timestamp,attr"`
+	Packages   []CoberturaPackage `xml:"packages>package"`
+}
+
+type CoberturaPackage struct {
+	Name       string           `xml:"name,attr"`
+	LineRate   string           `xml:"line-rate,attr"`
+	BranchRate string           `xml:"branch-rate,attr"`
+	Complexity string           `xml:"complexity,attr"`
+	Classes    []CoberturaClass `xml:"classes>class"`
+}
+
+type CoberturaClass struct {
+	Name       string          `xml:"name,attr"`
+	Filename   string          `xml:"filename,attr"`
+	LineRate   string          `xml:"line-rate,attr"`
+	BranchRate string          `xml:"branch-rate,attr"`
+	Complexity string          `xml:"complexity,attr"`
+	Methods    struct{}        `xml:"methods"`
+	Lines      []CoberturaLine `xml:"lines>line"`
+}
+
+type CoberturaLine struct {
+	Number int `xml:"number,attr"`
+	Hits   int `xml:"hits,attr"`
+}
+
+// GenerateCobertura converts a Profile into a Cobertura coverage
+// report. Go packages become Cobertura packages and source files
+// become classes. A line is reported for each line spanned by a
+// block, with the greatest count of the blocks spanning it.
+func GenerateCobertura(p *Profile, timestamp time.Time) CoberturaCoverage {
+	report := CoberturaCoverage{
+		BranchRate: "0",
+		Version:    "gocov",
+		Timestamp:  timestamp.UnixNano() / int64(time.Millisecond),
+		Packages:   []CoberturaPackage{},
+	}
+
+	files := map[string]map[int]int{}
+	for _, b := range p.Blocks {
+		lines, ok := files[b.File]
+		if !ok {
+			lines = map[int]int{}
+			files[b.File] = lines
+		}
+		for n := b.StartLine; n <= b.EndLine; n++ {
+			if hits, ok := lines[n]; !ok || b.Count > hits {
+				lines[n] = b.Count
+			}
+		}
+	}
+
+	totalLines, totalCovered := 0, 0
+	for _, pkgName := range p.Packages() {
+		pkg := CoberturaPackage{
+			Name:       pkgName,
+			BranchRate: "0",
+			Complexity: "0",
+			Classes:    []CoberturaClass{},
+		}
+
+		pkgLines, pkgCovered := 0, 0
+		for _, f := range p.Package(pkgName).Summary().Files {
+			lines := files[f.Name]
+			class := CoberturaClass{
+				Name:       path.Base(f.Name),
+				Filename:   f.Name,
+				BranchRate: "0",
+				Complexity: "0",
+				Lines:      make([]CoberturaLine, 0, len(lines)),
+			}
+
+			covered := 0
+			for n, hits := range lines {
+				class.Lines = append(class.Lines, CoberturaLine{n, hits})
+				if hits > 0 {
+					covered++
+				}
+			}
+			sort.Slice(class.Lines, func(i, j int) bool {
+				return class.Lines[i].Number < class.Lines[j].Number
+			})
+			class.LineRate = rate(covered, len(lines))
+
+			pkgLines += len(lines)
+			pkgCovered += covered
+			pkg.Classes = append(pkg.Classes, class)
+		}
+		pkg.LineRate = rate(pkgCovered, pkgLines)
+
+		totalLines += pkgLines
+		totalCovered += pkgCovered
+		report.Packages = append(report.Packages, pkg)
+	}
+
+	report.Lines = totalLines
+	report.Covered = totalCovered
+	report.LineRate = rate(totalCovered, totalLines)
+	return report
+}
+
+// WriteCobertura writes a Cobertura XML coverage report for the
+// Profile.
+func WriteCobertura(out io.Writer, p *Profile, timestamp time.Time) error {
+	data, err := xml.MarshalIndent(GenerateCobertura(p, timestamp), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+	if _, err := out.Write(data); err != nil {
+		return err
+	}
+	_, err = out.Write([]byte{'\n'})
+	return err
+}
+
+func rate(covered, total int) string {
+	if total == 0 {
+		return "0"
+	}
+	return fmt.Sprintf("%.4f", float64(covered)/float64(total))
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package coverage
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/turbinelabs/test/assert"
+)
+
+func TestGenerateCobertura(t *testing.T) {
+	report := GenerateCobertura(mustParse(t, testProfile), time.Unix(1500000000, 0))
+	assert.Equal(t, report.Timestamp, int64(1500000000000))
+	assert.Equal(t, report.Lines, 16)
+	assert.Equal(t, report.Covered, 8)
+	assert.Equal(t, report.LineRate, "0.5000")
+	assert.Equal(t, len(report.Packages), 2)
+
+	foo := report.Packages[0]
+	assert.Equal(t, foo.Name, "example.com/foo")
+	assert.Equal(t, foo.LineRate, "0.3846")
+	assert.Equal(t, len(foo.Classes), 2)
+
+	a := foo.Classes[0]
+	assert.Equal(t, a.Name, "a.go")
+	assert.Equal(t, a.Filename, "example.com/foo/a.go")
+	assert.Equal(t, a.LineRate, "0.5000")
+
+	// line 14 is spanned by a covered and an uncovered block
+	assert.DeepEqual(t, a.Lines, []CoberturaLine{
+		{5, 1}, {6, 1}, {7, 1},
+		{9, 0}, {10, 0}, {11, 0},
+		{13, 1}, {14, 1}, {15, 0}, {16, 0},
+	})
+
+	bar := report.Packages[1]
+	assert.Equal(t, bar.Name, "example.com/foo/bar")
+	assert.Equal(t, bar.LineRate, "1.0000")
+}
+
+func TestWriteCobertura(t *testing.T) {
+	buf := &bytes.Buffer{}
+	assert.Nil(t, WriteCobertura(buf, mustParse(t, testProfile), time.Unix(1500000000, 0)))
+	assert.HasPrefix(t, buf.String(), xml.Header+"<coverage ")
+
+	var report CoberturaCoverage
+	assert.Nil(t, xml.Unmarshal(buf.Bytes(), &report))
+	assert.Equal(t, len(report.Packages), 2)
+	assert.Equal(t, len(report.Packages[0].Classes), 2)
+	assert.Equal(t, len(report.Packages[0].Classes[0].Lines), 10)
+}
@@ -0,0 +1,246 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package coverage reads go test coverage profiles (as written via
+// -test.coverprofile), summarizes them, and converts them to other
+// formats.
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+// matches a profile line, e.g. "a/b/c.go:10.2,12.16 3 1"
+var blockRegex = regexp.MustCompile(`^(.+):(\d+)\.(\d+),(\d+)\.(\d+) (\d+) (\d+)$`)
+
+// Block is a block of statements in a source file, and the number of
+// times it was executed.
+type Block struct {
+	File      string
+	StartLine int
+	StartCol  int
+	EndLine   int
+	EndCol    int
+	NumStmt   int
+	Count     int
+}
+
+type blockKey struct {
+	file                                 string
+	startLine, startCol, endLine, endCol int
+}
+
+func (b *Block) key() blockKey {
+	return blockKey{b.File, b.StartLine, b.StartCol, b.EndLine, b.EndCol}
+}
+
+// Profile is a coverage profile: a coverage mode ("set", "count" or
+// "atomic") and the blocks of the covered source files. Each block
+// appears once; see Merge.
+type Profile struct {
+	Mode   string
+	Blocks []*Block
+
+	index map[blockKey]*Block
+}
+
+// NewProfile returns an empty Profile with the given mode.
+func NewProfile(mode string) *Profile {
+	return &Profile{Mode: mode, index: map[blockKey]*Block{}}
+}
+
+// ReadProfile reads a coverage profile from the named file.
+func ReadProfile(filename string) (*Profile, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseProfile(f)
+}
+
+// ParseProfile parses a coverage profile. Repeated blocks, as written
+// for tests run more than once, are merged.
+func ParseProfile(r io.Reader) (*Profile, error) {
+	scanner := bufio.NewScanner(r)
+	var p *Profile
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if p == nil {
+			if !strings.HasPrefix(line, "mode: ") {
+				return nil, fmt.Errorf("line %d: expected coverage mode, got %q", lineNum, line)
+			}
+			p = NewProfile(strings.TrimPrefix(line, "mode: "))
+			continue
+		}
+
+		if strings.HasPrefix(line, "mode: ") {
+			// concatenated profiles
+			continue
+		}
+
+		m := blockRegex.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("line %d: malformed coverage block %q", lineNum, line)
+		}
+
+		ints := make([]int, 6)
+		for i := range ints {
+			v, err := strconv.Atoi(m[i+2])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", lineNum, err)
+			}
+			ints[i] = v
+		}
+
+		p.add(&Block{
+			File:      m[1],
+			StartLine: ints[0],
+			StartCol:  ints[1],
+			EndLine:   ints[2],
+			EndCol:    ints[3],
+			NumStmt:   ints[4],
+			Count:     ints[5],
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if p == nil {
+		return nil, fmt.Errorf("empty coverage profile")
+	}
+	return p, nil
+}
+
+// add adds a copy of the block, merging it with an existing block for
+// the same statements if present: in "set" mode a block is covered
+// if either is; otherwise, counts are summed.
+func (p *Profile) add(b *Block) {
+	if existing, ok := p.index[b.key()]; ok {
+		if p.Mode == "set" {
+			if b.Count > existing.Count {
+				existing.Count = b.Count
+			}
+		} else {
+			existing.Count += b.Count
+		}
+		return
+	}
+
+	copied := *b
+	p.index[b.key()] = &copied
+	p.Blocks = append(p.Blocks, &copied)
+}
+
+// Merge adds the blocks of another profile to this one.
+func (p *Profile) Merge(other *Profile) {
+	for _, b := range other.Blocks {
+		p.add(b)
+	}
+}
+
+// Packages returns the names of the packages whose source files
+// appear in the profile, in order.
+func (p *Profile) Packages() []string {
+	seen := map[string]bool{}
+	pkgs := []string{}
+	for _, b := range p.Blocks {
+		pkg := path.Dir(b.File)
+		if !seen[pkg] {
+			seen[pkg] = true
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	sort.Strings(pkgs)
+	return pkgs
+}
+
+// Package returns a profile containing only the blocks of the named
+// package's source files.
+func (p *Profile) Package(pkgName string) *Profile {
+	pkgProfile := NewProfile(p.Mode)
+	for _, b := range p.Blocks {
+		if path.Dir(b.File) == pkgName {
+			pkgProfile.add(b)
+		}
+	}
+	return pkgProfile
+}
+
+// Summary returns the statement coverage of each source file in the
+// profile and in total.
+func (p *Profile) Summary() *results.Coverage {
+	files := map[string]*results.FileCoverage{}
+	summary := &results.Coverage{}
+	for _, b := range p.Blocks {
+		f, ok := files[b.File]
+		if !ok {
+			f = &results.FileCoverage{Name: b.File}
+			files[b.File] = f
+			summary.Files = append(summary.Files, f)
+		}
+
+		f.Statements += b.NumStmt
+		summary.Statements += b.NumStmt
+		if b.Count > 0 {
+			f.Covered += b.NumStmt
+			summary.Covered += b.NumStmt
+		}
+	}
+
+	sort.Slice(summary.Files, func(i, j int) bool {
+		return summary.Files[i].Name < summary.Files[j].Name
+	})
+	return summary
+}
+
+// Write writes the profile in the format produced by go test.
+func (p *Profile) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "mode: %s\n", p.Mode)
+	for _, b := range p.Blocks {
+		fmt.Fprintf(
+			bw,
+			"%s:%d.%d,%d.%d %d %d\n",
+			b.File,
+			b.StartLine,
+			b.StartCol,
+			b.EndLine,
+			b.EndCol,
+			b.NumStmt,
+			b.Count,
+		)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package coverage
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/turbinelabs/test/assert"
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+const testProfile = `mode: set
+example.com/foo/a.go:5.13,7.2 1 1
+example.com/foo/a.go:9.13,11.2 1 0
+example.com/foo/a.go:13.20,14.12 1 1
+example.com/foo/a.go:14.12,16.3 1 0
+example.com/foo/b.go:3.13,5.2 2 0
+example.com/foo/bar/c.go:3.13,5.2 4 1
+`
+
+func mustParse(t *testing.T, profile string) *Profile {
+	p, err := ParseProfile(strings.NewReader(profile))
+	assert.Nil(t, err)
+	return p
+}
+
+func TestParseProfile(t *testing.T) {
+	p := mustParse(t, testProfile)
+	assert.Equal(t, p.Mode, "set")
+	assert.Equal(t, len(p.Blocks), 6)
+	assert.DeepEqual(t, p.Blocks[0], &Block{
+		File:      "example.com/foo/a.go",
+		StartLine: 5,
+		StartCol:  13,
+		EndLine:   7,
+		EndCol:    2,
+		NumStmt:   1,
+		Count:     1,
+	})
+}
+
+func TestParseProfileErrors(t *testing.T) {
+	_, err := ParseProfile(strings.NewReader(""))
+	assert.ErrorContains(t, err, "empty coverage profile")
+
+	_, err = ParseProfile(strings.NewReader("a.go:1.1,2.2 1 1\n"))
+	assert.ErrorContains(t, err, "line 1: expected coverage mode")
+
+	_, err = ParseProfile(strings.NewReader("mode: set\na.go:1.1,2.2 1\n"))
+	assert.ErrorContains(t, err, "line 2: malformed coverage block")
+}
+
+func TestParseProfileRepeatedBlocks(t *testing.T) {
+	// tests run with -count > 1 repeat their blocks
+	p := mustParse(t, "mode: count\na.go:1.1,2.2 1 3\na.go:1.1,2.2 1 2\n")
+	assert.Equal(t, len(p.Blocks), 1)
+	assert.Equal(t, p.Blocks[0].Count, 5)
+
+	p = mustParse(t, "mode: set\na.go:1.1,2.2 1 0\na.go:1.1,2.2 1 1\n")
+	assert.Equal(t, len(p.Blocks), 1)
+	assert.Equal(t, p.Blocks[0].Count, 1)
+}
+
+func TestProfileMerge(t *testing.T) {
+	p := mustParse(t, testProfile)
+	p.Merge(mustParse(t, "mode: set\nexample.com/foo/b.go:3.13,5.2 2 1\nexample.com/baz/d.go:1.1,2.2 1 1\n"))
+
+	assert.Equal(t, len(p.Blocks), 7)
+	assert.Equal(t, p.Blocks[4].Count, 1)
+	assert.Equal(t, p.Blocks[6].File, "example.com/baz/d.go")
+
+	// merging does not modify the merged profile's blocks
+	other := mustParse(t, "mode: set\nexample.com/foo/a.go:5.13,7.2 1 1\n")
+	p.Merge(other)
+	p.Blocks[0].Count = 7
+	assert.Equal(t, other.Blocks[0].Count, 1)
+}
+
+func TestProfilePackages(t *testing.T) {
+	p := mustParse(t, testProfile)
+	assert.ArrayEqual(t, p.Packages(), []string{"example.com/foo", "example.com/foo/bar"})
+
+	pkg := p.Package("example.com/foo/bar")
+	assert.Equal(t, pkg.Mode, "set")
+	assert.Equal(t, len(pkg.Blocks), 1)
+	assert.Equal(t, pkg.Blocks[0].File, "example.com/foo/bar/c.go")
+}
+
+func TestProfileSummary(t *testing.T) {
+	summary := mustParse(t, testProfile).Summary()
+	assert.DeepEqual(t, summary, &results.Coverage{
+		Statements: 10,
+		Covered:    6,
+		Files: []*results.FileCoverage{
+			{Name: "example.com/foo/a.go", Statements: 4, Covered: 2},
+			{Name: "example.com/foo/b.go", Statements: 2, Covered: 0},
+			{Name: "example.com/foo/bar/c.go", Statements: 4, Covered: 4},
+		},
+	})
+	assert.Equal(t, summary.Percent(), 60.0)
+}
+
+func TestProfileWrite(t *testing.T) {
+	buf := &bytes.Buffer{}
+	assert.Nil(t, mustParse(t, testProfile).Write(buf))
+	assert.Equal(t, buf.String(), testProfile)
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/turbinelabs/test/assert"
+	"github.com/turbinelabs/test/tempfile"
+	"github.com/turbinelabs/test/testrunner/parser"
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+func TestFlagValue(t *testing.T) {
+	args := []string{"-test.v", "-test.coverprofile", "c.out", "-test.outputdir=/tmp"}
+
+	value, ok := flagValue(args, "-test.coverprofile")
+	assert.True(t, ok)
+	assert.Equal(t, value, "c.out")
+
+	value, ok = flagValue(args, "-test.outputdir")
+	assert.True(t, ok)
+	assert.Equal(t, value, "/tmp")
+
+	_, ok = flagValue(args, "-test.run")
+	assert.False(t, ok)
+
+	_, ok = flagValue([]string{"-test.coverprofile"}, "-test.coverprofile")
+	assert.False(t, ok)
+}
+
+func TestNewCoverProfile(t *testing.T) {
+	profile := newCoverProfile(
+		[]string{"-test.coverprofile=c.out", "-test.outputdir=/tmp/out"},
+		"-test.coverprofile",
+		"-test.outputdir",
+	)
+	assert.Equal(t, profile, coverProfile{path: "/tmp/out/c.out"})
+
+	profile = newCoverProfile([]string{"-test.v"}, "-test.coverprofile", "-test.outputdir")
+	defer os.Remove(profile.path)
+	assert.True(t, profile.temporary)
+	assert.True(t, filepath.IsAbs(profile.path))
+}
+
+func TestWithCoverProfile(t *testing.T) {
+	p := withCoverProfile(parser.GoLangParser, coverProfile{path: "/tmp/c.out", temporary: true})
+	assert.ArrayEqual(
+		t,
+		p.FlagFn([]string{"-test.run=X"}),
+		[]string{"-test.run=X", "-test.coverprofile=/tmp/c.out", "-test.v=true"},
+	)
+
+	// an explicitly requested profile is left alone
+	p = withCoverProfile(parser.GoLangParser, coverProfile{path: "c.out"})
+	assert.ArrayEqual(
+		t,
+		p.FlagFn([]string{"-test.coverprofile=c.out"}),
+		[]string{"-test.coverprofile=c.out", "-test.v=true"},
+	)
+}
+
+func TestRecordCoverage(t *testing.T) {
+	dir := tempfile.TempDir(t, "testrunner-coverage")
+	defer dir.Cleanup()
+
+	saved := TestOutput
+	defer func() {
+		TestOutput = saved
+	}()
+	TestOutput = dir.Path()
+
+	profilePath := dir.Write(
+		t,
+		"mode: set\nexample.com/a/a.go:1.1,2.2 3 1\nexample.com/a/a.go:3.1,4.2 1 0\n",
+		"profile",
+	)
+
+	// the package name does not match the profile
+	pkg := &results.TestPackage{Name: "tmp/a"}
+	recordCoverage("tmp/a", []*results.TestPackage{pkg}, coverProfile{path: profilePath, temporary: true})
+
+	assert.NonNil(t, pkg.Coverage)
+	assert.Equal(t, pkg.Coverage.Statements, 4)
+	assert.Equal(t, pkg.Coverage.Covered, 3)
+
+	_, err := os.Stat(profilePath)
+	assert.True(t, os.IsNotExist(err))
+
+	contents, err := ioutil.ReadFile(filepath.Join(dir.Path(), "tmp.a"+CoverProfileExtension))
+	assert.Nil(t, err)
+	assert.HasPrefix(t, string(contents), "mode: set\n")
+
+	// each package gets its own coverage
+	profilePath = dir.Write(
+		t,
+		"mode: set\nexample.com/a/a.go:1.1,2.2 3 1\nexample.com/b/b.go:1.1,2.2 1 0\n",
+		"profile",
+	)
+	pkgA := &results.TestPackage{Name: "example.com/a"}
+	pkgB := &results.TestPackage{Name: "example.com/b"}
+	recordCoverage("example.com", []*results.TestPackage{pkgA, pkgB}, coverProfile{path: profilePath})
+	assert.Equal(t, pkgA.Coverage.Percent(), 100.0)
+	assert.Equal(t, pkgB.Coverage.Percent(), 0.0)
+
+	// missing profiles are ignored
+	pkg = &results.TestPackage{Name: "tmp/a"}
+	recordCoverage("tmp/a", []*results.TestPackage{pkg}, coverProfile{path: profilePath + ".missing"})
+	assert.Nil(t, pkg.Coverage)
+}
+
+func TestWriteCobertura(t *testing.T) {
+	dir := tempfile.TempDir(t, "testrunner-cobertura")
+	defer dir.Cleanup()
+
+	savedOutput, savedCobertura := TestOutput, Cobertura
+	defer func() {
+		TestOutput, Cobertura = savedOutput, savedCobertura
+	}()
+	TestOutput = dir.Path()
+	Cobertura = "coverage.xml"
+
+	// no profiles, no report
+	writeCobertura()
+	_, err := os.Stat(filepath.Join(dir.Path(), "coverage.xml"))
+	assert.True(t, os.IsNotExist(err))
+
+	for _, name := range []string{"a", "b"} {
+		err := ioutil.WriteFile(
+			filepath.Join(dir.Path(), name+CoverProfileExtension),
+			[]byte("mode: set\nexample.com/"+name+"/x.go:1.1,2.2 1 1\n"),
+			0644,
+		)
+		assert.Nil(t, err)
+	}
+
+	writeCobertura()
+	contents, err := ioutil.ReadFile(filepath.Join(dir.Path(), "coverage.xml"))
+	assert.Nil(t, err)
+	assert.StringContains(t, string(contents), `<package name="example.com/a"`)
+	assert.StringContains(t, string(contents), `<package name="example.com/b"`)
+
+	// subdirectories are created
+	Cobertura = filepath.Join("reports", "coverage.xml")
+	writeCobertura()
+	_, err = os.Stat(filepath.Join(dir.Path(), "reports", "coverage.xml"))
+	assert.Nil(t, err)
+
+	// absolute paths are used as given
+	other := tempfile.TempDir(t, "testrunner-cobertura-abs")
+	defer other.Cleanup()
+
+	Cobertura = filepath.Join(other.Path(), "reports", "coverage.xml")
+	writeCobertura()
+	_, err = os.Stat(Cobertura)
+	assert.Nil(t, err)
+}
@@ -24,6 +24,30 @@ limitations under the License.
   are passed to that executable (in a subprocess).  Specific test
   parsers may modify the arguments passed to the subprocess.
 
+  Alternatively, testrunner may run go test itself, against one or
+  more packages, if its first argument is "go":
+
+    testrunner go test ./...
+
+  Or it may parse the output of go test from its standard input if its
+  first argument is "-":
+
+    go test -v ./... | testrunner -
+
+  In either case, a package's results are reported under the package
+  name found in go test's output (e.g. "ok  github.com/foo/bar"). A
+  separate report is written for each package.
+
+  The junit reports in TEST_RUNNER_OUTPUT may be combined into a
+  single report with the merge command (see Merging Reports, below):
+
+    testrunner merge [output-file]
+
+  And the history of each test's results may be summarized with the
+  history command (see History, below):
+
+    testrunner history [-runs N] [-top N] [package ...]
+
 
   Environment Variables
 
@@ -42,11 +66,298 @@ limitations under the License.
   TEST_RUNNER_OUTPUT (default: "testresults") - Sets the path to the
   directory where formatted test results are written.
 
+  TEST_RUNNER_PARSER (default: "golang") - Selects the parser used to
+  interpret the test executable's output. See below.
+
+  TEST_RUNNER_FORMATS (default: "junit") - A comma-separated list of
+  the formats in which reports are written. See below.
+
+  TEST_RUNNER_ENV_PROPERTIES (default: "") - A comma-separated list of
+  environment variables (e.g. CI build identifiers) to record in
+  junit reports. See below.
+
+  TEST_RUNNER_SHARD_INDEX (default: "0") and TEST_RUNNER_SHARD_COUNT
+  (default: "1") - Select the share of each package's tests run by
+  this worker. See below.
+
+  TEST_RUNNER_PROGRESS (default: "output") - Selects how progress is
+  shown while tests run: "output" copies the test output to stdout and
+  stderr; "status" shows a status line instead. See below.
+
+  TEST_RUNNER_SLOW_TEST (default: "") - A duration (e.g. "30s") after
+  which a running test is reported as slow. See below.
+
+  TEST_RUNNER_HISTORY (default: "false") - Whether each run's results
+  are appended to the history file in TEST_RUNNER_OUTPUT. See below.
+
+
+  Configuration File
+
+  Any of the TEST_RUNNER_ settings described here may also be given
+  in a JSON configuration file named ".testrunner.json", found in the
+  working directory or its closest ancestor containing one.
+  Environment variables override the file. Each setting's key is its
+  environment variable's name, without the "TEST_RUNNER_" prefix, in
+  lower case. Values may be strings, numbers, booleans, or arrays of
+  strings (for comma-separated lists). Relative "output" and
+  "bench_baseline" directories are relative to the file's directory.
+  The file must give its format version, which is currently 1:
+
+    {
+      "version": 1,
+      "output": "testresults",
+      "formats": ["junit", "json"],
+      "retries": 2,
+      "timeout": "10m"
+    }
+
+  Invalid settings, whether from the environment or the configuration
+  file, are reported (naming the environment variable or file and
+  key) before any tests run, and testrunner exits with status 2.
+
 
   Parsers
 
-  Currently only a single parser exists. It parses go-style test
-  verbose output. It modifies test command line arguments to include
-  the verbose flag ("-test.v=true").
+  The "golang" parser parses go-style test verbose output. It modifies
+  test command line arguments to include the verbose flag
+  ("-test.v=true"). Output from parallel tests is attributed using the
+  "=== PAUSE", "=== CONT" and "=== NAME" markers go test emits when
+  output switches between running tests.
+
+  The "json" parser pipes the test executable's output through "go
+  tool test2json" and parses the resulting stream of JSON events.
+  Because each event names its test, output from parallel tests,
+  subtests and benchmarks is attributed correctly. It modifies test
+  command line arguments to include "-test.v=test2json" and requires
+  the go tool to be on the PATH.
+
+
+  Progress
+
+  Test output is parsed as it is written. By default, it is also
+  copied to stdout and stderr. If TEST_RUNNER_PROGRESS is "status",
+  a status line counting the tests that have passed, failed, been
+  skipped, or are running (naming the longest running test) is shown
+  on stderr instead. On a terminal, the line is redrawn in place;
+  otherwise a new line is written, at most every 10 seconds. The full
+  output is still included in reports.
+
+  If TEST_RUNNER_SLOW_TEST is set, a warning is written to stderr,
+  once, for each top-level test that runs longer than that duration.
+  A parallel test's time spent paused is not counted.
+
+
+  Report Formats
+
+  A report is written for each package in each format named by
+  TEST_RUNNER_FORMATS. Report files are named after the package, with
+  an extension that depends on the format (e.g. "a.b.c.xml" for
+  package "a/b/c"):
+
+    junit    - junit-style XML (".xml")
+    tap      - Test Anything Protocol, version 13 (".tap")
+    json     - JSON, using the versioned schema described by
+               report.JSONReport (".json")
+    markdown - a human-readable summary listing failed tests first,
+               followed by the slowest tests (".md")
+
+  junit reports follow the Jenkins JUnit schema. Each package's test
+  suite records when and on which host its tests ran, and has
+  properties describing the Go environment ("go.os", "go.arch" and
+  "go.version", that of testrunner itself) and the environment
+  variables named by TEST_RUNNER_ENV_PROPERTIES ("env.<name>"). A
+  package that failed without any failing tests is reported as an
+  error of a synthetic test case: "[build]" if the package did not
+  build, or "[package]" otherwise.
+
+
+  Build Failures
+
+  If a package's tests do not build, or go vet (run by go test) finds
+  problems, both parsers collect the compiler and vet diagnostics
+  ("file.go:12:3: message") from the package's output. In junit
+  reports, they are listed, one per line, by the error of the
+  package's "[build]" test case; json reports list them as the
+  package's "buildErrors".
+
+
+  Benchmarks
+
+  Both parsers capture benchmark results (when benchmarks are enabled
+  via "-test.bench"). Each benchmark metric (ns/op, B/op, allocs/op
+  and any custom metrics) is reported as a property of the package's
+  test suite, named "benchmark.<benchmark> <unit>".
+
+  If TEST_RUNNER_BENCH_TOLERANCE is set to a percentage, benchmark
+  results are compared with those in the package's previous junit
+  report in TEST_RUNNER_BENCH_BASELINE (default: TEST_RUNNER_OUTPUT).
+  Each benchmark's ns/op, B/op and allocs/op are compared. If a metric's
+  mean over repeated runs (see "-test.count") exceeds the baseline's
+  mean by more than the tolerance, the regression is reported as a
+  failed test and the package fails. When both runs repeat the
+  benchmark, the means must also differ by more than one standard
+  deviation on each side.
+
+
+  Panics
+
+  If a test panics, or the test executable crashes with a fatal
+  runtime error, the test executable exits without reporting the
+  results of the tests still running. Both parsers attribute the
+  crash to those tests or, if go test has already reported the
+  panicking test's failure, to that test. They are reported as
+  failures of type "panic", with the panic and its goroutine trace as
+  their failure message.
+
+
+  Retries
+
+  If TEST_RUNNER_RETRIES is set to a positive number, failed tests are
+  rerun, up to that many times, until they pass. Only failed top-level
+  tests are rerun (via "-test.run"); benchmarks are not. Tests that
+  pass when retried are reported as flaky rather than failed: in junit
+  reports, their original failure is reported as a "flakyFailure" and
+  a "flaky.<test>" suite property records the number of retries. If
+  every failed test turns out to be flaky, the package passes.
+  Retries are only supported when running a test executable.
+
+
+  Coverage
+
+  If TEST_RUNNER_COVERAGE is true, testrunner captures a coverage
+  profile from each run, adding "-test.coverprofile" to the test
+  executable's arguments (or "-coverprofile" to go test's), unless
+  one was already given. The test executable must have been built
+  with coverage enabled (e.g. "go test -c -cover"). Each package's
+  statement coverage is reported as properties of its test suite:
+  "coverage.statements", "coverage.covered" and "coverage.percent",
+  plus "coverage.file.<file>" with the percentage covered of each
+  source file. The profile itself is saved in TEST_RUNNER_OUTPUT,
+  named after the package with the extension ".cover".
+
+  If TEST_RUNNER_COBERTURA is set to a file name, the profiles saved
+  in TEST_RUNNER_OUTPUT (including those from previous runs) are
+  merged into a Cobertura XML report written to that file. A relative
+  path is relative to TEST_RUNNER_OUTPUT. Directories in the path are
+  created as needed. Setting it implies TEST_RUNNER_COVERAGE.
+  Coverage is not captured when parsing go test output from standard
+  input.
+
+
+  Resource Usage
+
+  When running a test executable, the CPU time it used (user and
+  system), its wall time and its peak resident set size are reported
+  as properties of its test suite: "usage.user_time",
+  "usage.system_time" and "usage.wall_time", in seconds, and
+  "usage.max_rss", in bytes. The peak resident set size is only known
+  on Linux, the BSDs and macOS. When tests are retried, the times of
+  every run are summed and the largest peak is reported. Resource
+  usage is not recorded when running go test, whose own usage cannot
+  be separated from that of the packages it tests.
+
+
+  Sharding
+
+  If TEST_RUNNER_SHARD_COUNT is greater than 1, a package's tests are
+  divided among that many workers, and only the share of the worker
+  given by TEST_RUNNER_SHARD_INDEX (counting from 0) is run, via
+  "-test.run". testrunner lists the test executable's tests (with
+  "-test.list", honoring any "-test.run" flag given) and assigns the
+  longest to the least loaded shard, using the tests' durations in the
+  merged report (see Merging Reports) found in TEST_RUNNER_OUTPUT as
+  "merged.xml". Package reports are ignored, since each covers only
+  one shard's tests. Tests without a recorded duration are assumed to
+  take the average time. Every worker must see the same merged report,
+  or the shards may overlap or miss tests. Without one, tests are
+  assigned by name alone. Only top-level tests, examples and fuzz
+  targets are sharded; benchmarks run on every shard. Sharding is only
+  supported when running a test executable.
+
+
+  Timeouts
+
+  TEST_RUNNER_TIMEOUT and TEST_RUNNER_TEST_TIMEOUT limit how long a
+  test executable, and any single test within it, may run. Each is a
+  duration such as "90s" or "10m"; by default neither is enforced.
+  When a limit is exceeded, the test executable is sent SIGQUIT (and
+  killed if it has not exited 10 seconds later). The tests running at
+  the time are reported as failures of type "timeout", with the
+  resulting goroutine dump as their failure message. Tests running
+  when the test executable's own timeout ("-test.timeout") expires
+  are reported the same way.
+
+  When running go test, TEST_RUNNER_TIMEOUT is passed to go test as
+  its -timeout flag, unless that flag is already given, and
+  TEST_RUNNER_TEST_TIMEOUT is not supported.
+
+
+  Merging Reports
+
+  "testrunner merge" reads the junit reports in TEST_RUNNER_OUTPUT
+  (skipping, with a warning, any XML file that is not one) and writes
+  a single report combining them to the given file or, by default, to
+  "merged.xml" in TEST_RUNNER_OUTPUT. Suites with the same name are
+  combined into one: test cases reported by more than one of them are
+  taken from the most recent run, and copies of the same run (with
+  the same timestamp and hostname) are counted once. Totals are
+  recomputed. A one-line summary is printed to standard output, and
+  the command exits with a non-zero status if any test failed or
+  reported an error.
+
+
+  History
+
+  If TEST_RUNNER_HISTORY is true, the results of each package's tests
+  (including subtests) are appended to "history.jsonl" in
+  TEST_RUNNER_OUTPUT, one JSON record per package per run, whereas
+  reports are overwritten by the next run. Records are written whole,
+  so concurrent testrunners may share the file. The file grows with
+  every run; it is never trimmed, so remove it to start over.
+
+  "testrunner history" summarizes each test's last 20 runs (or
+  "-runs N"; skipped runs are not counted) recorded in the file,
+  optionally only for the named packages, and prints a table of the
+  20 flakiest tests (or "-top N"; 0 prints every test): the number of
+  runs, the number (and fraction) that failed or were flaky, the
+  number of flips between passing and failing (and their fraction of
+  consecutive runs), the mean duration, and the duration trend (the
+  change in mean duration between the older and newer halves of the
+  runs). Tests are ordered by flip rate, then failure rate. Unreadable
+  records are skipped with a warning.
+
+
+  Interrupts
+
+  The test executable (or go test) runs in its own process group.
+  SIGINT and SIGTERM received by testrunner are forwarded to that
+  group, so that the tests (and any processes they started) stop
+  rather than being orphaned. testrunner itself waits for them to
+  exit, then writes its reports as usual: tests that were running or
+  paused at the time, and did not go on to pass, are reported as
+  failures of type "interrupted", and failed tests are not retried.
+  Signals are not forwarded when parsing go test output from standard
+  input. On Windows, the test executable is killed instead.
+
+
+  Exit Status
+
+  testrunner exits with one of the following statuses:
+
+    0      every test passed
+    1      a test failed, or a package failed to build
+    2      invalid arguments or configuration
+    3      the test output could not be parsed, or the tests exited
+           with a non-zero status although their output reports no
+           failure
+    4      a test or test executable timed out
+    5      testrunner could not run the tests, or failed itself
+    128+n  testrunner was interrupted by signal n (e.g. 130 for
+           SIGINT)
+
+  An interruption takes precedence over a timeout, and a timeout over
+  other failures. The merge command exits with status 1 if any test
+  failed, and the history command with status 5 if the history cannot
+  be read.
 */
 package main
@@ -0,0 +1,123 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime/debug"
+	"syscall"
+
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+// testrunner's exit statuses. A run interrupted by a signal exits with
+// 128 plus the signal's number, as a shell reports a process killed by
+// it.
+const (
+	exitPassed      = 0 // every test passed
+	exitFailed      = 1 // a test failed, or a package failed to build
+	exitUsage       = 2 // invalid arguments or configuration
+	exitParseError  = 3 // the test output could not be parsed
+	exitTimeout     = 4 // a test or test executable timed out
+	exitRunnerError = 5 // testrunner itself failed
+	exitSignalBase  = 128
+)
+
+// exitStatusOf returns the exit status of a command given the error
+// returned when waiting for it. A command killed by a signal has the
+// status a shell would report (128 plus the signal's number). Errors
+// other than a non-zero exit status are returned.
+func exitStatusOf(err error) (int, error) {
+	if err == nil {
+		return 0, nil
+	}
+
+	e, ok := err.(*exec.ExitError)
+	if !ok {
+		return 0, err
+	}
+
+	switch sysStatus := e.ProcessState.Sys().(type) {
+	case syscall.WaitStatus:
+		if sysStatus.Signaled() {
+			return exitSignalBase + int(sysStatus.Signal()), nil
+		}
+		return sysStatus.ExitStatus(), nil
+	default:
+		return 1, nil
+	}
+}
+
+// runExitStatus returns testrunner's exit status given how the test
+// command's last run ended and the packages' results. An interruption
+// takes precedence over a timeout, and a timeout over other failures.
+// A test command that fails although no package did indicates that
+// its output was not understood.
+func runExitStatus(run testRun, pkgs []*results.TestPackage) int {
+	if run.signal != nil {
+		if s, ok := run.signal.(syscall.Signal); ok {
+			return exitSignalBase + int(s)
+		}
+		return exitSignalBase + int(syscall.SIGINT)
+	}
+
+	failed, timedOut := false, run.timeout != ""
+	for _, pkg := range pkgs {
+		if pkg.Result == results.Failed {
+			failed = true
+		}
+		for _, t := range pkg.AllTests() {
+			if t.Result == results.Failed && t.FailureKind == results.Timeout {
+				timedOut = true
+			}
+		}
+	}
+
+	switch {
+	case timedOut:
+		return exitTimeout
+	case failed:
+		return exitFailed
+	case run.exitStatus != 0:
+		fmt.Fprintf(
+			os.Stderr,
+			"testrunner: the tests exited with status %d, but their output reports no failure\n",
+			run.exitStatus,
+		)
+		return exitParseError
+	default:
+		return exitPassed
+	}
+}
+
+// exitOnPanic reports a panic (e.g. an I/O error writing a report) as
+// a runner error, with its stack trace, and exits. It must be
+// deferred.
+func exitOnPanic() {
+	if r := recover(); r != nil {
+		fmt.Fprintf(os.Stderr, "testrunner: %v\n\n%s", r, debug.Stack())
+		os.Exit(exitRunnerError)
+	}
+}
+
+// exitWithRunnerError reports an error running the tests and exits.
+func exitWithRunnerError(err error) {
+	fmt.Fprintf(os.Stderr, "testrunner: %v\n", err)
+	os.Exit(exitRunnerError)
+}
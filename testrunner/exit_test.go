@@ -0,0 +1,91 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/turbinelabs/test/assert"
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+func TestExitStatusOf(t *testing.T) {
+	status, err := exitStatusOf(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, status, 0)
+
+	status, err = exitStatusOf(errors.New("boom"))
+	assert.ErrorContains(t, err, "boom")
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+
+	status, err = exitStatusOf(exec.Command("sh", "-c", "exit 3").Run())
+	assert.Nil(t, err)
+	assert.Equal(t, status, 3)
+
+	status, err = exitStatusOf(exec.Command("sh", "-c", "kill -TERM $$").Run())
+	assert.Nil(t, err)
+	assert.Equal(t, status, 128+int(syscall.SIGTERM))
+}
+
+func TestRunExitStatus(t *testing.T) {
+	passed := &results.TestPackage{
+		Result: results.Passed,
+		Tests:  []*results.Test{{Name: "TestA", Result: results.Passed}},
+	}
+	failed := &results.TestPackage{
+		Result: results.Failed,
+		Tests:  []*results.Test{{Name: "TestA", Result: results.Failed}},
+	}
+	timedOut := &results.TestPackage{
+		Result: results.Failed,
+		Tests: []*results.Test{
+			{
+				Name:   "TestA",
+				Result: results.Passed,
+				Subtests: []*results.Test{
+					{Name: "TestA/b", Result: results.Failed, FailureKind: results.Timeout},
+				},
+			},
+		},
+	}
+
+	for _, tc := range []struct {
+		run    testRun
+		pkgs   []*results.TestPackage
+		status int
+	}{
+		{testRun{}, []*results.TestPackage{passed}, exitPassed},
+		{testRun{exitStatus: 1}, []*results.TestPackage{passed, failed}, exitFailed},
+		{testRun{exitStatus: 1}, []*results.TestPackage{timedOut}, exitTimeout},
+		{testRun{timeout: "too slow"}, []*results.TestPackage{failed}, exitTimeout},
+		{testRun{exitStatus: 2}, []*results.TestPackage{passed}, exitParseError},
+		{
+			testRun{exitStatus: 1, timeout: "too slow", signal: syscall.SIGTERM},
+			[]*results.TestPackage{timedOut},
+			128 + int(syscall.SIGTERM),
+		},
+	} {
+		assert.Equal(t, runExitStatus(tc.run, tc.pkgs), tc.status)
+	}
+}
@@ -0,0 +1,373 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+const (
+	// HistoryFile is the name of the file, in the output directory,
+	// to which the results of every run are appended.
+	HistoryFile = "history.jsonl"
+
+	// HistoryVersion is the version of the history record format.
+	HistoryVersion = 1
+)
+
+// historyRecord is a line of the history file: the results of a
+// single run of a package's tests.
+type historyRecord struct {
+	Version  int           `json:"version"`
+	Package  string        `json:"package"`
+	Start    time.Time     `json:"start"`
+	Hostname string        `json:"hostname,omitempty"`
+	Result   string        `json:"result"`
+	Duration float64       `json:"duration"` // seconds
+	Tests    []historyTest `json:"tests"`    // including subtests
+}
+
+type historyTest struct {
+	Name     string  `json:"name"`
+	Result   string  `json:"result"`   // "pass", "fail", "skip", or "flaky"
+	Duration float64 `json:"duration"` // seconds
+}
+
+var historyResults = map[results.TestResult]string{
+	results.Passed:  "pass",
+	results.Failed:  "fail",
+	results.Skipped: "skip",
+	results.Flaky:   "flaky",
+}
+
+// getHistory returns whether results are appended to the history
+// file.
+func getHistory() (bool, error) {
+	enabled, err := strconv.ParseBool(History)
+	if err != nil {
+		return false, invalidSetting(ENV_HISTORY, History, "is not a boolean")
+	}
+	return enabled, nil
+}
+
+// newHistoryRecord converts a package's results into a history
+// record.
+func newHistoryRecord(pkg *results.TestPackage) historyRecord {
+	record := historyRecord{
+		Version:  HistoryVersion,
+		Package:  pkg.Name,
+		Start:    pkg.Start,
+		Result:   historyResults[pkg.Result],
+		Duration: pkg.Duration,
+		Tests:    []historyTest{},
+	}
+	if pkg.Environment != nil {
+		record.Hostname = pkg.Environment.Hostname
+	}
+
+	var add func(tests []*results.Test)
+	add = func(tests []*results.Test) {
+		for _, t := range tests {
+			record.Tests = append(
+				record.Tests,
+				historyTest{
+					Name:     t.Name,
+					Result:   historyResults[t.Result],
+					Duration: t.Duration,
+				},
+			)
+			add(t.Subtests)
+		}
+	}
+	add(pkg.Tests)
+
+	return record
+}
+
+// appendHistory appends a record of each package's results to the
+// history file in the output directory. Each record is written with a
+// single write, so that concurrent testrunners may share the file.
+func appendHistory(pkgs []*results.TestPackage) {
+	if err := os.MkdirAll(TestOutput, 0755); err != nil {
+		panic(err)
+	}
+
+	path := filepath.Join(TestOutput, HistoryFile)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	for _, pkg := range pkgs {
+		line, err := json.Marshal(newHistoryRecord(pkg))
+		if err != nil {
+			panic(err)
+		}
+		if _, err := f.Write(append(line, '\n')); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// readHistory reads history records, in the order they were run.
+// Lines that cannot be parsed (e.g. one truncated by a crash) or that
+// have another version are skipped and counted.
+func readHistory(r io.Reader) ([]historyRecord, int, error) {
+	records := []historyRecord{}
+	skipped := 0
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var record historyRecord
+		if err := json.Unmarshal(line, &record); err != nil || record.Version != HistoryVersion {
+			skipped++
+			continue
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	// concurrent runs may finish out of order
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Start.Before(records[j].Start)
+	})
+
+	return records, skipped, nil
+}
+
+// testHistory summarizes a test's recent runs. Runs in which the test
+// was skipped are not counted.
+type testHistory struct {
+	pkg       string
+	name      string
+	runs      int
+	failures  int       // runs that failed or were flaky
+	flips     int       // changes between passing and failing
+	durations []float64 // seconds, oldest first
+}
+
+// failureRate returns the fraction of runs that failed.
+func (h *testHistory) failureRate() float64 {
+	if h.runs == 0 {
+		return 0
+	}
+	return float64(h.failures) / float64(h.runs)
+}
+
+// flipRate returns the fraction of consecutive runs whose outcomes
+// differ.
+func (h *testHistory) flipRate() float64 {
+	if h.runs < 2 {
+		return 0
+	}
+	return float64(h.flips) / float64(h.runs-1)
+}
+
+// meanDuration returns the test's mean duration, in seconds.
+func (h *testHistory) meanDuration() float64 {
+	return mean(h.durations)
+}
+
+// trend returns the relative change in the test's mean duration
+// between the older and newer halves of its runs (e.g. 0.1 if it has
+// become 10% slower), or false if there are too few runs, or they
+// took no time.
+func (h *testHistory) trend() (float64, bool) {
+	half := len(h.durations) / 2
+	if half == 0 {
+		return 0, false
+	}
+
+	older := mean(h.durations[:half])
+	newer := mean(h.durations[len(h.durations)-half:])
+	if older == 0 {
+		return 0, false
+	}
+	return (newer - older) / older, true
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total / float64(len(values))
+}
+
+// summarizeHistory summarizes each test's last maxRuns runs in the
+// records, flakiest first: by flip rate, then failure rate. Only
+// records of the given packages are considered, unless none are
+// given.
+func summarizeHistory(records []historyRecord, maxRuns int, pkgs []string) []*testHistory {
+	include := map[string]bool{}
+	for _, pkg := range pkgs {
+		include[pkg] = true
+	}
+
+	type run struct {
+		failed   bool
+		duration float64
+	}
+
+	type key struct{ pkg, name string }
+	runs := map[key][]run{}
+	for _, record := range records {
+		if len(include) > 0 && !include[record.Package] {
+			continue
+		}
+		for _, t := range record.Tests {
+			if t.Result == "skip" {
+				continue
+			}
+			k := key{record.Package, t.Name}
+			runs[k] = append(runs[k], run{t.Result != "pass", t.Duration})
+		}
+	}
+
+	summaries := make([]*testHistory, 0, len(runs))
+	for k, testRuns := range runs {
+		if maxRuns > 0 && len(testRuns) > maxRuns {
+			testRuns = testRuns[len(testRuns)-maxRuns:]
+		}
+
+		h := &testHistory{pkg: k.pkg, name: k.name, runs: len(testRuns)}
+		for i, r := range testRuns {
+			if r.failed {
+				h.failures++
+			}
+			if i > 0 && r.failed != testRuns[i-1].failed {
+				h.flips++
+			}
+			h.durations = append(h.durations, r.duration)
+		}
+		summaries = append(summaries, h)
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		a, b := summaries[i], summaries[j]
+		switch {
+		case a.flipRate() != b.flipRate():
+			return a.flipRate() > b.flipRate()
+		case a.failureRate() != b.failureRate():
+			return a.failureRate() > b.failureRate()
+		case a.pkg != b.pkg:
+			return a.pkg < b.pkg
+		default:
+			return a.name < b.name
+		}
+	})
+	return summaries
+}
+
+// writeHistory writes a table of test histories.
+func writeHistory(out io.Writer, summaries []*testHistory) {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "PACKAGE\tTEST\tRUNS\tFAILURES\tFLIPS\tMEAN\tTREND")
+	for _, h := range summaries {
+		trend := "-"
+		if t, ok := h.trend(); ok {
+			trend = fmt.Sprintf("%+.1f%%", t*100)
+		}
+
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%d\t%d (%.1f%%)\t%d (%.1f%%)\t%.3fs\t%s\n",
+			h.pkg,
+			h.name,
+			h.runs,
+			h.failures,
+			h.failureRate()*100,
+			h.flips,
+			h.flipRate()*100,
+			h.meanDuration(),
+			trend,
+		)
+	}
+	w.Flush()
+}
+
+// runHistory implements the history command: it summarizes the recent
+// runs of each test recorded in the history file, flakiest first.
+// Returns exitRunnerError if the history cannot be read.
+func runHistory(args []string) int {
+	flags := flag.NewFlagSet("testrunner history", flag.ContinueOnError)
+	maxRuns := flags.Int("runs", 20, "summarize each test's last `N` runs (0 for all)")
+	top := flags.Int("top", 20, "show the flakiest `N` tests (0 for all)")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: testrunner history [-runs N] [-top N] [package ...]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	errs := append([]error{}, configErrs...)
+	if err := checkOutputDir(); err != nil {
+		errs = append(errs, err)
+	}
+	if reportErrors(errs) {
+		return exitUsage
+	}
+
+	path := filepath.Join(TestOutput, HistoryFile)
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "testrunner: %v\n", err)
+		return exitRunnerError
+	}
+	defer f.Close()
+
+	records, skipped, err := readHistory(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "testrunner: %s: %v\n", path, err)
+		return exitRunnerError
+	}
+	if skipped > 0 {
+		fmt.Fprintf(os.Stderr, "testrunner: skipped %d unreadable records in %s\n", skipped, path)
+	}
+
+	summaries := summarizeHistory(records, *maxRuns, flags.Args())
+	if *top > 0 && len(summaries) > *top {
+		summaries = summaries[:*top]
+	}
+	writeHistory(os.Stdout, summaries)
+	return exitPassed
+}
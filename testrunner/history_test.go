@@ -0,0 +1,231 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/turbinelabs/test/assert"
+	"github.com/turbinelabs/test/tempfile"
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+func TestNewHistoryRecord(t *testing.T) {
+	start := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	record := newHistoryRecord(&results.TestPackage{
+		Name:        "example.com/a",
+		Result:      results.Failed,
+		Start:       start,
+		Duration:    1.5,
+		Environment: &results.Environment{Hostname: "builder-1"},
+		Tests: []*results.Test{
+			{
+				Name:     "TestA",
+				Result:   results.Failed,
+				Duration: 1.0,
+				Subtests: []*results.Test{
+					{Name: "TestA/x", Result: results.Passed, Duration: 0.25},
+					{Name: "TestA/y", Result: results.Failed, Duration: 0.75},
+				},
+			},
+			{Name: "TestB", Result: results.Flaky, Duration: 0.5},
+			{Name: "TestC", Result: results.Skipped},
+		},
+	})
+
+	assert.DeepEqual(t, record, historyRecord{
+		Version:  HistoryVersion,
+		Package:  "example.com/a",
+		Start:    start,
+		Hostname: "builder-1",
+		Result:   "fail",
+		Duration: 1.5,
+		Tests: []historyTest{
+			{Name: "TestA", Result: "fail", Duration: 1.0},
+			{Name: "TestA/x", Result: "pass", Duration: 0.25},
+			{Name: "TestA/y", Result: "fail", Duration: 0.75},
+			{Name: "TestB", Result: "flaky", Duration: 0.5},
+			{Name: "TestC", Result: "skip"},
+		},
+	})
+}
+
+func TestAppendAndReadHistory(t *testing.T) {
+	dir := tempfile.TempDir(t, "testrunner-history")
+	defer dir.Cleanup()
+
+	savedOutput := TestOutput
+	defer func() { TestOutput = savedOutput }()
+	TestOutput = filepath.Join(dir.Path(), "out")
+
+	start := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	later := &results.TestPackage{
+		Name:   "example.com/a",
+		Result: results.Passed,
+		Start:  start.Add(time.Minute),
+		Tests:  []*results.Test{{Name: "TestA", Result: results.Passed}},
+	}
+	earlier := &results.TestPackage{
+		Name:   "example.com/a",
+		Result: results.Failed,
+		Start:  start,
+		Tests:  []*results.Test{{Name: "TestA", Result: results.Failed}},
+	}
+
+	// the output directory is created, and records are appended
+	appendHistory([]*results.TestPackage{later})
+	appendHistory([]*results.TestPackage{earlier})
+
+	path := filepath.Join(TestOutput, HistoryFile)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	assert.Nil(t, err)
+	f.WriteString("{\"version\":99}\n{\"version\":1,\"pack")
+	f.Close()
+
+	data, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	records, skipped, err := readHistory(bytes.NewReader(data))
+	assert.Nil(t, err)
+	assert.Equal(t, skipped, 2)
+	assert.DeepEqual(t, records, []historyRecord{
+		newHistoryRecord(earlier),
+		newHistoryRecord(later),
+	})
+}
+
+func historyRun(results ...string) historyRecord {
+	record := historyRecord{Version: HistoryVersion, Package: "example.com/a"}
+	for i, result := range results {
+		if result != "" {
+			record.Tests = append(
+				record.Tests,
+				historyTest{Name: "Test" + string(rune('A'+i)), Result: result, Duration: 1.0},
+			)
+		}
+	}
+	return record
+}
+
+func TestSummarizeHistory(t *testing.T) {
+	records := []historyRecord{
+		historyRun("pass", "fail", "pass"),
+		historyRun("pass", "pass", "fail"),
+		historyRun("pass", "fail", "skip"),
+		historyRun("pass", "pass", "flaky"),
+		historyRun("pass", "fail", ""),
+	}
+	records[4].Tests[0].Duration = 3.0
+
+	other := historyRun("fail")
+	other.Package = "example.com/b"
+	records = append(records, other)
+
+	summaries := summarizeHistory(records, 0, nil)
+	assert.Equal(t, len(summaries), 4)
+
+	// TestB flips on every run
+	b := summaries[0]
+	assert.Equal(t, b.name, "TestB")
+	assert.Equal(t, b.runs, 5)
+	assert.Equal(t, b.failures, 3)
+	assert.Equal(t, b.flips, 4)
+	assert.Equal(t, b.flipRate(), 1.0)
+	assert.Equal(t, b.failureRate(), 0.6)
+
+	// skipped runs are not counted, and flaky runs count as failures
+	c := summaries[1]
+	assert.Equal(t, c.name, "TestC")
+	assert.Equal(t, c.runs, 3)
+	assert.Equal(t, c.failures, 2)
+	assert.Equal(t, c.flips, 1)
+
+	// a single run has no flip rate or trend
+	other1 := summaries[2]
+	assert.Equal(t, other1.pkg, "example.com/b")
+	assert.Equal(t, other1.flipRate(), 0.0)
+	_, ok := other1.trend()
+	assert.False(t, ok)
+
+	a := summaries[3]
+	assert.Equal(t, a.name, "TestA")
+	assert.Equal(t, a.failureRate(), 0.0)
+	assert.Equal(t, a.meanDuration(), 1.4)
+	trend, ok := a.trend()
+	assert.True(t, ok)
+	assert.Equal(t, trend, 1.0)
+
+	// only the last runs are summarized
+	summaries = summarizeHistory(records, 2, []string{"example.com/a"})
+	assert.Equal(t, len(summaries), 3)
+	assert.Equal(t, summaries[0].name, "TestB")
+	assert.Equal(t, summaries[0].runs, 2)
+	assert.Equal(t, summaries[0].flips, 1)
+}
+
+func TestWriteHistory(t *testing.T) {
+	var buf bytes.Buffer
+	writeHistory(&buf, summarizeHistory(
+		[]historyRecord{
+			historyRun("pass", "fail"),
+			historyRun("pass", "pass"),
+		},
+		0,
+		nil,
+	))
+
+	assert.Equal(
+		t,
+		buf.String(),
+		strings.Join(
+			[]string{
+				"PACKAGE        TEST   RUNS  FAILURES   FLIPS       MEAN    TREND",
+				"example.com/a  TestB  2     1 (50.0%)  1 (100.0%)  1.000s  +0.0%",
+				"example.com/a  TestA  2     0 (0.0%)   0 (0.0%)    1.000s  +0.0%",
+				"",
+			},
+			"\n",
+		),
+	)
+}
+
+func TestRunHistory(t *testing.T) {
+	dir := tempfile.TempDir(t, "testrunner-history")
+	defer dir.Cleanup()
+
+	savedOutput := TestOutput
+	defer func() { TestOutput = savedOutput }()
+	TestOutput = dir.Path()
+
+	// no history yet
+	assert.Equal(t, runHistory(nil), exitRunnerError)
+
+	appendHistory([]*results.TestPackage{
+		{
+			Name:   "example.com/a",
+			Result: results.Passed,
+			Tests:  []*results.Test{{Name: "TestA", Result: results.Passed}},
+		},
+	})
+	assert.Equal(t, runHistory([]string{"-runs", "5", "-top", "1"}), exitPassed)
+	assert.Equal(t, runHistory([]string{"-runs", "x"}), exitUsage)
+}
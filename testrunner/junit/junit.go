@@ -21,26 +21,65 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"github.com/turbinelabs/test/testrunner/results"
 )
 
+// The elements and attributes of the report follow the Jenkins JUnit
+// schema (a superset of the Ant JUnit schema), plus Maven Surefire's
+// flakyFailure element.
+
 type JunitTestSuites struct {
-	XMLName xml.Name `xml:"testsuites"`
-	Suites  []JunitTestSuite
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Duration string           `xml:"time,attr"`
+	Suites   []JunitTestSuite `xml:"testsuite"`
 }
 
 type JunitTestSuite struct {
 	XMLName    xml.Name        `xml:"testsuite"`
+	ID         int             `xml:"id,attr"`
 	Name       string          `xml:"name,attr"`
 	Tests      int             `xml:"tests,attr"`
 	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Skipped    int             `xml:"skipped,attr"`
 	Duration   string          `xml:"time,attr"`
-	Properties []JunitProperty `xml:"properties>property,omitempty"`
-	TestCases  []JunitTestCase
-	Output     *JunitOutput `xml:"system-out,omitempty"`
+	Timestamp  string          `xml:"timestamp,attr,omitempty"`
+	Hostname   string          `xml:"hostname,attr,omitempty"`
+	Properties JunitProperties `xml:"properties,omitempty"`
+	TestCases  []JunitTestCase `xml:"testcase"`
+	Output     *JunitOutput    `xml:"system-out,omitempty"`
+}
+
+// JunitProperties is marshalled as a properties element containing a
+// property element for each JunitProperty. The schema requires at
+// least one property, so an empty JunitProperties is omitted.
+type JunitProperties []JunitProperty
+
+type junitPropertiesElement struct {
+	Properties []JunitProperty `xml:"property"`
+}
+
+func (p JunitProperties) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(junitPropertiesElement{p}, start)
+}
+
+func (p *JunitProperties) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	elem := junitPropertiesElement{}
+	if err := d.DecodeElement(&elem, &start); err != nil {
+		return err
+	}
+	*p = elem.Properties
+	return nil
 }
 
 type JunitProperty struct {
@@ -54,14 +93,21 @@ type JunitTestCase struct {
 	Name      string            `xml:"name,attr"`
 	Duration  string            `xml:"time,attr"`
 	Skipped   *JunitSkipMessage `xml:"skipped,omitempty"`
+	Error     *JunitFailure     `xml:"error,omitempty"`
 	Failure   *JunitFailure     `xml:"failure,omitempty"`
-	Output    *JunitOutput      `xml:"system-out,omitempty"`
+
+	// For flaky tests, the failure that preceded the successful
+	// retry.
+	FlakyFailure *JunitFailure `xml:"flakyFailure,omitempty"`
+
+	Output *JunitOutput `xml:"system-out,omitempty"`
 }
 
 type JunitSkipMessage struct {
 	Message string `xml:",cdata"`
 }
 
+// JunitFailure describes a failure (or error) of a test case.
 type JunitFailure struct {
 	Message  string `xml:"message,attr"`
 	Type     string `xml:"type,attr"`
@@ -75,8 +121,12 @@ type JunitOutput struct {
 // WriteReport writes a []*results.TestPackage to a writer using
 // the junit-standard format for test results.
 func WriteReport(out io.Writer, pkgs []*results.TestPackage) {
-	suites := GenerateReport(pkgs)
+	WriteSuites(out, GenerateReport(pkgs))
+}
 
+// WriteSuites writes a report, such as one produced by MergeReports,
+// to a writer.
+func WriteSuites(out io.Writer, suites JunitTestSuites) {
 	xml, err := xml.MarshalIndent(suites, "", "\t")
 	if err != nil {
 		panic(err)
@@ -85,6 +135,41 @@ func WriteReport(out io.Writer, pkgs []*results.TestPackage) {
 	out.Write([]byte{'\n'})
 }
 
+// ReadReport reads a junit-style report previously written by
+// WriteReport. Reports containing a single testsuite element, as
+// written by some other tools, are also accepted.
+func ReadReport(in io.Reader) (JunitTestSuites, error) {
+	decoder := xml.NewDecoder(in)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return JunitTestSuites{}, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "testsuites":
+			suites := JunitTestSuites{}
+			if err := decoder.DecodeElement(&suites, &start); err != nil {
+				return JunitTestSuites{}, err
+			}
+			return suites, nil
+		case "testsuite":
+			suite := JunitTestSuite{}
+			if err := decoder.DecodeElement(&suite, &start); err != nil {
+				return JunitTestSuites{}, err
+			}
+			return totals([]JunitTestSuite{suite}), nil
+		default:
+			return JunitTestSuites{}, fmt.Errorf("not a junit report: unexpected <%s> element", start.Name.Local)
+		}
+	}
+}
+
 // Escapes strings containing characters disallowed in XML (even in
 // CDATA sections). The escaping is lossy and is meant only to prevent
 // XML parsers from failing to decode the document.
@@ -126,13 +211,28 @@ func GenerateReport(pkgs []*results.TestPackage) JunitTestSuites {
 
 	for i, pkg := range pkgs {
 		suite := JunitTestSuite{
+			ID:         i,
 			Name:       pkg.Name,
-			Tests:      len(pkg.Tests),
 			Duration:   formatDuration(pkg.Duration),
-			Properties: []JunitProperty{},
+			Properties: environmentProperties(pkg.Environment),
 			TestCases:  []JunitTestCase{},
 		}
 
+		if !pkg.Start.IsZero() {
+			suite.Timestamp = formatTimestamp(pkg.Start)
+		}
+		if pkg.Environment != nil {
+			suite.Hostname = pkg.Environment.Hostname
+		}
+
+		suite.Properties = append(suite.Properties, benchmarkProperties(pkg.Benchmarks)...)
+		if pkg.Coverage != nil {
+			suite.Properties = append(suite.Properties, coverageProperties(pkg.Coverage)...)
+		}
+		if pkg.Usage != nil {
+			suite.Properties = append(suite.Properties, usageProperties(pkg.Usage)...)
+		}
+
 		if pkg.Output != "" {
 			suite.Output = &JunitOutput{sanitize(pkg.Output)}
 		}
@@ -142,39 +242,349 @@ func GenerateReport(pkgs []*results.TestPackage) JunitTestSuites {
 			classname = classname[i+1:]
 		}
 
-		for _, test := range pkg.Tests {
-			output := test.Output.String()
-			var testCaseOutput *JunitOutput
-			if output != "" {
-				testCaseOutput = &JunitOutput{sanitize(output)}
-			}
+		addTestCases(&suite, classname, nil, pkg.Tests)
+		if pkg.Result == results.Failed && suite.Failures == 0 {
+			suite.Errors++
+			suite.TestCases = append(suite.TestCases, packageError(pkg, classname))
+		}
+		suite.Tests = len(suite.TestCases)
 
-			testCase := JunitTestCase{
-				Classname: classname,
-				Name:      test.Name,
-				Duration:  formatDuration(test.Duration),
-				Output:    testCaseOutput,
-			}
+		suites[i] = suite
+	}
 
-			switch test.Result {
-			case results.Failed:
-				suite.Failures++
-				testCase.Failure = &JunitFailure{
-					Message:  "Failed",
-					Contents: test.Failure.String(),
-				}
-			case results.Skipped:
-				testCase.Skipped = &JunitSkipMessage{output}
-			}
+	return totals(suites)
+}
+
+const (
+	// BuildErrorTestCase names the test case used to report the
+	// failure of a package that did not build.
+	BuildErrorTestCase = "[build]"
+
+	// PackageErrorTestCase names the test case used to report the
+	// failure of a package in which no test failed, for reasons
+	// other than a build failure (e.g., TestMain exited with a
+	// non-zero status).
+	PackageErrorTestCase = "[package]"
+)
+
+// packageError returns a test case reporting, as an error, the
+// failure of a package in which no test failed. The error's contents
+// are the package's build errors, one per line, if any, and otherwise
+// the package's output.
+func packageError(pkg *results.TestPackage, classname string) JunitTestCase {
+	testCase := JunitTestCase{
+		Classname: classname,
+		Name:      PackageErrorTestCase,
+		Duration:  formatDuration(0),
+		Error: &JunitFailure{
+			Message:  "Package failed",
+			Type:     "package",
+			Contents: sanitize(pkg.Output),
+		},
+	}
+
+	if pkg.BuildFailed || len(pkg.BuildErrors) > 0 {
+		testCase.Name = BuildErrorTestCase
+		testCase.Error.Message = "Build failed"
+		testCase.Error.Type = "build"
+	}
+
+	if len(pkg.BuildErrors) > 0 {
+		lines := make([]string, len(pkg.BuildErrors))
+		for i, e := range pkg.BuildErrors {
+			lines[i] = e.String()
+		}
+		testCase.Error.Message = fmt.Sprintf("Build failed: %s", pkg.BuildErrors[0])
+		testCase.Error.Contents = sanitize(strings.Join(lines, "\n") + "\n")
+	}
+
+	return testCase
+}
 
-			suite.TestCases = append(suite.TestCases, testCase)
+// addTestCases adds a test case for each test and, recursively, each
+// of its subtests. Subtests use their parent test's name, qualified
+// by the package's classname, as their classname (e.g.,
+// "pkg.TestFoo" or "pkg.TestFoo/case_1").
+func addTestCases(
+	suite *JunitTestSuite,
+	pkgClassname string,
+	parent *results.Test,
+	tests []*results.Test,
+) {
+	classname := pkgClassname
+	if parent != nil {
+		classname += "." + parent.Name
+	}
+
+	for _, test := range tests {
+		output := test.Output.String()
+		var testCaseOutput *JunitOutput
+		if output != "" {
+			testCaseOutput = &JunitOutput{sanitize(output)}
 		}
 
-		suites[i] = suite
+		testCase := JunitTestCase{
+			Classname: classname,
+			Name:      test.Name,
+			Duration:  formatDuration(test.Duration),
+			Output:    testCaseOutput,
+		}
+
+		switch test.Result {
+		case results.Failed:
+			suite.Failures++
+			testCase.Failure = failure(test)
+		case results.Skipped:
+			suite.Skipped++
+			testCase.Skipped = &JunitSkipMessage{output}
+		case results.Flaky:
+			testCase.FlakyFailure = failure(test)
+			suite.Properties = append(
+				suite.Properties,
+				JunitProperty{
+					Name:  FlakyPropertyPrefix + test.Name,
+					Value: strconv.Itoa(test.Retries),
+				},
+			)
+		}
+
+		suite.TestCases = append(suite.TestCases, testCase)
+
+		addTestCases(suite, pkgClassname, test, test.Subtests)
 	}
-	return JunitTestSuites{Suites: suites}
+}
+
+// failure returns the JunitFailure for a failed test. Failures caused
+// by something other than the test itself are given a distinct type.
+func failure(test *results.Test) *JunitFailure {
+	switch test.FailureKind {
+	case results.Timeout:
+		return &JunitFailure{
+			Message:  "Timed out",
+			Type:     "timeout",
+			Contents: test.Failure.String(),
+		}
+	case results.Panic:
+		return &JunitFailure{
+			Message:  "Panicked",
+			Type:     "panic",
+			Contents: test.Failure.String(),
+		}
+	case results.Interrupted:
+		return &JunitFailure{
+			Message:  "Interrupted",
+			Type:     "interrupted",
+			Contents: test.Failure.String(),
+		}
+	default:
+		return &JunitFailure{
+			Message:  "Failed",
+			Type:     "failure",
+			Contents: test.Failure.String(),
+		}
+	}
+}
+
+// FlakyPropertyPrefix prefixes the names of properties that identify
+// flaky tests, e.g. "flaky.TestFoo". The property's value is the
+// number of times the test was retried.
+const FlakyPropertyPrefix = "flaky."
+
+// GoPropertyPrefix prefixes the names of properties that describe
+// the Go environment in which the tests ran: "go.os", "go.arch", and
+// "go.version".
+const GoPropertyPrefix = "go."
+
+// EnvPropertyPrefix prefixes the names of properties that record
+// selected environment variables, e.g. "env.BUILD_NUMBER".
+const EnvPropertyPrefix = "env."
+
+// environmentProperties produces properties describing the
+// environment.
+func environmentProperties(env *results.Environment) JunitProperties {
+	properties := JunitProperties{}
+	if env == nil {
+		return properties
+	}
+
+	add := func(name, value string) {
+		if value != "" {
+			properties = append(properties, JunitProperty{Name: name, Value: value})
+		}
+	}
+
+	add(GoPropertyPrefix+"os", env.GOOS)
+	add(GoPropertyPrefix+"arch", env.GOARCH)
+	add(GoPropertyPrefix+"version", env.GoVersion)
+
+	names := make([]string, 0, len(env.Vars))
+	for name := range env.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		properties = append(
+			properties,
+			JunitProperty{Name: EnvPropertyPrefix + name, Value: env.Vars[name]},
+		)
+	}
+	return properties
+}
+
+// CoveragePropertyPrefix prefixes the names of properties that
+// report statement coverage. A package's coverage is reported as
+// "coverage.statements", "coverage.covered", and "coverage.percent".
+// The percentage of each source file's statements covered is
+// reported as "coverage.file.<file name>".
+const CoveragePropertyPrefix = "coverage."
+
+// coverageProperties produces properties summarizing coverage.
+func coverageProperties(c *results.Coverage) []JunitProperty {
+	properties := []JunitProperty{
+		{Name: CoveragePropertyPrefix + "statements", Value: strconv.Itoa(c.Statements)},
+		{Name: CoveragePropertyPrefix + "covered", Value: strconv.Itoa(c.Covered)},
+		{Name: CoveragePropertyPrefix + "percent", Value: formatPercent(c.Percent())},
+	}
+	for _, f := range c.Files {
+		properties = append(
+			properties,
+			JunitProperty{
+				Name:  CoveragePropertyPrefix + "file." + f.Name,
+				Value: formatPercent(f.Percent()),
+			},
+		)
+	}
+	return properties
+}
+
+// UsagePropertyPrefix prefixes the names of properties that report
+// the resources used by a package's test executable: CPU and wall
+// time, in seconds, as "usage.user_time", "usage.system_time" and
+// "usage.wall_time", and the peak resident set size, in bytes, as
+// "usage.max_rss" (if known).
+const UsagePropertyPrefix = "usage."
+
+// usageProperties produces properties describing resource usage.
+func usageProperties(u *results.ResourceUsage) []JunitProperty {
+	properties := []JunitProperty{
+		{Name: UsagePropertyPrefix + "user_time", Value: formatDuration(u.UserTime)},
+		{Name: UsagePropertyPrefix + "system_time", Value: formatDuration(u.SystemTime)},
+		{Name: UsagePropertyPrefix + "wall_time", Value: formatDuration(u.WallTime)},
+	}
+	if u.MaxRSS > 0 {
+		properties = append(
+			properties,
+			JunitProperty{
+				Name:  UsagePropertyPrefix + "max_rss",
+				Value: strconv.FormatInt(u.MaxRSS, 10),
+			},
+		)
+	}
+	return properties
+}
+
+func formatPercent(p float64) string {
+	return strconv.FormatFloat(p, 'f', 1, 64)
+}
+
+// BenchmarkPropertyPrefix prefixes the names of properties that
+// report benchmark results. Each benchmark metric is reported as a
+// property named for the benchmark and the metric's unit, separated
+// by a space, e.g. "benchmark.BenchmarkFoo-8 ns/op". Neither
+// benchmark names nor units contain whitespace, though units may
+// contain dots. A benchmark run more than once produces a property
+// for each run.
+const BenchmarkPropertyPrefix = "benchmark."
+
+var procsRegex = regexp.MustCompile(`^(.+)-(\d+)$`)
+
+// benchmarkProperties produces properties for each benchmark metric.
+func benchmarkProperties(benchmarks []*results.Benchmark) []JunitProperty {
+	properties := []JunitProperty{}
+	for _, b := range benchmarks {
+		prefix := BenchmarkPropertyPrefix + b.FullName() + " "
+		add := func(unit string, value float64) {
+			properties = append(
+				properties,
+				JunitProperty{
+					Name:  prefix + unit,
+					Value: strconv.FormatFloat(value, 'f', -1, 64),
+				},
+			)
+		}
+
+		add("ns/op", b.NsPerOp)
+		if b.HasMemStats {
+			add("B/op", b.BytesPerOp)
+			add("allocs/op", b.AllocsPerOp)
+		}
+
+		units := make([]string, 0, len(b.Metrics))
+		for unit := range b.Metrics {
+			units = append(units, unit)
+		}
+		sort.Strings(units)
+		for _, unit := range units {
+			add(unit, b.Metrics[unit])
+		}
+	}
+	return properties
+}
+
+// Benchmarks reconstructs the benchmark results reported as
+// properties of the suite.
+func (suite JunitTestSuite) Benchmarks() []*results.Benchmark {
+	benchmarks := []*results.Benchmark{}
+	var b *results.Benchmark
+	for _, property := range suite.Properties {
+		if !strings.HasPrefix(property.Name, BenchmarkPropertyPrefix) {
+			continue
+		}
+
+		name := property.Name[len(BenchmarkPropertyPrefix):]
+		i := strings.LastIndex(name, " ")
+		if i == -1 {
+			continue
+		}
+		name, unit := name[0:i], name[i+1:]
+
+		value, err := strconv.ParseFloat(property.Value, 64)
+		if err != nil {
+			continue
+		}
+
+		// each run's properties begin with ns/op
+		if unit == "ns/op" || b == nil || b.FullName() != name {
+			b = &results.Benchmark{Name: name, Metrics: map[string]float64{}}
+			if m := procsRegex.FindStringSubmatch(name); len(m) == 3 {
+				b.Name = m[1]
+				b.Procs, _ = strconv.Atoi(m[2])
+			}
+			benchmarks = append(benchmarks, b)
+		}
+
+		switch unit {
+		case "ns/op":
+			b.NsPerOp = value
+		case "B/op":
+			b.HasMemStats = true
+			b.BytesPerOp = value
+		case "allocs/op":
+			b.HasMemStats = true
+			b.AllocsPerOp = value
+		default:
+			b.Metrics[unit] = value
+		}
+	}
+	return benchmarks
 }
 
 func formatDuration(f float64) string {
 	return fmt.Sprintf("%.3f", f)
 }
+
+// formatTimestamp formats a time as an ISO 8601 timestamp in UTC,
+// without a time zone, as required by the Ant JUnit schema.
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05")
+}
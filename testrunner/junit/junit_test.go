@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/turbinelabs/test/assert"
 	"github.com/turbinelabs/test/testrunner/results"
@@ -90,6 +91,110 @@ var (
 		},
 	}
 
+	subtestSuite = []*results.TestPackage{
+		{
+			Name:     "github.com/turbinelabs/something",
+			Result:   results.Failed,
+			Duration: 1.234,
+			Tests: []*results.Test{
+				{
+					Name:     "TestFoo",
+					Result:   results.Failed,
+					Duration: 1.2,
+					Subtests: []*results.Test{
+						{
+							Name:     "TestFoo/a",
+							Result:   results.Passed,
+							Duration: 0.2,
+						},
+						{
+							Name:     "TestFoo/b",
+							Result:   results.Failed,
+							Duration: 1.0,
+							Failure:  makeBuffer("b failed"),
+							Subtests: []*results.Test{
+								{
+									Name:     "TestFoo/b/c",
+									Result:   results.Failed,
+									Duration: 1.0,
+									Failure:  makeBuffer("c failed"),
+								},
+							},
+						},
+					},
+				},
+				{
+					Name:     "TestBar",
+					Result:   results.Passed,
+					Duration: 0.3,
+				},
+			},
+		},
+	}
+
+	benchmarkSuite = []*results.TestPackage{
+		{
+			Name:     "github.com/turbinelabs/something",
+			Result:   results.Passed,
+			Duration: 1.234,
+			Benchmarks: []*results.Benchmark{
+				{
+					Name:        "BenchmarkFoo",
+					Procs:       8,
+					Iterations:  1000,
+					NsPerOp:     1234.5,
+					HasMemStats: true,
+					BytesPerOp:  56,
+					AllocsPerOp: 2,
+					Metrics: map[string]float64{
+						"widgets/op": 3,
+						"MB/s":       12.5,
+						"req/s.p99":  7,
+					},
+				},
+				{
+					Name:       "BenchmarkBar",
+					Iterations: 100,
+					NsPerOp:    10,
+				},
+			},
+		},
+	}
+
+	environmentSuite = []*results.TestPackage{
+		{
+			Name:     "github.com/turbinelabs/something",
+			Result:   results.Passed,
+			Start:    time.Date(2018, 1, 2, 3, 4, 5, 0, time.FixedZone("PST", -8*60*60)),
+			Duration: 1.234,
+			Tests: []*results.Test{
+				{Name: "TestFoo", Result: results.Passed, Duration: 1.2},
+			},
+			Environment: &results.Environment{
+				Hostname:  "builder-1",
+				GOOS:      "linux",
+				GOARCH:    "amd64",
+				GoVersion: "go1.10.3",
+				Vars: map[string]string{
+					"BUILD_NUMBER": "1234",
+					"BRANCH":       "master",
+				},
+			},
+		},
+	}
+
+	buildFailureSuite = []*results.TestPackage{
+		{
+			Name:        "github.com/turbinelabs/something",
+			Result:      results.Failed,
+			BuildFailed: true,
+			Tests:       []*results.Test{},
+			Output: "# github.com/turbinelabs/something\n" +
+				"./a.go:3:23: undefined: x\n" +
+				"FAIL\tgithub.com/turbinelabs/something [build failed]\n",
+		},
+	}
+
 	suiteOutput = []*results.TestPackage{
 		{
 			Name:     "github.com/turbinelabs/tbn/something",
@@ -152,6 +257,7 @@ func TestGenerateReportFailure(t *testing.T) {
 	assert.Nil(t, testCase1.Skipped)
 	assert.DeepEqual(t, testCase1.Failure, &JunitFailure{
 		Message:  "Failed",
+		Type:     "failure",
 		Contents: "some assertion",
 	})
 	assert.DeepEqual(t, testCase1.Output, &JunitOutput{"some output"})
@@ -164,6 +270,252 @@ func TestGenerateReportFailure(t *testing.T) {
 	assert.Nil(t, testCase2.Failure)
 }
 
+func TestGenerateReportFailureKinds(t *testing.T) {
+	timeout := &results.Test{
+		Name:        "TestTimeout",
+		Result:      results.Failed,
+		Failure:     makeBuffer("SIGQUIT: quit"),
+		FailureKind: results.Timeout,
+	}
+	panicked := &results.Test{
+		Name:        "TestPanic",
+		Result:      results.Failed,
+		Failure:     makeBuffer("panic: boom"),
+		FailureKind: results.Panic,
+	}
+	interrupted := &results.Test{
+		Name:        "TestInterrupted",
+		Result:      results.Failed,
+		Failure:     makeBuffer("=== RUN   TestInterrupted"),
+		FailureKind: results.Interrupted,
+	}
+
+	suites := GenerateReport([]*results.TestPackage{
+		{
+			Name:   "github.com/turbinelabs/something",
+			Result: results.Failed,
+			Tests:  []*results.Test{timeout, panicked, interrupted},
+		},
+	})
+	assert.Equal(t, len(suites.Suites), 1)
+
+	suite := suites.Suites[0]
+	assert.Equal(t, suite.Failures, 3)
+	assert.DeepEqual(t, suite.TestCases[0].Failure, &JunitFailure{
+		Message:  "Timed out",
+		Type:     "timeout",
+		Contents: "SIGQUIT: quit",
+	})
+	assert.DeepEqual(t, suite.TestCases[1].Failure, &JunitFailure{
+		Message:  "Panicked",
+		Type:     "panic",
+		Contents: "panic: boom",
+	})
+	assert.DeepEqual(t, suite.TestCases[2].Failure, &JunitFailure{
+		Message:  "Interrupted",
+		Type:     "interrupted",
+		Contents: "=== RUN   TestInterrupted",
+	})
+}
+
+func TestGenerateReportFlaky(t *testing.T) {
+	suites := GenerateReport([]*results.TestPackage{
+		{
+			Name:   "github.com/turbinelabs/something",
+			Result: results.Passed,
+			Tests: []*results.Test{
+				{
+					Name:    "TestFoo",
+					Result:  results.Flaky,
+					Failure: makeBuffer("some assertion"),
+					Retries: 2,
+				},
+			},
+		},
+	})
+	assert.Equal(t, len(suites.Suites), 1)
+
+	suite := suites.Suites[0]
+	assert.Equal(t, suite.Tests, 1)
+	assert.Equal(t, suite.Failures, 0)
+	assert.DeepEqual(t, suite.Properties, JunitProperties{
+		{Name: "flaky.TestFoo", Value: "2"},
+	})
+
+	testCase := suite.TestCases[0]
+	assert.Nil(t, testCase.Failure)
+	assert.DeepEqual(t, testCase.FlakyFailure, &JunitFailure{
+		Message:  "Failed",
+		Type:     "failure",
+		Contents: "some assertion",
+	})
+}
+
+func TestGenerateReportCoverage(t *testing.T) {
+	suites := GenerateReport([]*results.TestPackage{
+		{
+			Name:   "github.com/turbinelabs/something",
+			Result: results.Passed,
+			Coverage: &results.Coverage{
+				Statements: 3,
+				Covered:    2,
+				Files: []*results.FileCoverage{
+					{Name: "github.com/turbinelabs/something/a.go", Statements: 2, Covered: 2},
+					{Name: "github.com/turbinelabs/something/b.go", Statements: 1},
+				},
+			},
+		},
+	})
+	assert.Equal(t, len(suites.Suites), 1)
+	assert.DeepEqual(t, suites.Suites[0].Properties, JunitProperties{
+		{Name: "coverage.statements", Value: "3"},
+		{Name: "coverage.covered", Value: "2"},
+		{Name: "coverage.percent", Value: "66.7"},
+		{Name: "coverage.file.github.com/turbinelabs/something/a.go", Value: "100.0"},
+		{Name: "coverage.file.github.com/turbinelabs/something/b.go", Value: "0.0"},
+	})
+}
+
+func TestGenerateReportUsage(t *testing.T) {
+	pkg := &results.TestPackage{
+		Name:   "github.com/turbinelabs/something",
+		Result: results.Passed,
+		Usage: &results.ResourceUsage{
+			UserTime:   1.5,
+			SystemTime: 0.25,
+			WallTime:   2,
+			MaxRSS:     12345678,
+		},
+	}
+
+	suites := GenerateReport([]*results.TestPackage{pkg})
+	assert.Equal(t, len(suites.Suites), 1)
+	assert.DeepEqual(t, suites.Suites[0].Properties, JunitProperties{
+		{Name: "usage.user_time", Value: "1.500"},
+		{Name: "usage.system_time", Value: "0.250"},
+		{Name: "usage.wall_time", Value: "2.000"},
+		{Name: "usage.max_rss", Value: "12345678"},
+	})
+
+	pkg.Usage.MaxRSS = 0
+	suites = GenerateReport([]*results.TestPackage{pkg})
+	assert.DeepEqual(t, suites.Suites[0].Properties, JunitProperties{
+		{Name: "usage.user_time", Value: "1.500"},
+		{Name: "usage.system_time", Value: "0.250"},
+		{Name: "usage.wall_time", Value: "2.000"},
+	})
+}
+
+func TestGenerateReportEnvironment(t *testing.T) {
+	suites := GenerateReport(environmentSuite)
+	assert.Equal(t, len(suites.Suites), 1)
+
+	suite := suites.Suites[0]
+	assert.Equal(t, suite.Timestamp, "2018-01-02T11:04:05")
+	assert.Equal(t, suite.Hostname, "builder-1")
+	assert.DeepEqual(t, suite.Properties, JunitProperties{
+		{Name: "go.os", Value: "linux"},
+		{Name: "go.arch", Value: "amd64"},
+		{Name: "go.version", Value: "go1.10.3"},
+		{Name: "env.BRANCH", Value: "master"},
+		{Name: "env.BUILD_NUMBER", Value: "1234"},
+	})
+
+	// unknown start time and environment are omitted
+	suite = GenerateReport(passingSuite).Suites[0]
+	assert.Equal(t, suite.Timestamp, "")
+	assert.Equal(t, suite.Hostname, "")
+
+	var buf bytes.Buffer
+	WriteReport(&buf, passingSuite)
+	assert.StringDoesNotContain(t, buf.String(), "<properties")
+	assert.StringDoesNotContain(t, buf.String(), "timestamp=")
+}
+
+func TestGenerateReportBuildFailure(t *testing.T) {
+	suites := GenerateReport(buildFailureSuite)
+	assert.Equal(t, suites.Tests, 1)
+	assert.Equal(t, suites.Failures, 0)
+	assert.Equal(t, suites.Errors, 1)
+
+	suite := suites.Suites[0]
+	assert.Equal(t, suite.Tests, 1)
+	assert.Equal(t, suite.Failures, 0)
+	assert.Equal(t, suite.Errors, 1)
+	assert.Equal(t, len(suite.TestCases), 1)
+
+	testCase := suite.TestCases[0]
+	assert.Equal(t, testCase.Classname, "something")
+	assert.Equal(t, testCase.Name, BuildErrorTestCase)
+	assert.Nil(t, testCase.Failure)
+	assert.DeepEqual(t, testCase.Error, &JunitFailure{
+		Message:  "Build failed",
+		Type:     "build",
+		Contents: buildFailureSuite[0].Output,
+	})
+}
+
+func TestGenerateReportBuildErrors(t *testing.T) {
+	pkg := *buildFailureSuite[0]
+	pkg.BuildErrors = []*results.BuildError{
+		{File: "./a.go", Line: 3, Column: 23, Message: "undefined: x"},
+		{File: "./a.go", Line: 5, Message: "have ()\nwant (int)"},
+	}
+
+	suite := GenerateReport([]*results.TestPackage{&pkg}).Suites[0]
+	assert.Equal(t, suite.Errors, 1)
+	assert.Equal(t, len(suite.TestCases), 1)
+
+	testCase := suite.TestCases[0]
+	assert.Equal(t, testCase.Name, BuildErrorTestCase)
+	assert.DeepEqual(t, testCase.Error, &JunitFailure{
+		Message:  "Build failed: ./a.go:3:23: undefined: x",
+		Type:     "build",
+		Contents: "./a.go:3:23: undefined: x\n./a.go:5: have ()\nwant (int)\n",
+	})
+
+	validateReport(t, []*results.TestPackage{&pkg})
+}
+
+func TestGenerateReportPackageFailure(t *testing.T) {
+	suites := GenerateReport([]*results.TestPackage{
+		{
+			Name:   "github.com/turbinelabs/something",
+			Result: results.Failed,
+			Tests: []*results.Test{
+				{Name: "TestFoo", Result: results.Passed},
+			},
+			Output: "TestMain failed\n",
+		},
+	})
+
+	suite := suites.Suites[0]
+	assert.Equal(t, suite.Tests, 2)
+	assert.Equal(t, suite.Errors, 1)
+
+	testCase := suite.TestCases[1]
+	assert.Equal(t, testCase.Name, PackageErrorTestCase)
+	assert.DeepEqual(t, testCase.Error, &JunitFailure{
+		Message:  "Package failed",
+		Type:     "package",
+		Contents: "TestMain failed\n",
+	})
+}
+
+func TestGenerateReportTotals(t *testing.T) {
+	pkgs := append(append(append([]*results.TestPackage{}, failingSuite...), skippedSuite...), subtestSuite...)
+	suites := GenerateReport(pkgs)
+	assert.Equal(t, suites.Tests, 9)
+	assert.Equal(t, suites.Failures, 4)
+	assert.Equal(t, suites.Errors, 0)
+	assert.Equal(t, suites.Duration, "3.702")
+
+	for i, suite := range suites.Suites {
+		assert.Equal(t, suite.ID, i)
+	}
+	assert.Equal(t, suites.Suites[1].Skipped, 1)
+}
+
 func TestGenerateReportSkipped(t *testing.T) {
 	suites := GenerateReport(skippedSuite)
 	assert.Equal(t, len(suites.Suites), 1)
@@ -193,6 +545,134 @@ func TestGenerateReportSkipped(t *testing.T) {
 	assert.Nil(t, testCase2.Failure)
 }
 
+func TestGenerateReportSubtests(t *testing.T) {
+	suites := GenerateReport(subtestSuite)
+	assert.Equal(t, len(suites.Suites), 1)
+
+	suite := suites.Suites[0]
+	assert.Equal(t, suite.Tests, 5)
+	assert.Equal(t, suite.Failures, 3)
+
+	type nameAndClass struct {
+		classname, name string
+	}
+	got := make([]nameAndClass, len(suite.TestCases))
+	for i, testCase := range suite.TestCases {
+		got[i] = nameAndClass{testCase.Classname, testCase.Name}
+	}
+
+	assert.ArrayEqual(t, got, []nameAndClass{
+		{"something", "TestFoo"},
+		{"something.TestFoo", "TestFoo/a"},
+		{"something.TestFoo", "TestFoo/b"},
+		{"something.TestFoo/b", "TestFoo/b/c"},
+		{"something", "TestBar"},
+	})
+
+	assert.DeepEqual(t, suite.TestCases[3].Failure, &JunitFailure{
+		Message:  "Failed",
+		Type:     "failure",
+		Contents: "c failed",
+	})
+}
+
+func TestGenerateReportBenchmarks(t *testing.T) {
+	suites := GenerateReport(benchmarkSuite)
+	assert.Equal(t, len(suites.Suites), 1)
+
+	suite := suites.Suites[0]
+	assert.Equal(t, suite.Tests, 0)
+	assert.ArrayEqual(t, suite.Properties, JunitProperties{
+		{"benchmark.BenchmarkFoo-8 ns/op", "1234.5"},
+		{"benchmark.BenchmarkFoo-8 B/op", "56"},
+		{"benchmark.BenchmarkFoo-8 allocs/op", "2"},
+		{"benchmark.BenchmarkFoo-8 MB/s", "12.5"},
+		{"benchmark.BenchmarkFoo-8 req/s.p99", "7"},
+		{"benchmark.BenchmarkFoo-8 widgets/op", "3"},
+		{"benchmark.BenchmarkBar ns/op", "10"},
+	})
+}
+
+func TestWriteReportBenchmarks(t *testing.T) {
+	var buf bytes.Buffer
+	WriteReport(&buf, benchmarkSuite)
+
+	s := strings.Replace(buf.String(), "\n", "", -1)
+
+	assert.MatchesRegex(
+		t,
+		s,
+		`<properties>\s*<property name="benchmark.BenchmarkFoo-8 ns/op" value="1234.5"></property>`,
+	)
+}
+
+func TestReadReport(t *testing.T) {
+	var buf bytes.Buffer
+	WriteReport(&buf, failingSuite)
+
+	suites, err := ReadReport(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, len(suites.Suites), 1)
+
+	suite := suites.Suites[0]
+	assert.Equal(t, suite.Name, "github.com/turbinelabs/something")
+	assert.Equal(t, suite.Tests, 2)
+	assert.Equal(t, suite.Failures, 1)
+	assert.Equal(t, suite.Duration, "1.234")
+	assert.Equal(t, len(suite.TestCases), 2)
+
+	testCase1 := suite.TestCases[0]
+	assert.Equal(t, testCase1.Classname, "something")
+	assert.Equal(t, testCase1.Name, "TestFoo")
+	assert.Equal(t, testCase1.Duration, "1.200")
+	assert.DeepEqual(t, testCase1.Failure, &JunitFailure{
+		Message:  "Failed",
+		Type:     "failure",
+		Contents: "some assertion",
+	})
+	assert.DeepEqual(t, testCase1.Output, &JunitOutput{"some output"})
+
+	_, err = ReadReport(strings.NewReader("<testsuites>"))
+	assert.NonNil(t, err)
+
+	buf.Reset()
+	WriteReport(&buf, environmentSuite)
+	suites, err = ReadReport(&buf)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, suites.Suites[0].Properties, GenerateReport(environmentSuite).Suites[0].Properties)
+	assert.Equal(t, suites.Suites[0].Timestamp, "2018-01-02T11:04:05")
+	assert.Equal(t, suites.Suites[0].Hostname, "builder-1")
+}
+
+func TestSuiteBenchmarks(t *testing.T) {
+	var buf bytes.Buffer
+	WriteReport(&buf, benchmarkSuite)
+
+	suites, err := ReadReport(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, len(suites.Suites), 1)
+	assert.DeepEqual(t, suites.Suites[0].Benchmarks(), []*results.Benchmark{
+		{
+			Name:        "BenchmarkFoo",
+			Procs:       8,
+			NsPerOp:     1234.5,
+			HasMemStats: true,
+			BytesPerOp:  56,
+			AllocsPerOp: 2,
+			Metrics: map[string]float64{
+				"widgets/op": 3,
+				"MB/s":       12.5,
+				"req/s.p99":  7,
+			},
+		},
+		{
+			Name:    "BenchmarkBar",
+			NsPerOp: 10,
+			Metrics: map[string]float64{},
+		},
+	})
+}
+
 func TestGenerateReportCombined(t *testing.T) {
 	combinedInput := append([]*results.TestPackage{}, passingSuite...)
 	combinedInput = append(combinedInput, failingSuite...)
@@ -217,7 +697,7 @@ func TestWriteReportSuccess(t *testing.T) {
 
 	s := strings.Replace(buf.String(), "\n", "", -1)
 
-	assert.MatchesRegex(t, s, `^<testsuites>.*</testsuites>$`)
+	assert.MatchesRegex(t, s, `^<testsuites .*>.*</testsuites>$`)
 	assert.MatchesRegex(t, s, `<testsuite .*name="github.com/turbinelabs/something".*>`)
 	assert.MatchesRegex(t, s, `<testsuite .*tests="2".*>`)
 	assert.MatchesRegex(t, s, `<testsuite .*failures="0".*>`)
@@ -235,7 +715,7 @@ func TestWriteReportFailure(t *testing.T) {
 
 	s := strings.Replace(buf.String(), "\n", "", -1)
 
-	assert.MatchesRegex(t, s, `^<testsuites>.*</testsuites>$`)
+	assert.MatchesRegex(t, s, `^<testsuites .*>.*</testsuites>$`)
 	assert.MatchesRegex(t, s, `<testsuite .*name="github.com/turbinelabs/something".*>`)
 	assert.MatchesRegex(t, s, `<testsuite .*tests="2".*>`)
 	assert.MatchesRegex(t, s, `<testsuite .*failures="1".*>`)
@@ -251,7 +731,7 @@ func TestWriteReportSkipped(t *testing.T) {
 
 	s := strings.Replace(buf.String(), "\n", "", -1)
 
-	assert.MatchesRegex(t, s, `^<testsuites>.*</testsuites>$`)
+	assert.MatchesRegex(t, s, `^<testsuites .*>.*</testsuites>$`)
 	assert.MatchesRegex(t, s, `<testsuite .*name="github.com/turbinelabs/something".*>`)
 	assert.MatchesRegex(t, s, `<testsuite .*tests="2".*>`)
 	assert.MatchesRegex(t, s, `<testsuite .*failures="0".*>`)
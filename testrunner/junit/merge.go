@@ -0,0 +1,162 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package junit
+
+import (
+	"strconv"
+)
+
+type testCaseKey struct {
+	classname, name string
+}
+
+type propertyKey struct {
+	name, value string
+}
+
+// runKey identifies the run that produced a suite.
+type runKey struct {
+	name, timestamp, hostname string
+}
+
+// MergeReports combines reports into a single report with one suite
+// per suite name, in order of first appearance. Suites of the same
+// name from different runs (e.g., runs of different subsets of a
+// package's tests) are combined: their test cases are merged and
+// their durations added. A test case appearing in more than one such
+// suite is taken from the suite with the latest timestamp. Copies of
+// a suite from the same run (with the same name, timestamp and
+// hostname) are counted once. Suite and report totals are
+// recomputed.
+func MergeReports(reports ...JunitTestSuites) JunitTestSuites {
+	type mergedSuite struct {
+		suite      JunitTestSuite
+		runs       map[runKey]bool
+		testCases  map[testCaseKey]int
+		timestamps map[testCaseKey]string
+		properties map[propertyKey]bool
+		duration   float64
+	}
+
+	merged := []*mergedSuite{}
+	byName := map[string]*mergedSuite{}
+	for _, report := range reports {
+		for _, suite := range report.Suites {
+			run := runKey{suite.Name, suite.Timestamp, suite.Hostname}
+
+			m, ok := byName[suite.Name]
+			if !ok {
+				m = &mergedSuite{
+					suite: JunitTestSuite{
+						Name:      suite.Name,
+						Timestamp: suite.Timestamp,
+						Hostname:  suite.Hostname,
+						TestCases: []JunitTestCase{},
+					},
+					runs:       map[runKey]bool{},
+					testCases:  map[testCaseKey]int{},
+					timestamps: map[testCaseKey]string{},
+					properties: map[propertyKey]bool{},
+				}
+				byName[suite.Name] = m
+				merged = append(merged, m)
+			} else if m.runs[run] {
+				continue
+			}
+			if suite.Timestamp != "" {
+				// without a timestamp, runs are indistinguishable
+				m.runs[run] = true
+			}
+
+			d, _ := strconv.ParseFloat(suite.Duration, 64)
+			m.duration += d
+
+			if suite.Timestamp != "" && (m.suite.Timestamp == "" || suite.Timestamp < m.suite.Timestamp) {
+				// the earliest run
+				m.suite.Timestamp = suite.Timestamp
+				m.suite.Hostname = suite.Hostname
+			}
+
+			for _, p := range suite.Properties {
+				key := propertyKey{p.Name, p.Value}
+				if !m.properties[key] {
+					m.properties[key] = true
+					m.suite.Properties = append(m.suite.Properties, p)
+				}
+			}
+
+			for _, testCase := range suite.TestCases {
+				key := testCaseKey{testCase.Classname, testCase.Name}
+				if i, ok := m.testCases[key]; ok {
+					if suite.Timestamp >= m.timestamps[key] {
+						m.suite.TestCases[i] = testCase
+						m.timestamps[key] = suite.Timestamp
+					}
+					continue
+				}
+				m.testCases[key] = len(m.suite.TestCases)
+				m.timestamps[key] = suite.Timestamp
+				m.suite.TestCases = append(m.suite.TestCases, testCase)
+			}
+
+			if suite.Output != nil {
+				if m.suite.Output == nil {
+					m.suite.Output = &JunitOutput{}
+				}
+				m.suite.Output.Contents += suite.Output.Contents
+			}
+		}
+	}
+
+	suites := make([]JunitTestSuite, len(merged))
+	for i, m := range merged {
+		suite := m.suite
+		suite.ID = i
+		suite.Duration = formatDuration(m.duration)
+		for _, testCase := range suite.TestCases {
+			switch {
+			case testCase.Failure != nil:
+				suite.Failures++
+			case testCase.Error != nil:
+				suite.Errors++
+			case testCase.Skipped != nil:
+				suite.Skipped++
+			}
+		}
+		suite.Tests = len(suite.TestCases)
+		suites[i] = suite
+	}
+
+	return totals(suites)
+}
+
+// totals returns a report of the given suites, with totals across the
+// suites.
+func totals(suites []JunitTestSuite) JunitTestSuites {
+	report := JunitTestSuites{Suites: suites}
+	duration := 0.0
+	for _, suite := range suites {
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+
+		d, _ := strconv.ParseFloat(suite.Duration, 64)
+		duration += d
+	}
+	report.Duration = formatDuration(duration)
+	return report
+}
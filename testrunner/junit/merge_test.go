@@ -0,0 +1,161 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package junit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/turbinelabs/test/assert"
+)
+
+func testCase(name string) JunitTestCase {
+	return JunitTestCase{Classname: "a", Name: name, Duration: "0.100"}
+}
+
+func failedTestCase(name string) JunitTestCase {
+	tc := testCase(name)
+	tc.Failure = &JunitFailure{Message: "Failed", Type: "failure"}
+	return tc
+}
+
+func TestMergeReports(t *testing.T) {
+	shard1 := JunitTestSuites{
+		Suites: []JunitTestSuite{
+			{
+				Name:       "foo/a",
+				Timestamp:  "2018-01-02T03:04:05",
+				Hostname:   "host1",
+				Duration:   "1.000",
+				Properties: JunitProperties{{Name: "go.os", Value: "linux"}},
+				TestCases:  []JunitTestCase{testCase("TestA1"), failedTestCase("TestA2")},
+				Output:     &JunitOutput{"shard 1\n"},
+			},
+		},
+	}
+	shard2 := JunitTestSuites{
+		Suites: []JunitTestSuite{
+			{
+				Name:       "foo/a",
+				Timestamp:  "2018-01-02T03:04:00",
+				Hostname:   "host2",
+				Duration:   "2.000",
+				Properties: JunitProperties{{Name: "go.os", Value: "linux"}},
+				TestCases:  []JunitTestCase{testCase("TestA3")},
+				Output:     &JunitOutput{"shard 2\n"},
+			},
+			{
+				Name:      "foo/b",
+				Duration:  "0.500",
+				TestCases: []JunitTestCase{testCase("TestB")},
+			},
+		},
+	}
+
+	merged := MergeReports(shard1, shard2, shard1)
+	assert.Equal(t, merged.Tests, 4)
+	assert.Equal(t, merged.Failures, 1)
+	assert.Equal(t, merged.Errors, 0)
+	assert.Equal(t, merged.Duration, "3.500")
+	assert.Equal(t, len(merged.Suites), 2)
+
+	a := merged.Suites[0]
+	assert.Equal(t, a.ID, 0)
+	assert.Equal(t, a.Name, "foo/a")
+	assert.Equal(t, a.Tests, 3)
+	assert.Equal(t, a.Failures, 1)
+	assert.Equal(t, a.Duration, "3.000")
+	assert.Equal(t, a.Timestamp, "2018-01-02T03:04:00")
+	assert.Equal(t, a.Hostname, "host2")
+	assert.DeepEqual(t, a.Properties, JunitProperties{{Name: "go.os", Value: "linux"}})
+	assert.Equal(t, a.Output.Contents, "shard 1\nshard 2\n")
+
+	names := []string{}
+	for _, tc := range a.TestCases {
+		names = append(names, tc.Name)
+	}
+	assert.ArrayEqual(t, names, []string{"TestA1", "TestA2", "TestA3"})
+
+	b := merged.Suites[1]
+	assert.Equal(t, b.ID, 1)
+	assert.Equal(t, b.Name, "foo/b")
+	assert.Equal(t, b.Tests, 1)
+}
+
+func TestMergeReportsRerun(t *testing.T) {
+	first := JunitTestSuites{
+		Suites: []JunitTestSuite{
+			{
+				Name:      "foo/a",
+				Timestamp: "2018-01-02T03:04:05",
+				Duration:  "1.000",
+				TestCases: []JunitTestCase{testCase("TestA1"), failedTestCase("TestA2")},
+			},
+		},
+	}
+	rerun := JunitTestSuites{
+		Suites: []JunitTestSuite{
+			{
+				Name:      "foo/a",
+				Timestamp: "2018-01-02T04:00:00",
+				Duration:  "0.500",
+				TestCases: []JunitTestCase{testCase("TestA2")},
+			},
+		},
+	}
+
+	// the later result wins, regardless of order
+	for _, reports := range [][]JunitTestSuites{{first, rerun}, {rerun, first}} {
+		merged := MergeReports(reports...)
+		assert.Equal(t, merged.Tests, 2)
+		assert.Equal(t, merged.Failures, 0)
+		assert.Equal(t, len(merged.Suites[0].TestCases), 2)
+	}
+}
+
+func TestMergeReportsCountsErrorsAndSkips(t *testing.T) {
+	errored := testCase("[build]")
+	errored.Error = &JunitFailure{Message: "Build failed", Type: "build"}
+	skipped := testCase("TestSkip")
+	skipped.Skipped = &JunitSkipMessage{"skipped"}
+
+	merged := MergeReports(JunitTestSuites{
+		Suites: []JunitTestSuite{
+			{Name: "foo/a", TestCases: []JunitTestCase{errored}},
+			{Name: "foo/b", TestCases: []JunitTestCase{skipped}},
+		},
+	})
+	assert.Equal(t, merged.Tests, 2)
+	assert.Equal(t, merged.Errors, 1)
+	assert.Equal(t, merged.Suites[0].Errors, 1)
+	assert.Equal(t, merged.Suites[1].Skipped, 1)
+}
+
+func TestReadReportSingleSuite(t *testing.T) {
+	suites, err := ReadReport(strings.NewReader(`<?xml version="1.0"?>
+<testsuite name="foo" tests="1" failures="1" time="1.5">
+	<testcase classname="foo" name="TestFoo"><failure message="x"></failure></testcase>
+</testsuite>`))
+	assert.Nil(t, err)
+	assert.Equal(t, len(suites.Suites), 1)
+	assert.Equal(t, suites.Tests, 1)
+	assert.Equal(t, suites.Failures, 1)
+	assert.Equal(t, suites.Duration, "1.500")
+
+	_, err = ReadReport(strings.NewReader(`<coverage></coverage>`))
+	assert.ErrorContains(t, err, "not a junit report")
+}
@@ -0,0 +1,301 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package junit
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/turbinelabs/test/assert"
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+// The Jenkins JUnit schema (jenkins-junit.xsd), transcribed as a
+// table, with Maven Surefire's flakyFailure element added to
+// testcase. Each element lists its attributes, its children in
+// sequence order, and whether it may contain text.
+
+type attrType int
+
+const (
+	stringAttr attrType = iota
+	intAttr
+	decimalAttr
+	timestampAttr
+)
+
+var attrPatterns = map[attrType]*regexp.Regexp{
+	intAttr:       regexp.MustCompile(`^\d+$`),
+	decimalAttr:   regexp.MustCompile(`^\d+(\.\d+)?$`),
+	timestampAttr: regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}$`),
+}
+
+type attrSchema struct {
+	typ      attrType
+	required bool
+}
+
+type childSchema struct {
+	name     string
+	min, max int // max < 0 means unbounded
+}
+
+type elementSchema struct {
+	attrs    map[string]attrSchema
+	children []childSchema
+	text     bool
+}
+
+var (
+	failureSchema = elementSchema{
+		attrs: map[string]attrSchema{
+			"message": {stringAttr, false},
+			"type":    {stringAttr, false},
+		},
+		text: true,
+	}
+
+	outputSchema = elementSchema{text: true}
+
+	junitSchema = map[string]elementSchema{
+		"testsuites": {
+			attrs: map[string]attrSchema{
+				"name":     {stringAttr, false},
+				"time":     {decimalAttr, false},
+				"tests":    {intAttr, false},
+				"failures": {intAttr, false},
+				"disabled": {intAttr, false},
+				"errors":   {intAttr, false},
+			},
+			children: []childSchema{{"testsuite", 0, -1}},
+		},
+		"testsuite": {
+			attrs: map[string]attrSchema{
+				"name":      {stringAttr, true},
+				"tests":     {intAttr, true},
+				"failures":  {intAttr, false},
+				"errors":    {intAttr, false},
+				"time":      {decimalAttr, false},
+				"disabled":  {intAttr, false},
+				"skipped":   {intAttr, false},
+				"timestamp": {timestampAttr, false},
+				"hostname":  {stringAttr, false},
+				"id":        {stringAttr, false},
+				"package":   {stringAttr, false},
+			},
+			children: []childSchema{
+				{"properties", 0, 1},
+				{"testcase", 0, -1},
+				{"system-out", 0, 1},
+				{"system-err", 0, 1},
+			},
+		},
+		"properties": {
+			children: []childSchema{{"property", 1, -1}},
+		},
+		"property": {
+			attrs: map[string]attrSchema{
+				"name":  {stringAttr, true},
+				"value": {stringAttr, true},
+			},
+		},
+		"testcase": {
+			attrs: map[string]attrSchema{
+				"name":       {stringAttr, true},
+				"assertions": {stringAttr, false},
+				"time":       {decimalAttr, false},
+				"classname":  {stringAttr, false},
+				"status":     {stringAttr, false},
+			},
+			children: []childSchema{
+				{"skipped", 0, 1},
+				{"error", 0, -1},
+				{"failure", 0, -1},
+				{"flakyFailure", 0, -1},
+				{"system-out", 0, -1},
+				{"system-err", 0, -1},
+			},
+		},
+		"skipped": {
+			attrs: map[string]attrSchema{"message": {stringAttr, false}},
+			text:  true,
+		},
+		"error":        failureSchema,
+		"failure":      failureSchema,
+		"flakyFailure": failureSchema,
+		"system-out":   outputSchema,
+		"system-err":   outputSchema,
+	}
+)
+
+type xmlNode struct {
+	name     string
+	attrs    []xml.Attr
+	children []*xmlNode
+	text     string
+}
+
+func parseXML(data []byte) (*xmlNode, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var root *xmlNode
+	stack := []*xmlNode{}
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch tok := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: tok.Name.Local, attrs: tok.Attr}
+			if len(stack) == 0 {
+				root = node
+			} else {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			stack = stack[0 : len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(tok)
+			}
+		}
+	}
+	return root, nil
+}
+
+// validate returns the ways in which the node violates the schema.
+func validate(node *xmlNode, path string) []string {
+	path += "/" + node.name
+	schema, ok := junitSchema[node.name]
+	if !ok {
+		return []string{fmt.Sprintf("%s: unknown element", path)}
+	}
+
+	errs := []string{}
+	seen := map[string]bool{}
+	for _, attr := range node.attrs {
+		seen[attr.Name.Local] = true
+		attrSchema, ok := schema.attrs[attr.Name.Local]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("%s: unknown attribute %s", path, attr.Name.Local))
+			continue
+		}
+		if pattern, ok := attrPatterns[attrSchema.typ]; ok && !pattern.MatchString(attr.Value) {
+			errs = append(
+				errs,
+				fmt.Sprintf("%s: malformed attribute %s=%q", path, attr.Name.Local, attr.Value),
+			)
+		}
+	}
+	for name, attrSchema := range schema.attrs {
+		if attrSchema.required && !seen[name] {
+			errs = append(errs, fmt.Sprintf("%s: missing attribute %s", path, name))
+		}
+	}
+
+	if !schema.text && strings.TrimSpace(node.text) != "" {
+		errs = append(errs, fmt.Sprintf("%s: unexpected text", path))
+	}
+
+	children := node.children
+	for _, child := range schema.children {
+		n := 0
+		for len(children) > 0 && children[0].name == child.name {
+			errs = append(errs, validate(children[0], path)...)
+			children = children[1:]
+			n++
+		}
+		if n < child.min || (child.max >= 0 && n > child.max) {
+			errs = append(errs, fmt.Sprintf("%s: %d %s elements", path, n, child.name))
+		}
+	}
+	for _, child := range children {
+		errs = append(errs, fmt.Sprintf("%s: unexpected %s element", path, child.name))
+	}
+
+	return errs
+}
+
+func validateReport(t *testing.T, pkgs []*results.TestPackage) {
+	var buf bytes.Buffer
+	WriteReport(&buf, pkgs)
+
+	root, err := parseXML(buf.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, root.name, "testsuites")
+	assert.ArrayEqual(t, validate(root, ""), []string{})
+}
+
+func TestValidate(t *testing.T) {
+	root, err := parseXML([]byte(`
+<testsuites>
+	<testsuite tests="x" timestamp="yesterday">
+		<properties></properties>
+		<testcase name="a"><failure/><skipped/>text</testcase>
+	</testsuite>
+	<bogus/>
+</testsuites>`))
+	assert.Nil(t, err)
+	assert.HasSameElements(t, validate(root, ""), []string{
+		`/testsuites/testsuite: malformed attribute tests="x"`,
+		`/testsuites/testsuite: malformed attribute timestamp="yesterday"`,
+		"/testsuites/testsuite: missing attribute name",
+		"/testsuites/testsuite/properties: 0 property elements",
+		"/testsuites/testsuite/testcase: unexpected text",
+		"/testsuites/testsuite/testcase: unexpected skipped element",
+		"/testsuites: unexpected bogus element",
+	})
+}
+
+func TestWriteReportIsValid(t *testing.T) {
+	for _, pkgs := range [][]*results.TestPackage{
+		passingSuite,
+		failingSuite,
+		skippedSuite,
+		subtestSuite,
+		benchmarkSuite,
+		suiteOutput,
+		environmentSuite,
+		buildFailureSuite,
+	} {
+		validateReport(t, pkgs)
+	}
+
+	validateReport(t, []*results.TestPackage{
+		{
+			Name:   "github.com/turbinelabs/something",
+			Result: results.Passed,
+			Start:  time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC),
+			Tests: []*results.Test{
+				{Name: "TestFlaky", Result: results.Flaky, Retries: 1},
+				{Name: "TestPanic", Result: results.Failed, FailureKind: results.Panic},
+			},
+			Coverage: &results.Coverage{Statements: 1},
+		},
+	})
+}
@@ -17,31 +17,82 @@ limitations under the License.
 package main
 
 import (
-	"bytes"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"sync"
-	"syscall"
 	"time"
 
-	"github.com/turbinelabs/test/testrunner/junit"
 	"github.com/turbinelabs/test/testrunner/parser"
+	"github.com/turbinelabs/test/testrunner/report"
 	"github.com/turbinelabs/test/testrunner/results"
 )
 
 const (
 	ENV_ROOT_PACKAGE = "TEST_RUNNER_ROOT_PACKAGE"
 	ENV_OUTPUT_DIR   = "TEST_RUNNER_OUTPUT"
+	ENV_PARSER       = "TEST_RUNNER_PARSER"
+	ENV_FORMATS      = "TEST_RUNNER_FORMATS"
+	ENV_PROPERTIES   = "TEST_RUNNER_ENV_PROPERTIES"
+
+	ENV_BENCH_TOLERANCE = "TEST_RUNNER_BENCH_TOLERANCE"
+	ENV_BENCH_BASELINE  = "TEST_RUNNER_BENCH_BASELINE"
+
+	ENV_TIMEOUT      = "TEST_RUNNER_TIMEOUT"
+	ENV_TEST_TIMEOUT = "TEST_RUNNER_TEST_TIMEOUT"
+
+	ENV_RETRIES = "TEST_RUNNER_RETRIES"
+
+	ENV_COVERAGE  = "TEST_RUNNER_COVERAGE"
+	ENV_COBERTURA = "TEST_RUNNER_COBERTURA"
+
+	ENV_SHARD_INDEX = "TEST_RUNNER_SHARD_INDEX"
+	ENV_SHARD_COUNT = "TEST_RUNNER_SHARD_COUNT"
+
+	ENV_PROGRESS  = "TEST_RUNNER_PROGRESS"
+	ENV_SLOW_TEST = "TEST_RUNNER_SLOW_TEST"
+
+	ENV_HISTORY = "TEST_RUNNER_HISTORY"
 )
 
 var RootPackage = getEnv(ENV_ROOT_PACKAGE, "github.com/turbinelabs")
 
 var TestOutput = getEnv(ENV_OUTPUT_DIR, "testresults")
 
+var ParserName = getEnv(ENV_PARSER, "golang")
+
+var Formats = getEnv(ENV_FORMATS, "junit")
+
+var EnvProperties = getEnv(ENV_PROPERTIES, "")
+
+var BenchTolerance = getEnv(ENV_BENCH_TOLERANCE, "")
+
+var BenchBaseline = getEnv(ENV_BENCH_BASELINE, "")
+
+var PackageTimeout = getEnv(ENV_TIMEOUT, "")
+
+var TestTimeout = getEnv(ENV_TEST_TIMEOUT, "")
+
+var Retries = getEnv(ENV_RETRIES, "0")
+
+var Coverage = getEnv(ENV_COVERAGE, "false")
+
+var Cobertura = getEnv(ENV_COBERTURA, "")
+
+var ShardIndex = getEnv(ENV_SHARD_INDEX, "0")
+
+var ShardCount = getEnv(ENV_SHARD_COUNT, "1")
+
+var ProgressMode = getEnv(ENV_PROGRESS, progressOutput)
+
+var SlowTest = getEnv(ENV_SLOW_TEST, "")
+
+var History = getEnv(ENV_HISTORY, "false")
+
 type lockedWriter struct {
 	lock       *sync.Mutex
 	underlying io.Writer
@@ -62,92 +113,496 @@ func newLockedWriter(underlying io.Writer) io.Writer {
 }
 
 func main() {
-	testExecutable := os.Args[1]
-	pkgName := extractPackageFromTestExecutable(testExecutable)
-	pkgFileName := strings.Replace(pkgName, "/", ".", -1)
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: testrunner (test-executable [args] | go test [args] | - | merge | history)")
+		os.Exit(exitUsage)
+	}
 
-	testArgs := parser.GoLangParser.FlagFn(os.Args[2:])
+	switch os.Args[1] {
+	case "merge":
+		os.Exit(runMerge(os.Args[2:]))
+	case "history":
+		os.Exit(runHistory(os.Args[2:]))
+	}
 
-	test := exec.Command(testExecutable, testArgs...)
+	defer exitOnPanic()
 
-	output := new(bytes.Buffer)
-	outputWriter := newLockedWriter(output)
+	// collect every configuration problem before reporting them
+	errs := append([]error{}, configErrs...)
+	check := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
 
-	test.Stdout = io.MultiWriter(outputWriter, os.Stdout)
-	test.Stderr = io.MultiWriter(outputWriter, os.Stderr)
+	testParser, err := selectParser()
+	check(err)
+	reporters, err := selectReporters()
+	check(err)
+	limits, err := getTimeouts()
+	check(err)
+	retries, err := getRetries()
+	check(err)
+	captureCoverage, err := getCoverage()
+	check(err)
+	benchTolerance, err := getBenchTolerance()
+	check(err)
+	testShard, err := getShard()
+	check(err)
+	progress, err := getDisplay()
+	check(err)
+	recordHistory, err := getHistory()
+	check(err)
+	check(checkOutputDir())
+
+	switch os.Args[1] {
+	case "-":
+		check(requireUnsharded(testShard, "parsing go test output"))
+	case "go":
+		check(requireUnsharded(testShard, "running go test"))
+	}
 
-	start := time.Now()
-	exitStatus := 0
-	if err := test.Run(); err != nil {
-		switch e := err.(type) {
-		case *exec.ExitError:
-			switch sysStatus := e.ProcessState.Sys().(type) {
-			case syscall.WaitStatus:
-				exitStatus = sysStatus.ExitStatus()
-			default:
-				exitStatus = 1
+	if reportErrors(errs) {
+		os.Exit(exitUsage)
+	}
+
+	var (
+		pkgName        string
+		testExecutable string   // only if running a test executable
+		testArgs       []string // only if running a test executable
+		profile        *coverProfile
+		stream         *parser.Stream
+		interrupts     *interrupts // only if running a test command
+		run            testRun
+		start          = time.Now()
+	)
+
+	switch os.Args[1] {
+	case "-":
+		// parse go test output from stdin
+		pkgName = packageFromWorkingDir()
+		stream = testParser.StreamFn(pkgName)
+
+		var out io.Writer = stream
+		if !progress.status {
+			out = io.MultiWriter(stream, os.Stdout)
+		}
+
+		stop := watchProgress(stream, progress)
+		if _, err := io.Copy(out, os.Stdin); err != nil {
+			exitWithRunnerError(err)
+		}
+		stop()
+		run.duration = time.Since(start)
+
+	case "go":
+		// run go test against one or more packages; go test enforces
+		// the package timeout itself
+		pkgName = packageFromWorkingDir()
+		goTestArgs := testParser.GoTestFlagFn(os.Args[2:])
+		if limits.pkg > 0 {
+			goTestArgs = goTestTimeoutFlag(goTestArgs, limits.pkg)
+		}
+		if captureCoverage {
+			p := newCoverProfile(goTestArgs, "-coverprofile", "-outputdir")
+			if p.temporary {
+				goTestArgs = append(goTestArgs, "-coverprofile="+p.path)
+			}
+			profile = &p
+		}
+		stream = testParser.StreamFn(pkgName)
+		interrupts = handleInterrupts()
+		run, err = runTest(
+			exec.Command("go", goTestArgs...),
+			nil,
+			timeouts{},
+			progress,
+			stream,
+			interrupts,
+		)
+		if err != nil {
+			exitWithRunnerError(err)
+		}
+		// go test's resource usage includes building the packages
+		run.usage = nil
+
+	default:
+		testExecutable = os.Args[1]
+		pkgName = extractPackageFromTestExecutable(testExecutable)
+
+		testArgs = os.Args[2:]
+		if testShard.count > 1 {
+			testArgs, err = shardTestArgs(testShard, pkgName, testExecutable, testArgs)
+			if err != nil {
+				exitWithRunnerError(err)
 			}
-		default:
-			panic(err)
 		}
-	}
 
-	duration := time.Since(start)
+		runParser := testParser
+		if captureCoverage {
+			p := newCoverProfile(testArgs, "-test.coverprofile", "-test.outputdir")
+			runParser = withCoverProfile(testParser, p)
+			profile = &p
+		}
 
-	pkgs, err := parser.GoLangParser.ParseFn(pkgName, duration, output)
+		stream = testParser.StreamFn(pkgName)
+		interrupts = handleInterrupts()
+		run, err = runTestExecutable(
+			runParser,
+			pkgName,
+			testExecutable,
+			testArgs,
+			limits,
+			progress,
+			stream,
+			interrupts,
+		)
+		if err != nil {
+			exitWithRunnerError(err)
+		}
+	}
+
+	pkgs, err := stream.Finish(run.duration)
 	if err != nil {
-		panic(err)
+		fmt.Fprintf(os.Stderr, "testrunner: parsing test output: %v\n", err)
+		os.Exit(exitParseError)
+	}
+
+	env := environment()
+	for _, pkg := range pkgs {
+		if pkg.Start.IsZero() {
+			pkg.Start = start
+		}
+		pkg.Environment = env
+		pkg.Usage = run.usage
+	}
+
+	if run.signal != nil {
+		markInterrupted(pkgs, run.interrupted)
+	}
+
+	if profile != nil {
+		recordCoverage(pkgName, pkgs, *profile)
+	}
+
+	if testExecutable != "" && retries > 0 && run.signal == nil {
+		run, err = retryFailedTests(
+			testParser,
+			pkgs[0],
+			testExecutable,
+			testArgs,
+			limits,
+			progress,
+			interrupts,
+			retries,
+			run,
+		)
+		if err != nil {
+			exitWithRunnerError(err)
+		}
 	}
 
-	// Parsing errors may result in the package being marked as a
-	// failure even though the test binary reported success.
-	// Convert exit status to failure since it may mean some test
-	// results were not properly parsed.
+	checkBenchmarks(pkgs, benchTolerance)
+
 	for _, pkg := range pkgs {
-		if pkg.Result == results.Failed && exitStatus == 0 {
-			exitStatus = 1
+		for _, r := range reporters {
+			writeReport(pkg, r)
+		}
+	}
+
+	if recordHistory {
+		appendHistory(pkgs)
+	}
+
+	if Cobertura != "" {
+		writeCobertura()
+	}
+
+	os.Exit(runExitStatus(run, pkgs))
+}
+
+// reportErrors writes any configuration errors to stderr, returning
+// true if there were any.
+func reportErrors(errs []error) bool {
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "testrunner: %v\n", err)
+	}
+	return len(errs) > 0
+}
+
+// testRun describes a run of a test command.
+type testRun struct {
+	exitStatus int
+	duration   time.Duration
+	usage      *results.ResourceUsage // nil if unknown
+	timeout    string                 // the timeout exceeded, if any
+
+	// The signal that interrupted the run, if any, and the top-level
+	// tests unfinished at the time.
+	signal      os.Signal
+	interrupted []string
+}
+
+// runTestExecutable runs the test executable with the given
+// arguments, as modified for the parser, parsing its (possibly
+// filtered) output with the given stream.
+func runTestExecutable(
+	testParser parser.Parser,
+	pkgName string,
+	testExecutable string,
+	args []string,
+	limits timeouts,
+	progress display,
+	stream *parser.Stream,
+	interrupts *interrupts,
+) (testRun, error) {
+	var filter *exec.Cmd
+	if testParser.FilterFn != nil {
+		filterCmd, filterArgs := testParser.FilterFn(pkgName)
+		filter = exec.Command(filterCmd, filterArgs...)
+	}
+
+	return runTest(
+		exec.Command(testExecutable, testParser.FlagFn(args)...),
+		filter,
+		limits,
+		progress,
+		stream,
+		interrupts,
+	)
+}
+
+// runTest runs the given test command, parsing its output as it is
+// written with the given stream. If filter is not nil, the test's
+// output is piped through it and the filter's output is parsed
+// instead. The test's output is also copied to stdout and stderr,
+// unless a status line is displayed instead. The test is sent SIGQUIT
+// if it exceeds the given timeouts. The test runs in its own process
+// group, to which interrupts forwards any signals testrunner
+// receives. Returns an error if the test could not be run.
+func runTest(
+	test *exec.Cmd,
+	filter *exec.Cmd,
+	limits timeouts,
+	progress display,
+	stream *parser.Stream,
+	interrupts *interrupts,
+) (testRun, error) {
+	var outputWriter io.Writer = stream
+
+	testOutput := outputWriter
+	var pipeWriter *os.File
+	if filter != nil {
+		var (
+			pipeReader *os.File
+			err        error
+		)
+		pipeReader, pipeWriter, err = os.Pipe()
+		if err != nil {
+			return testRun{}, err
 		}
+
+		// the filter is left to exit once it has consumed all of the
+		// test's output, rather than being interrupted with it
+		setProcessGroup(filter)
+		filter.Stdin = pipeReader
+		filter.Stdout = outputWriter
+		filter.Stderr = io.MultiWriter(outputWriter, os.Stderr)
+		err = filter.Start()
+		pipeReader.Close()
+		if err != nil {
+			pipeWriter.Close()
+			return testRun{}, err
+		}
+
+		testOutput = newLockedWriter(pipeWriter)
 	}
 
-	report := openFile(pkgFileName)
-	defer report.Close()
+	test.Stdout, test.Stderr = testOutput, testOutput
+	if !progress.status {
+		test.Stdout = io.MultiWriter(testOutput, os.Stdout)
+		test.Stderr = io.MultiWriter(testOutput, os.Stderr)
+	}
+	setProcessGroup(test)
 
-	junit.WriteReport(report, pkgs)
+	start := time.Now()
+	if err := test.Start(); err != nil {
+		if filter != nil {
+			pipeWriter.Close()
+			filter.Wait()
+		}
+		return testRun{}, err
+	}
+	interrupts.start(test.Process, stream)
+	stopProgress := watchProgress(stream, progress)
+	timedOut := enforceTimeouts(test.Process, stream, limits)
+	exitStatus, waitErr := exitStatusOf(test.Wait())
+	run := testRun{
+		exitStatus: exitStatus,
+		duration:   time.Since(start),
+		timeout:    timedOut(),
+	}
+	interrupts.stop()
+	run.signal, run.interrupted = interrupts.received()
+
+	if filter != nil {
+		// the filter exits once it has consumed all of the test's
+		// output; its exit status is of no interest
+		pipeWriter.Close()
+		filter.Wait()
+	}
+	stopProgress()
 
-	os.Exit(exitStatus)
+	if waitErr != nil {
+		return testRun{}, waitErr
+	}
+	run.usage = resourceUsage(test.ProcessState, run.duration)
+
+	var note string
+	switch {
+	case run.signal != nil:
+		note = fmt.Sprintf("\n[%s: stopped tests]\n", run.signal)
+	case run.timeout != "":
+		note = fmt.Sprintf("\n[%s: sent SIGQUIT]\n", run.timeout)
+	}
+	if note != "" {
+		fmt.Fprint(os.Stderr, note)
+		outputWriter.Write([]byte(note))
+	}
+
+	return run, nil
 }
 
-func openFile(pkgFileName string) *os.File {
-	dirInfo, err := os.Stat(TestOutput)
-	if err == nil {
-		if !dirInfo.IsDir() {
-			panic(
-				fmt.Sprintf(
-					"Env var %s=%s is not a directory",
-					ENV_OUTPUT_DIR,
-					TestOutput))
+// goTestTimeoutFlag adds go test's -timeout flag, unless present.
+func goTestTimeoutFlag(args []string, timeout time.Duration) []string {
+	for _, arg := range args {
+		if arg == "-timeout" || strings.HasPrefix(arg, "-timeout=") {
+			return args
 		}
-	} else if os.IsNotExist(err) {
-		if err := os.MkdirAll(TestOutput, 0755); err != nil {
-			panic(err)
+	}
+
+	return append(args, fmt.Sprintf("-timeout=%s", timeout))
+}
+
+// writeReport writes the package's report, in the reporter's format,
+// to a file in the output directory named after the package.
+func writeReport(pkg *results.TestPackage, r report.Reporter) {
+	f := openFile(reportFileName(pkg.Name, r.Extension()))
+	defer f.Close()
+
+	r.WriteReport(f, []*results.TestPackage{pkg})
+}
+
+func selectParser() (parser.Parser, error) {
+	p, ok := parser.Parsers[ParserName]
+	if !ok {
+		return parser.Parser{}, invalidSetting(ENV_PARSER, ParserName, "is not a known parser")
+	}
+	return p, nil
+}
+
+// selectReporters returns the reporters for the comma-separated
+// format names in Formats.
+func selectReporters() ([]report.Reporter, error) {
+	reporters := []report.Reporter{}
+	for _, name := range strings.Split(Formats, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
 		}
-	} else {
+
+		r, ok := report.Reporters[name]
+		if !ok {
+			return nil, invalidSetting(
+				ENV_FORMATS,
+				Formats,
+				fmt.Sprintf("contains unknown format %q", name),
+			)
+		}
+		reporters = append(reporters, r)
+	}
+
+	if len(reporters) == 0 {
+		return nil, invalidSetting(ENV_FORMATS, Formats, "does not name any formats")
+	}
+	return reporters, nil
+}
+
+// environment describes the environment in which the tests ran,
+// including the environment variables named in EnvProperties.
+func environment() *results.Environment {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = ""
+	}
+
+	env := &results.Environment{
+		Hostname:  hostname,
+		GOOS:      runtime.GOOS,
+		GOARCH:    runtime.GOARCH,
+		GoVersion: runtime.Version(),
+		Vars:      map[string]string{},
+	}
+
+	for _, name := range strings.Split(EnvProperties, ",") {
+		name = strings.TrimSpace(name)
+		if value, ok := os.LookupEnv(name); ok && name != "" {
+			env.Vars[name] = value
+		}
+	}
+	return env
+}
+
+// reportFileName returns the name of the named package's report,
+// given the report's file extension.
+func reportFileName(pkgName, extension string) string {
+	return strings.Replace(pkgName, "/", ".", -1) + extension
+}
+
+// checkOutputDir returns an error if the output directory exists but
+// is not a directory.
+func checkOutputDir() error {
+	info, err := os.Stat(TestOutput)
+	switch {
+	case os.IsNotExist(err):
+		return nil
+	case err != nil:
+		return invalidSetting(ENV_OUTPUT_DIR, TestOutput, err.Error())
+	case !info.IsDir():
+		return invalidSetting(ENV_OUTPUT_DIR, TestOutput, "is not a directory")
+	}
+	return nil
+}
+
+// openFile creates (or truncates) the named file in the output
+// directory, which is created if necessary. The output directory is
+// assumed to have been checked by checkOutputDir.
+func openFile(reportFileName string) *os.File {
+	return createFile(filepath.Join(TestOutput, reportFileName))
+}
+
+// createFile creates or truncates the file at the given path, creating
+// its parent directories as needed.
+func createFile(path string) *os.File {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
 		panic(err)
 	}
 
-	reportFile := filepath.Join(TestOutput, fmt.Sprintf("%s.xml", pkgFileName))
-	report, err := os.OpenFile(reportFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	report, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
 	if err != nil {
 		panic(err)
 	}
 	return report
 }
 
-func getEnv(name, defaultValue string) string {
-	if value, present := os.LookupEnv(name); present {
-		return value
+// packageFromWorkingDir returns the default package name used when
+// the package name cannot be derived from a test executable.
+func packageFromWorkingDir() string {
+	wd, err := os.Getwd()
+	if err != nil {
+		panic(err)
 	}
-	return defaultValue
+	return extractPackageFromTestExecutable(wd)
 }
 
 func extractPackageFromTestExecutable(exec string) string {
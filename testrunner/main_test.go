@@ -17,9 +17,12 @@ limitations under the License.
 package main
 
 import (
+	"os"
+	"runtime"
 	"testing"
 
 	"github.com/turbinelabs/test/assert"
+	"github.com/turbinelabs/test/testrunner/report"
 )
 
 func TestExtractPackageFromTestExecutable(t *testing.T) {
@@ -34,3 +37,55 @@ func TestExtractPackageFromTestExecutable(t *testing.T) {
 
 	assert.Equal(t, result, "github.com/foo/bar/package/sub")
 }
+
+func TestSelectReporters(t *testing.T) {
+	saved := Formats
+	defer func() {
+		Formats = saved
+	}()
+
+	Formats = "junit"
+	reporters, err := selectReporters()
+	assert.Nil(t, err)
+	assert.DeepEqual(t, reporters, []report.Reporter{report.JUnitReporter})
+
+	Formats = "tap, markdown,,json"
+	reporters, err = selectReporters()
+	assert.Nil(t, err)
+	assert.DeepEqual(
+		t,
+		reporters,
+		[]report.Reporter{report.TAPReporter, report.MarkdownReporter, report.JSONReporter},
+	)
+
+	Formats = "junit,xunit"
+	_, err = selectReporters()
+	assert.ErrorContains(t, err, `TEST_RUNNER_FORMATS=junit,xunit contains unknown format "xunit"`)
+
+	Formats = " , "
+	_, err = selectReporters()
+	assert.ErrorContains(t, err, "does not name any formats")
+}
+
+func TestReportFileName(t *testing.T) {
+	assert.Equal(t, reportFileName("github.com/foo/bar", ".xml"), "github.com.foo.bar.xml")
+	assert.Equal(t, reportFileName("github.com/foo/bar", ".md"), "github.com.foo.bar.md")
+}
+
+func TestEnvironment(t *testing.T) {
+	saved := EnvProperties
+	defer func() {
+		EnvProperties = saved
+	}()
+
+	os.Setenv("TEST_RUNNER_TEST_PROPERTY", "xyz")
+	defer os.Unsetenv("TEST_RUNNER_TEST_PROPERTY")
+	os.Unsetenv("TEST_RUNNER_TEST_UNSET")
+
+	EnvProperties = "TEST_RUNNER_TEST_PROPERTY, TEST_RUNNER_TEST_UNSET,"
+	env := environment()
+	assert.Equal(t, env.GOOS, runtime.GOOS)
+	assert.Equal(t, env.GOARCH, runtime.GOARCH)
+	assert.Equal(t, env.GoVersion, runtime.Version())
+	assert.MapEqual(t, env.Vars, map[string]string{"TEST_RUNNER_TEST_PROPERTY": "xyz"})
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/turbinelabs/test/testrunner/junit"
+	"github.com/turbinelabs/test/testrunner/report"
+)
+
+// MergedReportFile is the name of the report written by the merge
+// command, in the output directory, unless another is given.
+const MergedReportFile = "merged.xml"
+
+// runMerge implements the merge command: it merges the junit reports
+// in the output directory into a single report, written to the named
+// file (or MergedReportFile), and prints a summary. Returns 1 if any
+// test failed, and 0 otherwise.
+func runMerge(args []string) int {
+	if len(args) > 1 {
+		fmt.Fprintln(os.Stderr, "usage: testrunner merge [output-file]")
+		return exitUsage
+	}
+
+	errs := append([]error{}, configErrs...)
+	if err := checkOutputDir(); err != nil {
+		errs = append(errs, err)
+	}
+	if reportErrors(errs) {
+		return exitUsage
+	}
+
+	outputFile := filepath.Join(TestOutput, MergedReportFile)
+	if len(args) == 1 {
+		outputFile = args[0]
+	}
+
+	filenames, err := filepath.Glob(
+		filepath.Join(TestOutput, "*"+report.JUnitReporter.Extension()),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	// neither the merged report nor a Cobertura report is an input
+	skip := map[string]bool{absPath(outputFile): true}
+	if Cobertura != "" {
+		skip[absPath(coberturaPath())] = true
+	}
+
+	reports := []junit.JunitTestSuites{}
+	for _, filename := range filenames {
+		if skip[absPath(filename)] {
+			continue
+		}
+
+		suites, err := readReport(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "testrunner: skipping %s: %v\n", filename, err)
+			continue
+		}
+		reports = append(reports, suites)
+	}
+
+	merged := junit.MergeReports(reports...)
+
+	var f *os.File
+	if len(args) == 1 {
+		f, err = os.Create(outputFile)
+		if err != nil {
+			panic(err)
+		}
+	} else {
+		f = openFile(MergedReportFile)
+	}
+	junit.WriteSuites(f, merged)
+	if err := f.Close(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println(mergeSummary(merged))
+
+	if merged.Failures > 0 || merged.Errors > 0 {
+		return exitFailed
+	}
+	return exitPassed
+}
+
+func readReport(filename string) (junit.JunitTestSuites, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return junit.JunitTestSuites{}, err
+	}
+	defer f.Close()
+
+	return junit.ReadReport(f)
+}
+
+// mergeSummary summarizes a merged report in a single line.
+func mergeSummary(merged junit.JunitTestSuites) string {
+	skipped, flaky := 0, 0
+	for _, suite := range merged.Suites {
+		skipped += suite.Skipped
+		for _, testCase := range suite.TestCases {
+			if testCase.FlakyFailure != nil {
+				flaky++
+			}
+		}
+	}
+
+	status := "PASS"
+	if merged.Failures > 0 || merged.Errors > 0 {
+		status = "FAIL"
+	}
+
+	return fmt.Sprintf(
+		"%s: %d packages, %d tests, %d failed, %d errors, %d skipped, %d flaky (%ss)",
+		status,
+		len(merged.Suites),
+		merged.Tests,
+		merged.Failures,
+		merged.Errors,
+		skipped,
+		flaky,
+		merged.Duration,
+	)
+}
+
+func absPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	return abs
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/turbinelabs/test/assert"
+	"github.com/turbinelabs/test/tempfile"
+	"github.com/turbinelabs/test/testrunner/junit"
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+func writeJunitReport(t *testing.T, path string, pkgs ...*results.TestPackage) {
+	var buf bytes.Buffer
+	junit.WriteReport(&buf, pkgs)
+	assert.Nil(t, ioutil.WriteFile(path, buf.Bytes(), 0644))
+}
+
+func readMergedReport(t *testing.T, path string) junit.JunitTestSuites {
+	f, err := os.Open(path)
+	assert.Nil(t, err)
+	defer f.Close()
+
+	suites, err := junit.ReadReport(f)
+	assert.Nil(t, err)
+	return suites
+}
+
+func TestRunMerge(t *testing.T) {
+	dir := tempfile.TempDir(t, "testrunner-merge")
+	defer dir.Cleanup()
+
+	savedOutput, savedCobertura := TestOutput, Cobertura
+	defer func() {
+		TestOutput, Cobertura = savedOutput, savedCobertura
+	}()
+	TestOutput = dir.Path()
+	Cobertura = "coverage.xml"
+
+	start := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	writeJunitReport(
+		t,
+		filepath.Join(dir.Path(), "a.xml"),
+		&results.TestPackage{
+			Name:     "example.com/a",
+			Result:   results.Passed,
+			Start:    start,
+			Duration: 1.0,
+			Tests: []*results.Test{
+				{Name: "TestA", Result: results.Passed},
+				{Name: "TestFlaky", Result: results.Flaky, Retries: 1},
+			},
+		},
+	)
+	writeJunitReport(
+		t,
+		filepath.Join(dir.Path(), "b.xml"),
+		&results.TestPackage{
+			Name:     "example.com/b",
+			Result:   results.Passed,
+			Start:    start,
+			Duration: 2.0,
+			Tests: []*results.Test{
+				{Name: "TestB", Result: results.Passed},
+				{Name: "TestSkipped", Result: results.Skipped},
+			},
+		},
+	)
+
+	// neither are junit reports
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir.Path(), "coverage.xml"), []byte("<coverage/>"), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir.Path(), "other.xml"), []byte("<other/>"), 0644))
+
+	assert.Equal(t, runMerge(nil), exitPassed)
+
+	mergedPath := filepath.Join(dir.Path(), MergedReportFile)
+	merged := readMergedReport(t, mergedPath)
+	assert.Equal(t, len(merged.Suites), 2)
+	assert.Equal(t, merged.Tests, 4)
+	assert.Equal(t, merged.Failures, 0)
+	assert.Equal(t, merged.Duration, "3.000")
+	assert.Equal(
+		t,
+		mergeSummary(merged),
+		"PASS: 2 packages, 4 tests, 0 failed, 0 errors, 1 skipped, 1 flaky (3.000s)",
+	)
+
+	// the merged report is not an input to the next merge
+	writeJunitReport(
+		t,
+		filepath.Join(dir.Path(), "c.xml"),
+		&results.TestPackage{
+			Name:   "example.com/c",
+			Result: results.Failed,
+			Start:  start,
+			Tests:  []*results.Test{{Name: "TestC", Result: results.Failed}},
+		},
+	)
+	assert.Equal(t, runMerge(nil), exitFailed)
+
+	merged = readMergedReport(t, mergedPath)
+	assert.Equal(t, len(merged.Suites), 3)
+	assert.Equal(t, merged.Tests, 5)
+	assert.Equal(t, merged.Failures, 1)
+
+	// the merged report may be written elsewhere
+	otherPath := filepath.Join(dir.Path(), "elsewhere", "all.xml")
+	assert.Nil(t, os.Mkdir(filepath.Dir(otherPath), 0755))
+	assert.Equal(t, runMerge([]string{otherPath}), exitFailed)
+	assert.DeepEqual(t, readMergedReport(t, otherPath), merged)
+
+	assert.Equal(t, runMerge([]string{"a", "b"}), exitUsage)
+}
+
+func TestMergeSummary(t *testing.T) {
+	summary := mergeSummary(junit.JunitTestSuites{
+		Tests:    3,
+		Errors:   1,
+		Duration: "1.500",
+		Suites:   []junit.JunitTestSuite{{}, {}},
+	})
+	assert.Equal(t, summary, "FAIL: 2 packages, 3 tests, 0 failed, 1 errors, 0 skipped, 0 flaky (1.500s)")
+}
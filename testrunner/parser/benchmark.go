@@ -0,0 +1,94 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+var (
+	// matches benchmark result lines, e.g.:
+	//   BenchmarkFoo-8   1000000   1234 ns/op   56 B/op   2 allocs/op
+	benchmarkRegex = regexp.MustCompile(`^(Benchmark\S*?)(?:-(\d+))?\s+(\d+)\s+(\S.*)$`)
+
+	procsSuffixRegex = regexp.MustCompile(`-\d+$`)
+)
+
+// parseBenchmark parses a benchmark result line, returning nil if the
+// line is not a benchmark result.
+func parseBenchmark(line string) *results.Benchmark {
+	m := benchmarkRegex.FindStringSubmatch(strings.TrimSpace(line))
+	if len(m) != 5 {
+		return nil
+	}
+
+	fields := strings.Fields(m[4])
+	if len(fields)%2 != 0 {
+		return nil
+	}
+
+	iterations, err := strconv.ParseInt(m[3], 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	b := &results.Benchmark{
+		Name:       m[1],
+		Iterations: iterations,
+		Metrics:    map[string]float64{},
+	}
+
+	if m[2] != "" {
+		b.Procs, _ = strconv.Atoi(m[2])
+	}
+
+	for i := 0; i < len(fields); i += 2 {
+		value, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return nil
+		}
+
+		switch unit := fields[i+1]; unit {
+		case "ns/op":
+			b.NsPerOp = value
+		case "B/op":
+			b.HasMemStats = true
+			b.BytesPerOp = value
+		case "allocs/op":
+			b.HasMemStats = true
+			b.AllocsPerOp = value
+		default:
+			b.Metrics[unit] = value
+		}
+	}
+
+	return b
+}
+
+// benchmarkName strips the GOMAXPROCS suffix go test adds to the
+// names of benchmarks in their result lines, if the given name is
+// not itself a known test.
+func (tt *testTree) benchmarkName(name string) string {
+	if _, ok := tt.tests[name]; ok || !strings.HasPrefix(name, "Benchmark") {
+		return name
+	}
+	return procsSuffixRegex.ReplaceAllString(name, "")
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package parser
+
+import (
+	"testing"
+
+	"github.com/turbinelabs/test/assert"
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+func TestParseBenchmark(t *testing.T) {
+	b := parseBenchmark("BenchmarkFoo-8   \t 1000000\t  1234 ns/op\t  56 B/op\t  2 allocs/op\n")
+	assert.DeepEqual(t, b, &results.Benchmark{
+		Name:        "BenchmarkFoo",
+		Procs:       8,
+		Iterations:  1000000,
+		NsPerOp:     1234,
+		HasMemStats: true,
+		BytesPerOp:  56,
+		AllocsPerOp: 2,
+		Metrics:     map[string]float64{},
+	})
+	assert.Equal(t, b.FullName(), "BenchmarkFoo-8")
+
+	b = parseBenchmark("BenchmarkBar/size=1.5 \t     100\t 3.680 ns/op\t 12.50 MB/s\t 3.000 widgets/op")
+	assert.DeepEqual(t, b, &results.Benchmark{
+		Name:       "BenchmarkBar/size=1.5",
+		Iterations: 100,
+		NsPerOp:    3.68,
+		Metrics: map[string]float64{
+			"MB/s":       12.5,
+			"widgets/op": 3,
+		},
+	})
+	assert.Equal(t, b.FullName(), "BenchmarkBar/size=1.5")
+
+	for _, notBenchmark := range []string{
+		"BenchmarkFoo",
+		"BenchmarkFoo-8",
+		"=== RUN   BenchmarkFoo",
+		"BenchmarkFoo 100 3.68",
+		"BenchmarkFoo 100 fast ns/op",
+		"TestFoo 100 3.68 ns/op",
+	} {
+		assert.Nil(t, parseBenchmark(notBenchmark))
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+var (
+	// matches compiler and vet diagnostics, e.g.:
+	//   ./foo.go:12:3: undefined: bar
+	//   ../baz/baz.go:7:39: fmt.Sprintf format %d has arg "x" of wrong type string
+	diagnosticRegex = regexp.MustCompile(`^(\S+\.go):(\d+)(?::(\d+))?: (.+)$`)
+
+	// matches go test's result line for a package that did not
+	// build, e.g. "FAIL	github.com/foo/bar [build failed]"
+	buildFailedRegex = regexp.MustCompile(`(?m)^FAIL\s+\S+\s+\[(?:build|setup) failed\]\s*$`)
+)
+
+// addBuildErrors records whether the package did not build, and if
+// so, the compiler and vet diagnostics in the package's output. Output
+// of a package that built is ignored, since tests may log lines
+// resembling diagnostics.
+func addBuildErrors(pkg *results.TestPackage, output string) {
+	if pkg.Result != results.Failed || !buildFailedRegex.MatchString(output) {
+		return
+	}
+	pkg.BuildFailed = true
+	pkg.BuildErrors = parseBuildErrors(output)
+}
+
+// parseBuildErrors returns the diagnostics found in the output of go
+// build or go vet. Indented lines following a diagnostic (e.g. the
+// "have" and "want" lines of a type mismatch) continue its message.
+func parseBuildErrors(output string) []*results.BuildError {
+	buildErrors := []*results.BuildError{}
+	var last *results.BuildError
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		if m := diagnosticRegex.FindStringSubmatch(line); m != nil {
+			last = &results.BuildError{File: m[1], Message: m[4]}
+			last.Line, _ = strconv.Atoi(m[2])
+			last.Column, _ = strconv.Atoi(m[3])
+			buildErrors = append(buildErrors, last)
+		} else if last != nil && strings.HasPrefix(line, "\t") {
+			last.Message += "\n" + strings.TrimSpace(line)
+		} else {
+			last = nil
+		}
+	}
+	return buildErrors
+}
+
+// buildPackageName returns the name of the package whose tests are
+// built by a go test -json build action, given its import path. The
+// import path of a package's test variant names the test executable
+// in brackets, e.g. "github.com/foo/bar_test [github.com/foo/bar.test]".
+func buildPackageName(importPath string) string {
+	if i := strings.Index(importPath, " ["); i >= 0 && strings.HasSuffix(importPath, ".test]") {
+		return strings.TrimSuffix(importPath[i+2:], ".test]")
+	}
+	return importPath
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package parser
+
+import (
+	"testing"
+
+	"github.com/turbinelabs/test/assert"
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+func TestParseBuildErrors(t *testing.T) {
+	buildErrors := parseBuildErrors(`# foo/bar
+./a.go:3:1: syntax error: unexpected }
+	and a continuation
+a_test.go:12: old-style diagnostic
+not a diagnostic
+	not a continuation
+./a.go:7:2: too many errors
+`)
+
+	assert.DeepEqual(t, buildErrors, []*results.BuildError{
+		{File: "./a.go", Line: 3, Column: 1, Message: "syntax error: unexpected }\nand a continuation"},
+		{File: "a_test.go", Line: 12, Message: "old-style diagnostic"},
+		{File: "./a.go", Line: 7, Column: 2, Message: "too many errors"},
+	})
+	assert.Equal(t, buildErrors[0].String(), "./a.go:3:1: syntax error: unexpected }\nand a continuation")
+	assert.Equal(t, buildErrors[1].String(), "a_test.go:12: old-style diagnostic")
+}
+
+func TestAddBuildErrors(t *testing.T) {
+	output := "./a.go:3:1: syntax error\nFAIL\tfoo/bar [build failed]\n"
+
+	pkg := &results.TestPackage{Result: results.Failed}
+	addBuildErrors(pkg, output)
+	assert.True(t, pkg.BuildFailed)
+	assert.Equal(t, len(pkg.BuildErrors), 1)
+
+	// setup failures have no diagnostics
+	pkg = &results.TestPackage{Result: results.Failed}
+	addBuildErrors(pkg, "FAIL	foo/bar  [setup failed]\n")
+	assert.True(t, pkg.BuildFailed)
+	assert.Equal(t, len(pkg.BuildErrors), 0)
+
+	// packages that built may log lines resembling diagnostics
+	pkg = &results.TestPackage{Result: results.Failed}
+	addBuildErrors(pkg, "a.go:3: log line\nFAIL\tfoo/bar\t0.1s\n")
+	assert.False(t, pkg.BuildFailed)
+	assert.Equal(t, len(pkg.BuildErrors), 0)
+}
+
+func TestBuildPackageName(t *testing.T) {
+	assert.Equal(t, buildPackageName("foo/bar [foo/bar.test]"), "foo/bar")
+	assert.Equal(t, buildPackageName("foo/bar_test [foo/bar.test]"), "foo/bar")
+	assert.Equal(t, buildPackageName("foo/dep"), "foo/dep")
+}
@@ -19,7 +19,6 @@ package parser
 import (
 	"bytes"
 	"fmt"
-	"io"
 	"regexp"
 	"strconv"
 	"strings"
@@ -30,86 +29,244 @@ import (
 )
 
 var (
-	resultRegex  = regexp.MustCompile(`^--- (PASS|FAIL|SKIP): (.+) \((\d+\.\d+)(?: seconds|s)\)$`)
+	// benchmark results omit the duration
+	resultRegex  = regexp.MustCompile(`^(\s*)--- (PASS|FAIL|SKIP|BENCH): (.+?)(?: \((\d+\.\d+)(?: seconds|s)\))?$`)
 	summaryRegex = regexp.MustCompile(`^(?:PASS|FAIL)$`)
+
+	// matches go test's markers for parallel tests: PAUSE when a
+	// test calls t.Parallel, CONT when it resumes, and (since go
+	// 1.20) NAME when output switches between running tests
+	parallelRegex = regexp.MustCompile(`^=== (PAUSE|CONT|NAME)\s*(.*)$`)
+
+	// matches go test's per-package result lines, e.g.:
+	//   ok  	github.com/foo/bar	0.012s
+	//   FAIL	github.com/foo/baz	1.234s
+	//   ?   	github.com/foo/qux	[no test files]
+	packageRegex = regexp.MustCompile(`^(ok|FAIL|\?)\s+(\S+)\s+(\(cached\)|(\d+\.\d+)s|\[.+\])`)
 )
 
-// Convert verbose go test output into test results suitable for formatting.
-func ParseTestOutput(
-	pkgName string,
-	duration time.Duration,
-	output *bytes.Buffer,
-) ([]*results.TestPackage, error) {
-	eof := false
-	var t *results.Test
-	testPkg := results.TestPackage{
-		Name:     pkgName,
-		Result:   results.Skipped,
-		Duration: duration.Seconds(),
-		Tests:    make([]*results.Test, 0),
-		Output:   string(output.Bytes()),
+const noPackageResult = "\n[Did not find package result: marking package as failed.]\n"
+
+// goPackage tracks a package while its verbose output is parsed.
+type goPackage struct {
+	*testTree
+
+	output   bytes.Buffer
+	duration float64 // from go test's package result line, if any
+
+	// the test receiving unindented output; with parallel tests,
+	// several tests may be running but go test marks each switch in
+	// output between them
+	current *results.Test
+
+	// the most recently completed test and the indentation of its
+	// result line: more deeply indented lines that follow are its
+	// failure (or skip) text
+	completed       *results.Test
+	completedIndent int
+}
+
+func newGoPackage(pkgName string, observe testObserver) *goPackage {
+	return &goPackage{
+		testTree: newTestTree(
+			&results.TestPackage{
+				Name:   pkgName,
+				Result: results.Skipped,
+				Tests:  make([]*results.Test, 0),
+			},
+			observe,
+		),
+		duration: -1,
 	}
+}
+
+// goParser parses verbose go test output a line at a time.
+type goParser struct {
+	pkgName string
+	observe testObserver
+	pkg     *goPackage
+	pkgs    []*goPackage
+}
 
-	for !eof {
-		lineBytes, err := output.ReadBytes('\n')
-		if err != nil {
-			if err == io.EOF {
-				eof = true
-			} else {
-				return nil, err
+func newGoParser(pkgName string, observe testObserver) *goParser {
+	return &goParser{
+		pkgName: pkgName,
+		observe: observe,
+		pkg:     newGoPackage(pkgName, observe),
+	}
+}
+
+func (p *goParser) parseLine(lineBytes []byte) error {
+	pkg := p.pkg
+	testPkg := pkg.pkg
+
+	pkg.output.Write(lineBytes)
+
+	line := strings.TrimRightFunc(string(lineBytes), unicode.IsSpace)
+
+	if pkg.crash != nil && !packageRegex.MatchString(line) {
+		// e.g. a goroutine dump: the test executable is exiting
+		pkg.crash.Write(lineBytes)
+		return nil
+	}
+
+	if kind, ok := crashKind(line); ok {
+		pkg.crashed(kind, string(lineBytes))
+	} else if strings.HasPrefix(line, "=== RUN ") {
+		// start of test
+		pkg.current = pkg.start(strings.TrimSpace(line[8:]))
+		pkg.completed = nil
+	} else if m := parallelRegex.FindStringSubmatch(line); len(m) == 3 {
+		switch {
+		case m[1] == "PAUSE":
+			if t, ok := pkg.tests[m[2]]; ok {
+				pkg.pause(t)
 			}
+			pkg.current = nil
+		case m[2] == "":
+			// package-level output
+			pkg.current = nil
+		case m[1] == "CONT":
+			pkg.current = pkg.start(m[2])
+			pkg.resume(pkg.current)
+		default:
+			// output from a running test
+			pkg.current = pkg.start(m[2])
 		}
+		pkg.completed = nil
+	} else if m := resultRegex.FindStringSubmatch(line); len(m) == 5 {
+		// end of test
+		var result results.TestResult
+		switch m[2] {
+		case "PASS", "BENCH":
+			result = results.Passed
+		case "SKIP":
+			result = results.Skipped
+		default:
+			result = results.Failed
+		}
+		duration, _ := strconv.ParseFloat(m[4], 64)
 
-		line := strings.TrimRightFunc(string(lineBytes), unicode.IsSpace)
-
-		if t == nil {
-			if strings.HasPrefix(line, "=== RUN ") {
-				// start of test
-				t = new(results.Test)
-				t.Name = strings.TrimSpace(line[8:])
-			} else if m := summaryRegex.FindStringSubmatch(line); len(m) == 1 {
-				// End of package
-				if testPkg.Result != results.Skipped {
-					return nil, fmt.Errorf("expected only a single package")
-				}
-				switch line {
-				case "PASS":
-					testPkg.Result = results.Passed
-				default:
-					testPkg.Result = results.Failed
-				}
-			} else if len(testPkg.Tests) > 0 && strings.HasPrefix(line, "\t") {
-				// test failure output
-				lastTest := testPkg.Tests[len(testPkg.Tests)-1]
-				lastTest.Failure.Write(lineBytes)
-			}
-		} else {
-			if m := resultRegex.FindStringSubmatch(line); len(m) == 4 {
-				// end of test
-				switch m[1] {
-				case "PASS":
-					t.Result = results.Passed
-				case "SKIP":
-					t.Result = results.Skipped
-				default:
-					t.Result = results.Failed
-				}
-				t.Duration, _ = strconv.ParseFloat(m[3], 64)
-
-				testPkg.Tests = append(testPkg.Tests, t)
-				t = nil
-			} else {
-				t.Output.Write(lineBytes)
+		t := pkg.start(pkg.benchmarkName(m[3]))
+		pkg.reportResult(t, result)
+		pkg.finish(t, result, duration)
+		pkg.completed = t
+		pkg.completedIndent = len(m[1])
+		if t == pkg.current {
+			pkg.current = nil
+		}
+	} else if m := summaryRegex.FindStringSubmatch(line); len(m) == 1 {
+		// end of package's test output
+		if testPkg.Result != results.Skipped {
+			return fmt.Errorf("expected only a single package")
+		}
+		switch line {
+		case "PASS":
+			testPkg.Result = results.Passed
+		default:
+			testPkg.Result = results.Failed
+		}
+		pkg.current = nil
+		pkg.completed = nil
+	} else if m := packageRegex.FindStringSubmatch(line); len(m) == 5 {
+		// end of package (go test with one or more packages)
+		testPkg.Name = m[2]
+		switch m[1] {
+		case "FAIL":
+			testPkg.Result = results.Failed
+		default:
+			if testPkg.Result == results.Skipped {
+				testPkg.Result = results.Passed
 			}
 		}
+		if m[4] != "" {
+			pkg.duration, _ = strconv.ParseFloat(m[4], 64)
+		}
+
+		p.pkgs = append(p.pkgs, pkg)
+		p.pkg = newGoPackage(p.pkgName, p.observe)
+	} else if b := parseBenchmark(line); b != nil {
+		testPkg.Benchmarks = append(testPkg.Benchmarks, b)
+		if pkg.current != nil {
+			pkg.current.Output.Write(lineBytes)
+		}
+	} else if pkg.completed != nil && indentation(line) > pkg.completedIndent {
+		// test failure output
+		pkg.completed.Failure.Write(lineBytes)
+	} else if pkg.current != nil {
+		pkg.current.Output.Write(lineBytes)
+	}
+
+	return nil
+}
+
+// finish completes parsing, returning a TestPackage for each package
+// found. The given duration is used for packages whose duration was
+// not reported by go test.
+func (p *goParser) finish(duration time.Duration) []*results.TestPackage {
+	pkgs := p.pkgs
+	if len(pkgs) == 0 || len(p.pkg.tests) > 0 {
+		pkgs = append(pkgs, p.pkg)
 	}
+	// Otherwise, any remaining output follows the last package's
+	// result line (e.g. go test's final PASS/FAIL line).
+
+	testPkgs := make([]*results.TestPackage, len(pkgs))
+	for i, pkg := range pkgs {
+		testPkg := pkg.pkg
+
+		if pkg.duration >= 0 {
+			testPkg.Duration = pkg.duration
+		} else {
+			testPkg.Duration = duration.Seconds()
+		}
+
+		if testPkg.Result == results.Skipped {
+			testPkg.Result = results.Failed
+			pkg.output.WriteString(noPackageResult)
+		}
+		testPkg.Output = pkg.output.String()
+		addBuildErrors(testPkg, testPkg.Output)
 
-	if testPkg.Result == results.Skipped {
-		testPkg.Result = results.Failed
-		testPkg.Output += "\n[Did not find package result: marking package as failed.]\n"
+		pkg.complete()
+
+		testPkgs[i] = testPkg
 	}
 
-	return []*results.TestPackage{&testPkg}, nil
+	return testPkgs
+}
+
+// Convert verbose go test output into test results suitable for
+// formatting. Subtest results (which go test indents beneath their
+// parent's result) are attached to their parent test. The output of
+// go test run against multiple packages produces one TestPackage per
+// package, named according to go test's package result lines. The
+// given package name and duration are used when the output does not
+// contain a package's name or duration (e.g. the output of a single
+// test executable).
+func ParseTestOutput(
+	pkgName string,
+	duration time.Duration,
+	output *bytes.Buffer,
+) ([]*results.TestPackage, error) {
+	return parseAll(NewGoStream(pkgName), duration, output)
+}
+
+// NewGoStream returns a Stream that parses verbose go test output as
+// ParseTestOutput does.
+func NewGoStream(pkgName string) *Stream {
+	return newStream(func(observe testObserver) lineParser {
+		return newGoParser(pkgName, observe)
+	})
+}
+
+// indentation returns the number of leading whitespace characters
+// in a line, or 0 if the line is empty.
+func indentation(line string) int {
+	if line == "" {
+		return 0
+	}
+	return len(line) - len(strings.TrimLeftFunc(line, unicode.IsSpace))
 }
 
 // Forces the presence of the go test verbose flag (-test.v=true).
@@ -125,3 +282,19 @@ func ForceVerboseFlag(args []string) []string {
 
 	return append(args, "-test.v=true")
 }
+
+// Forces the presence of the go test verbose flag (-v).
+func ForceGoTestVerboseFlag(args []string) []string {
+	result := make([]string, 0, len(args)+1)
+	for _, arg := range args {
+		switch arg {
+		case "-v", "-v=true", "-test.v", "-test.v=true":
+			return args
+		case "-v=false", "-test.v=false":
+			continue
+		}
+		result = append(result, arg)
+	}
+
+	return append(result, "-v")
+}
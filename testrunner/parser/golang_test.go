@@ -188,6 +188,199 @@ coverage: 57.1% of statements
 
 	NoPackageResultFailure = `=== RUN   TestPatchAgentErr
 --- PASS: TestPatchAgentErr (0.10s)
+`
+
+	Subtests = `=== RUN   TestC
+=== RUN   TestC/sub1
+    c_test.go:20: in sub1
+=== RUN   TestC/sub2
+=== RUN   TestC/sub2/deep
+    c_test.go:22: deep fail
+=== RUN   TestC/skip
+    c_test.go:24: nope
+--- FAIL: TestC (0.03s)
+    --- PASS: TestC/sub1 (0.01s)
+    --- FAIL: TestC/sub2 (0.02s)
+        --- FAIL: TestC/sub2/deep (0.02s)
+    --- SKIP: TestC/skip (0.00s)
+=== RUN   TestD
+--- PASS: TestD (0.00s)
+FAIL
+`
+
+	LegacySubtests = `=== RUN   TestC
+=== RUN   TestC/sub1
+=== RUN   TestC/sub2
+--- FAIL: TestC (0.03s)
+	c_test.go:30: parent fail
+    --- PASS: TestC/sub1 (0.01s)
+    	c_test.go:20: in sub1
+    --- FAIL: TestC/sub2 (0.02s)
+    	c_test.go:22: sub2 fail
+FAIL
+`
+
+	GoTestMultiplePackages = `=== RUN   TestA
+--- PASS: TestA (0.01s)
+PASS
+ok  	github.com/foo/a	0.015s
+=== RUN   TestB
+    b_test.go:16: b failed
+--- FAIL: TestB (0.02s)
+FAIL
+FAIL	github.com/foo/b	0.025s
+?   	github.com/foo/c	[no test files]
+ok  	github.com/foo/d	(cached)
+FAIL
+`
+
+	GoTestBuildFailures = `# github.com/foo/broken [github.com/foo/broken.test]
+./b.go:3:23: cannot use "x" (untyped string constant) as int value in return statement
+./b.go:7:9: not enough return values
+	have ()
+	want (int)
+FAIL	github.com/foo/broken [build failed]
+=== RUN   TestA
+--- PASS: TestA (0.00s)
+PASS
+ok  	github.com/foo/a	0.006s
+# github.com/foo/vetfail
+# [github.com/foo/vetfail]
+../vetfail/v.go:5:39: fmt.Sprintf format %d has arg "x" of wrong type string
+FAIL	github.com/foo/vetfail [build failed]
+FAIL
+`
+
+	GoTestNonVerbose = `--- FAIL: TestB (0.02s)
+    b_test.go:16: b failed
+FAIL
+FAIL	github.com/foo/b	0.025s
+ok  	github.com/foo/a	0.015s	coverage: 57.1% of statements
+FAIL
+`
+
+	ParallelTests = `=== RUN   TestP
+=== RUN   TestP/x
+=== PAUSE TestP/x
+=== RUN   TestP/y
+=== PAUSE TestP/y
+=== CONT  TestP/x
+    p_test.go:13: start x
+=== CONT  TestP/y
+    p_test.go:13: start y
+    p_test.go:17: end y
+    p_test.go:19: y failed
+=== NAME  TestP/x
+    p_test.go:17: end x
+--- FAIL: TestP (0.00s)
+    --- FAIL: TestP/y (0.00s)
+    --- PASS: TestP/x (0.01s)
+=== RUN   TestQ
+=== PAUSE TestQ
+=== RUN   TestR
+=== PAUSE TestR
+=== CONT  TestQ
+    p_test.go:27: q1
+=== CONT  TestR
+    p_test.go:35: r
+--- PASS: TestR (0.00s)
+=== NAME  TestQ
+    p_test.go:29: q2
+=== NAME
+package output
+--- PASS: TestQ (0.01s)
+FAIL
+`
+
+	Benchmarks = `=== RUN   TestA
+--- PASS: TestA (0.01s)
+goos: linux
+goarch: amd64
+pkg: foo/bar/baz
+=== RUN   BenchmarkX
+BenchmarkX
+BenchmarkX-8   	     100	         3.940 ns/op	       0 B/op	       0 allocs/op
+BenchmarkX-8   	     100	         3.520 ns/op	       0 B/op	       0 allocs/op
+=== RUN   BenchmarkY
+BenchmarkY
+BenchmarkY-8   	     100	        10.000 ns/op
+--- BENCH: BenchmarkY-8
+    y_test.go:12: logged
+=== RUN   BenchmarkZ
+BenchmarkZ
+--- FAIL: BenchmarkZ-8
+    z_test.go:20: failed
+PASS
+`
+
+	// test executable sent SIGQUIT while TestHang/sub was running
+	QuitDump = `=== RUN   TestOK
+    hang_test.go:8: fine
+--- PASS: TestOK (0.00s)
+=== RUN   TestHang
+=== RUN   TestHang/sub
+    hang_test.go:12: about to hang
+SIGQUIT: quit
+PC=0x40ee0e m=0 sigcode=0
+
+goroutine 8 gp=0x36eeb9d754a0 m=nil [sleep]:
+time.Sleep(0x34630b8a000)
+	/usr/local/go/src/runtime/time.go:368 +0x165 fp=0x36eeb9dd0f30 sp=0x36eeb9dd0ed8 pc=0x489b45
+example.com/hang.TestHang.func1(0x36eeb9e046c8?)
+	/tmp/hang/hang_test.go:13 +0x48 fp=0x36eeb9dd0f70 sp=0x36eeb9dd0f30 pc=0x543528
+created by testing.(*T).Run in goroutine 7
+	/usr/local/go/src/testing/testing.go:2258 +0x4d4
+
+rax    0xfffffffffffffffc
+rip    0x40ee0e
+`
+
+	// TestPanic/sub panics; go test reports it and its parent as
+	// failed before printing the panic
+	Panic = `=== RUN   TestOK
+--- PASS: TestOK (0.00s)
+=== RUN   TestPanic
+=== RUN   TestPanic/sub
+    pan_test.go:12: before
+--- FAIL: TestPanic (0.00s)
+    --- FAIL: TestPanic/sub (0.00s)
+panic: boom [recovered, repanicked]
+
+goroutine 8 [running]:
+example.com/pan.TestPanic.func1(0xbde77c88488?)
+	/tmp/pan/pan_test.go:13 +0x4c
+`
+
+	// foo/a crashes, foo/b times out (via -test.timeout), foo/c
+	// panics in a goroutine started by TestC
+	GoTestCrashes = `=== RUN   TestA
+fatal error: concurrent map writes
+
+goroutine 10 [running]:
+example.com/pan.TestA.func1()
+	/tmp/pan/a_test.go:27 +0x2d
+exit status 2
+FAIL	foo/a	0.012s
+=== RUN   TestB
+panic: test timed out after 300ms
+	running tests:
+		TestB (0s)
+
+goroutine 8 [running]:
+testing.(*M).startAlarm.func1()
+	/usr/local/go/src/testing/testing.go:2959 +0x34a
+exit status 2
+FAIL	foo/b	0.304s
+=== RUN   TestOK
+--- PASS: TestOK (0.00s)
+=== RUN   TestC
+panic: async boom
+
+goroutine 7 [running]:
+example.com/pan.TestC.func1()
+	/tmp/pan/c_test.go:18 +0x25
+exit status 2
+FAIL	foo/c	0.006s
 `
 
 	MultiplePackageFailure = `=== RUN   TestPatchAgentErr1
@@ -376,6 +569,381 @@ func TestParseOutputOnMultiplePackages(t *testing.T) {
 
 }
 
+func TestParseOutputOnSubtests(t *testing.T) {
+	duration := 11 * time.Second
+	pkgs, err := ParseTestOutput(testPackageName, duration, bytes.NewBuffer([]byte(Subtests)))
+	assert.Nil(t, err)
+	assert.Equal(t, len(pkgs), 1)
+
+	pkg := pkgs[0]
+	assert.Equal(t, pkg.Result, results.Failed)
+	assert.Equal(t, len(pkg.Tests), 2)
+	assert.Equal(t, len(pkg.AllTests()), 6)
+
+	testC := pkg.Tests[0]
+	assert.Equal(t, testC.Name, "TestC")
+	assert.Equal(t, testC.Result, results.Failed)
+	assert.Equal(t, testC.Duration, 0.03)
+	assert.Equal(t, testC.Output.String(), "")
+	assert.Equal(t, len(testC.Subtests), 3)
+
+	sub1 := testC.Subtests[0]
+	assert.Equal(t, sub1.Name, "TestC/sub1")
+	assert.Equal(t, sub1.Result, results.Passed)
+	assert.Equal(t, sub1.Duration, 0.01)
+	assert.Equal(t, sub1.Output.String(), "    c_test.go:20: in sub1\n")
+	assert.Equal(t, len(sub1.Subtests), 0)
+
+	sub2 := testC.Subtests[1]
+	assert.Equal(t, sub2.Name, "TestC/sub2")
+	assert.Equal(t, sub2.Result, results.Failed)
+	assert.Equal(t, len(sub2.Subtests), 1)
+
+	deep := sub2.Subtests[0]
+	assert.Equal(t, deep.Name, "TestC/sub2/deep")
+	assert.Equal(t, deep.Result, results.Failed)
+	assert.Equal(t, deep.Output.String(), "    c_test.go:22: deep fail\n")
+	assert.Equal(t, deep.Failure.String(), "    c_test.go:22: deep fail\n")
+
+	skip := testC.Subtests[2]
+	assert.Equal(t, skip.Name, "TestC/skip")
+	assert.Equal(t, skip.Result, results.Skipped)
+	assert.Equal(t, skip.Output.String(), "    c_test.go:24: nope\n")
+
+	testD := pkg.Tests[1]
+	assert.Equal(t, testD.Name, "TestD")
+	assert.Equal(t, testD.Result, results.Passed)
+}
+
+func TestParseOutputOnLegacySubtests(t *testing.T) {
+	duration := 11 * time.Second
+	pkgs, err := ParseTestOutput(
+		testPackageName,
+		duration,
+		bytes.NewBuffer([]byte(LegacySubtests)),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, len(pkgs), 1)
+
+	pkg := pkgs[0]
+	assert.Equal(t, len(pkg.Tests), 1)
+
+	testC := pkg.Tests[0]
+	assert.Equal(t, testC.Result, results.Failed)
+	assert.Equal(t, testC.Failure.String(), "\tc_test.go:30: parent fail\n")
+	assert.Equal(t, len(testC.Subtests), 2)
+
+	sub1 := testC.Subtests[0]
+	assert.Equal(t, sub1.Result, results.Passed)
+	assert.Equal(t, sub1.Failure.String(), "    \tc_test.go:20: in sub1\n")
+
+	sub2 := testC.Subtests[1]
+	assert.Equal(t, sub2.Result, results.Failed)
+	assert.Equal(t, sub2.Failure.String(), "    \tc_test.go:22: sub2 fail\n")
+}
+
+func TestParseOutputOnParallelTests(t *testing.T) {
+	duration := 11 * time.Second
+	pkgs, err := ParseTestOutput(
+		testPackageName,
+		duration,
+		bytes.NewBuffer([]byte(ParallelTests)),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, len(pkgs), 1)
+
+	pkg := pkgs[0]
+	assert.Equal(t, pkg.Result, results.Failed)
+	assert.Equal(t, len(pkg.Tests), 3)
+
+	testP := pkg.Tests[0]
+	assert.Equal(t, testP.Name, "TestP")
+	assert.Equal(t, testP.Result, results.Failed)
+	assert.Equal(t, testP.Output.String(), "")
+	assert.Equal(t, len(testP.Subtests), 2)
+
+	y := testP.Subtests[0]
+	assert.Equal(t, y.Name, "TestP/y")
+	assert.Equal(t, y.Result, results.Failed)
+	assert.Equal(
+		t,
+		y.Output.String(),
+		"    p_test.go:13: start y\n    p_test.go:17: end y\n    p_test.go:19: y failed\n",
+	)
+	assert.Equal(t, y.Failure.String(), y.Output.String())
+
+	x := testP.Subtests[1]
+	assert.Equal(t, x.Name, "TestP/x")
+	assert.Equal(t, x.Result, results.Passed)
+	assert.Equal(t, x.Duration, 0.01)
+	assert.Equal(t, x.Output.String(), "    p_test.go:13: start x\n    p_test.go:17: end x\n")
+	assert.Equal(t, x.Failure.String(), "")
+
+	testR := pkg.Tests[1]
+	assert.Equal(t, testR.Name, "TestR")
+	assert.Equal(t, testR.Result, results.Passed)
+	assert.Equal(t, testR.Output.String(), "    p_test.go:35: r\n")
+
+	testQ := pkg.Tests[2]
+	assert.Equal(t, testQ.Name, "TestQ")
+	assert.Equal(t, testQ.Result, results.Passed)
+	assert.Equal(t, testQ.Output.String(), "    p_test.go:27: q1\n    p_test.go:29: q2\n")
+}
+
+func TestParseOutputOnQuitDump(t *testing.T) {
+	pkgs, err := ParseTestOutput(
+		testPackageName,
+		time.Second,
+		bytes.NewBuffer([]byte(QuitDump)),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, len(pkgs), 1)
+
+	pkg := pkgs[0]
+	assert.Equal(t, pkg.Result, results.Failed)
+	assert.Equal(t, len(pkg.Tests), 2)
+
+	testOK := pkg.Tests[0]
+	assert.Equal(t, testOK.Result, results.Passed)
+	assert.Equal(t, testOK.FailureKind, results.TestFailure)
+
+	testHang := pkg.Tests[1]
+	assert.Equal(t, testHang.Name, "TestHang")
+	assert.Equal(t, testHang.Result, results.Failed)
+	assert.Equal(t, testHang.FailureKind, results.Timeout)
+	assert.Equal(t, len(testHang.Subtests), 1)
+
+	sub := testHang.Subtests[0]
+	assert.Equal(t, sub.Name, "TestHang/sub")
+	assert.Equal(t, sub.Result, results.Failed)
+	assert.Equal(t, sub.FailureKind, results.Timeout)
+	assert.Equal(t, sub.Output.String(), "    hang_test.go:12: about to hang\n")
+	assert.Equal(t, sub.Failure.String(), QuitDump[strings.Index(QuitDump, "SIGQUIT"):])
+}
+
+func TestParseOutputOnPanic(t *testing.T) {
+	pkgs, err := ParseTestOutput(
+		testPackageName,
+		time.Second,
+		bytes.NewBuffer([]byte(Panic)),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, len(pkgs), 1)
+
+	pkg := pkgs[0]
+	assert.Equal(t, pkg.Result, results.Failed)
+	assert.Equal(t, len(pkg.Tests), 2)
+	assert.Equal(t, pkg.Tests[0].Result, results.Passed)
+
+	testPanic := pkg.Tests[1]
+	assert.Equal(t, testPanic.Result, results.Failed)
+	assert.Equal(t, testPanic.FailureKind, results.TestFailure)
+	assert.Equal(t, testPanic.Failure.String(), "")
+	assert.Equal(t, len(testPanic.Subtests), 1)
+
+	sub := testPanic.Subtests[0]
+	assert.Equal(t, sub.Name, "TestPanic/sub")
+	assert.Equal(t, sub.Result, results.Failed)
+	assert.Equal(t, sub.FailureKind, results.Panic)
+	assert.Equal(t, sub.Output.String(), "    pan_test.go:12: before\n")
+	assert.Equal(t, sub.Failure.String(), Panic[strings.Index(Panic, "panic:"):])
+}
+
+func TestParseOutputOnGoTestCrashes(t *testing.T) {
+	pkgs, err := ParseTestOutput(
+		testPackageName,
+		time.Second,
+		bytes.NewBuffer([]byte(GoTestCrashes)),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, len(pkgs), 3)
+
+	for i, kind := range []results.FailureKind{results.Panic, results.Timeout, results.Panic} {
+		pkg := pkgs[i]
+		assert.Equal(t, pkg.Result, results.Failed)
+
+		test := pkg.Tests[len(pkg.Tests)-1]
+		assert.Equal(t, test.Result, results.Failed)
+		assert.Equal(t, test.FailureKind, kind)
+		assert.MatchesRegex(t, test.Failure.String(), "^(panic|fatal error): ")
+		assert.StringContains(t, test.Failure.String(), "exit status 2\n")
+	}
+
+	assert.Equal(t, pkgs[0].Name, "foo/a")
+	assert.Equal(t, pkgs[1].Name, "foo/b")
+	assert.Equal(t, pkgs[2].Name, "foo/c")
+
+	assert.Equal(t, len(pkgs[2].Tests), 2)
+	assert.Equal(t, pkgs[2].Tests[0].Name, "TestOK")
+	assert.Equal(t, pkgs[2].Tests[0].Result, results.Passed)
+	assert.Equal(t, pkgs[2].Tests[1].Name, "TestC")
+}
+
+func TestParseOutputOnBenchmarks(t *testing.T) {
+	duration := 11 * time.Second
+	pkgs, err := ParseTestOutput(
+		testPackageName,
+		duration,
+		bytes.NewBuffer([]byte(Benchmarks)),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, len(pkgs), 1)
+
+	pkg := pkgs[0]
+	assert.Equal(t, pkg.Result, results.Passed)
+	assert.Equal(t, len(pkg.Tests), 4)
+
+	names := make([]string, len(pkg.Tests))
+	for i, test := range pkg.Tests {
+		names[i] = test.Name
+	}
+	assert.ArrayEqual(t, names, []string{"TestA", "BenchmarkY", "BenchmarkZ", "BenchmarkX"})
+
+	benchY := pkg.Tests[1]
+	assert.Equal(t, benchY.Result, results.Passed)
+	assert.Equal(t, benchY.Failure.String(), "    y_test.go:12: logged\n")
+
+	benchZ := pkg.Tests[2]
+	assert.Equal(t, benchZ.Result, results.Failed)
+	assert.Equal(t, benchZ.Failure.String(), "    z_test.go:20: failed\n")
+
+	benchX := pkg.Tests[3]
+	assert.Equal(t, benchX.Result, results.Passed)
+
+	assert.Equal(t, len(pkg.Benchmarks), 3)
+	assert.Equal(t, pkg.Benchmarks[0].FullName(), "BenchmarkX-8")
+	assert.Equal(t, pkg.Benchmarks[0].NsPerOp, 3.94)
+	assert.True(t, pkg.Benchmarks[0].HasMemStats)
+	assert.Equal(t, pkg.Benchmarks[1].FullName(), "BenchmarkX-8")
+	assert.Equal(t, pkg.Benchmarks[1].NsPerOp, 3.52)
+	assert.Equal(t, pkg.Benchmarks[2].FullName(), "BenchmarkY-8")
+	assert.Equal(t, pkg.Benchmarks[2].NsPerOp, 10.0)
+	assert.False(t, pkg.Benchmarks[2].HasMemStats)
+}
+
+func TestParseOutputOnGoTestMultiplePackages(t *testing.T) {
+	duration := 11 * time.Second
+	pkgs, err := ParseTestOutput(
+		testPackageName,
+		duration,
+		bytes.NewBuffer([]byte(GoTestMultiplePackages)),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, len(pkgs), 4)
+
+	a := pkgs[0]
+	assert.Equal(t, a.Name, "github.com/foo/a")
+	assert.Equal(t, a.Result, results.Passed)
+	assert.Equal(t, a.Duration, 0.015)
+	assert.Equal(t, len(a.Tests), 1)
+	assert.Equal(t, a.Tests[0].Name, "TestA")
+	assert.Equal(t, a.Output, "=== RUN   TestA\n--- PASS: TestA (0.01s)\nPASS\nok  \tgithub.com/foo/a\t0.015s\n")
+
+	b := pkgs[1]
+	assert.Equal(t, b.Name, "github.com/foo/b")
+	assert.Equal(t, b.Result, results.Failed)
+	assert.Equal(t, b.Duration, 0.025)
+	assert.Equal(t, len(b.Tests), 1)
+	assert.Equal(t, b.Tests[0].Name, "TestB")
+	assert.Equal(t, b.Tests[0].Result, results.Failed)
+	assert.Equal(t, b.Tests[0].Failure.String(), "    b_test.go:16: b failed\n")
+
+	c := pkgs[2]
+	assert.Equal(t, c.Name, "github.com/foo/c")
+	assert.Equal(t, c.Result, results.Passed)
+	assert.Equal(t, c.Duration, 11.0)
+	assert.Equal(t, len(c.Tests), 0)
+
+	d := pkgs[3]
+	assert.Equal(t, d.Name, "github.com/foo/d")
+	assert.Equal(t, d.Result, results.Passed)
+	assert.Equal(t, len(d.Tests), 0)
+}
+
+func TestParseOutputOnGoTestBuildFailures(t *testing.T) {
+	pkgs, err := ParseTestOutput(
+		testPackageName,
+		time.Second,
+		bytes.NewBuffer([]byte(GoTestBuildFailures)),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, len(pkgs), 3)
+
+	broken := pkgs[0]
+	assert.Equal(t, broken.Name, "github.com/foo/broken")
+	assert.Equal(t, broken.Result, results.Failed)
+	assert.True(t, broken.BuildFailed)
+	assert.Equal(t, len(broken.Tests), 0)
+	assert.DeepEqual(t, broken.BuildErrors, []*results.BuildError{
+		{
+			File:    "./b.go",
+			Line:    3,
+			Column:  23,
+			Message: `cannot use "x" (untyped string constant) as int value in return statement`,
+		},
+		{
+			File:    "./b.go",
+			Line:    7,
+			Column:  9,
+			Message: "not enough return values\nhave ()\nwant (int)",
+		},
+	})
+	assert.StringDoesNotContain(t, broken.Output, "Did not find package result")
+
+	a := pkgs[1]
+	assert.Equal(t, a.Result, results.Passed)
+	assert.False(t, a.BuildFailed)
+	assert.Equal(t, len(a.BuildErrors), 0)
+
+	vetfail := pkgs[2]
+	assert.Equal(t, vetfail.Name, "github.com/foo/vetfail")
+	assert.Equal(t, vetfail.Result, results.Failed)
+	assert.DeepEqual(t, vetfail.BuildErrors, []*results.BuildError{
+		{
+			File:    "../vetfail/v.go",
+			Line:    5,
+			Column:  39,
+			Message: `fmt.Sprintf format %d has arg "x" of wrong type string`,
+		},
+	})
+}
+
+func TestParseOutputOnGoTestNonVerbose(t *testing.T) {
+	duration := 11 * time.Second
+	pkgs, err := ParseTestOutput(
+		testPackageName,
+		duration,
+		bytes.NewBuffer([]byte(GoTestNonVerbose)),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, len(pkgs), 2)
+
+	b := pkgs[0]
+	assert.Equal(t, b.Name, "github.com/foo/b")
+	assert.Equal(t, b.Result, results.Failed)
+	assert.Equal(t, len(b.Tests), 1)
+	assert.Equal(t, b.Tests[0].Result, results.Failed)
+	assert.Equal(t, b.Tests[0].Failure.String(), "    b_test.go:16: b failed\n")
+
+	a := pkgs[1]
+	assert.Equal(t, a.Name, "github.com/foo/a")
+	assert.Equal(t, a.Result, results.Passed)
+	assert.Equal(t, a.Duration, 0.015)
+	assert.Equal(t, len(a.Tests), 0)
+}
+
+func TestForceGoTestVerboseFlag(t *testing.T) {
+	result := ForceGoTestVerboseFlag([]string{"test", "./..."})
+	assert.DeepEqual(t, result, []string{"test", "./...", "-v"})
+
+	verboseArgs := []string{"test", "-v", "./..."}
+	result = ForceGoTestVerboseFlag(verboseArgs)
+	assert.DeepEqual(t, result, verboseArgs)
+
+	result = ForceGoTestVerboseFlag([]string{"test", "-v=false", "./..."})
+	assert.DeepEqual(t, result, []string{"test", "./...", "-v"})
+}
+
 func TestForceVerboseFlag(t *testing.T) {
 	nonVerboseArgs := []string{"-test.timeout=4s"}
 	result := ForceVerboseFlag(nonVerboseArgs)
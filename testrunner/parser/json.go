@@ -0,0 +1,317 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+var (
+	frameRegex       = regexp.MustCompile(`^=== (?:RUN|PAUSE|CONT|NAME)\s`)
+	frameResultRegex = regexp.MustCompile(`^\s*--- (PASS|FAIL|SKIP|BENCH): `)
+)
+
+// testEvent is a single event in the stream produced by test2json.
+// See "go doc cmd/test2json" for details.
+type testEvent struct {
+	Time    time.Time
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+	Output  string
+
+	// Build actions ("build-output" and "build-fail") name the
+	// package being built by import path, and a package that did
+	// not build names the import path that failed.
+	ImportPath  string
+	FailedBuild string
+}
+
+// jsonPackage tracks a package while its events are processed.
+type jsonPackage struct {
+	*testTree
+
+	buildOutput bytes.Buffer
+	output      bytes.Buffer
+	elapsed     float64
+}
+
+func (p *jsonPackage) addOutput(t *results.Test, text string) {
+	p.output.WriteString(text)
+
+	line := strings.TrimRightFunc(text, isNewline)
+	if p.crash != nil {
+		// e.g. a goroutine dump: the test executable is exiting
+		if !packageRegex.MatchString(line) {
+			p.crash.WriteString(text)
+		}
+		return
+	} else if kind, ok := crashKind(line); ok {
+		p.crashed(kind, text)
+		return
+	}
+
+	if b := parseBenchmark(text); b != nil {
+		p.pkg.Benchmarks = append(p.pkg.Benchmarks, b)
+	}
+
+	if t == nil {
+		return
+	}
+
+	switch {
+	case frameRegex.MatchString(line):
+		// framing only
+	case frameResultRegex.MatchString(line):
+		result := results.Passed
+		switch frameResultRegex.FindStringSubmatch(line)[1] {
+		case "FAIL":
+			result = results.Failed
+		case "SKIP":
+			result = results.Skipped
+		}
+		p.reportResult(t, result)
+	case p.reported[t]:
+		// subsequent output is failure (or skip) text
+		t.Failure.WriteString(text)
+	default:
+		t.Output.WriteString(text)
+	}
+}
+
+func isNewline(r rune) bool {
+	return r == '\n' || r == '\r'
+}
+
+// jsonParser parses a test2json event stream a line at a time.
+type jsonParser struct {
+	pkgName string
+	observe testObserver
+	pkgs    map[string]*jsonPackage
+	order   []*jsonPackage
+
+	// build output, by import path, until claimed by a package
+	// that failed to build
+	buildOutput map[string]*bytes.Buffer
+	buildOrder  []string
+
+	last *jsonPackage
+}
+
+func newJSONParser(pkgName string, observe testObserver) *jsonParser {
+	return &jsonParser{
+		pkgName:     pkgName,
+		observe:     observe,
+		pkgs:        map[string]*jsonPackage{},
+		buildOutput: map[string]*bytes.Buffer{},
+	}
+}
+
+func (jp *jsonParser) getPkg(name string) *jsonPackage {
+	if name == "" {
+		name = jp.pkgName
+	}
+	if p, ok := jp.pkgs[name]; ok {
+		return p
+	}
+
+	p := &jsonPackage{
+		testTree: newTestTree(
+			&results.TestPackage{
+				Name:   name,
+				Result: results.Skipped,
+				Tests:  make([]*results.Test, 0),
+			},
+			jp.observe,
+		),
+	}
+	jp.pkgs[name] = p
+	jp.order = append(jp.order, p)
+	return p
+}
+
+func (jp *jsonParser) parseLine(lineBytes []byte) error {
+	if len(bytes.TrimSpace(lineBytes)) == 0 {
+		return nil
+	}
+
+	var event testEvent
+	if lineBytes[0] != '{' || json.Unmarshal(lineBytes, &event) != nil {
+		// not an event: e.g. output from the go tool itself
+		if jp.last == nil {
+			jp.last = jp.getPkg(jp.pkgName)
+		}
+		jp.last.output.Write(lineBytes)
+		return nil
+	}
+
+	if event.ImportPath != "" {
+		if event.Action == "build-output" {
+			b, ok := jp.buildOutput[event.ImportPath]
+			if !ok {
+				b = &bytes.Buffer{}
+				jp.buildOutput[event.ImportPath] = b
+				jp.buildOrder = append(jp.buildOrder, event.ImportPath)
+			}
+			b.WriteString(event.Output)
+		}
+		return nil
+	}
+
+	p := jp.getPkg(event.Package)
+	jp.last = p
+
+	if event.Test == "" {
+		switch event.Action {
+		case "output":
+			p.addOutput(nil, event.Output)
+		case "pass":
+			p.pkg.Result = results.Passed
+			p.elapsed = event.Elapsed
+		case "skip":
+			// no test files: consider this a pass
+			p.pkg.Result = results.Passed
+			p.elapsed = event.Elapsed
+		case "fail":
+			p.pkg.Result = results.Failed
+			p.elapsed = event.Elapsed
+			if b, ok := jp.buildOutput[event.FailedBuild]; ok {
+				p.buildOutput.Write(b.Bytes())
+				delete(jp.buildOutput, event.FailedBuild)
+			}
+		}
+		return nil
+	}
+
+	t := p.start(p.benchmarkName(event.Test))
+	switch event.Action {
+	case "output":
+		p.addOutput(t, event.Output)
+	case "pause":
+		p.pause(t)
+	case "cont":
+		p.resume(t)
+	case "pass", "bench":
+		p.finish(t, results.Passed, event.Elapsed)
+	case "fail":
+		p.finish(t, results.Failed, event.Elapsed)
+	case "skip":
+		p.finish(t, results.Skipped, event.Elapsed)
+	}
+	return nil
+}
+
+// finish completes parsing, returning a TestPackage for each package
+// in the event stream. The given duration is used if there is only
+// one.
+func (jp *jsonParser) finish(duration time.Duration) []*results.TestPackage {
+	for _, importPath := range jp.buildOrder {
+		if b, ok := jp.buildOutput[importPath]; ok {
+			jp.getPkg(buildPackageName(importPath)).buildOutput.Write(b.Bytes())
+		}
+	}
+
+	if len(jp.order) == 0 {
+		jp.getPkg(jp.pkgName)
+	}
+
+	testPkgs := make([]*results.TestPackage, len(jp.order))
+	for i, p := range jp.order {
+		testPkg := p.pkg
+
+		if len(jp.order) == 1 {
+			testPkg.Duration = duration.Seconds()
+		} else {
+			testPkg.Duration = p.elapsed
+		}
+
+		if testPkg.Result == results.Skipped {
+			testPkg.Result = results.Failed
+			p.output.WriteString(noPackageResult)
+		}
+		testPkg.Output = p.buildOutput.String() + p.output.String()
+		addBuildErrors(testPkg, testPkg.Output)
+
+		p.complete()
+
+		testPkgs[i] = testPkg
+	}
+
+	return testPkgs
+}
+
+// ParseJSONOutput converts the test2json event stream produced by
+// "go tool test2json" or "go test -json" into test results suitable
+// for formatting. Events are attributed to packages and tests by
+// name, so interleaved output from parallel tests, subtests, and
+// benchmarks is reported accurately. Lines that are not JSON events
+// are treated as output of the most recently seen package. One
+// TestPackage is returned per package in the event stream. If the
+// stream names no packages, pkgName is used.
+func ParseJSONOutput(
+	pkgName string,
+	duration time.Duration,
+	output *bytes.Buffer,
+) ([]*results.TestPackage, error) {
+	return parseAll(NewJSONStream(pkgName), duration, output)
+}
+
+// NewJSONStream returns a Stream that parses a test2json event stream
+// as ParseJSONOutput does.
+func NewJSONStream(pkgName string) *Stream {
+	return newStream(func(observe testObserver) lineParser {
+		return newJSONParser(pkgName, observe)
+	})
+}
+
+// Forces the test executable to produce the output expected by
+// test2json (-test.v=test2json).
+func ForceTest2JSONFlag(args []string) []string {
+	result := make([]string, 0, len(args)+1)
+	for _, arg := range args {
+		if arg == "-test.v" || strings.HasPrefix(arg, "-test.v=") {
+			continue
+		}
+		result = append(result, arg)
+	}
+
+	return append(result, "-test.v=test2json")
+}
+
+// Forces the presence of the go test JSON flag (-json).
+func ForceGoTestJSONFlag(args []string) []string {
+	for _, arg := range args {
+		if arg == "-json" || arg == "-json=true" {
+			return args
+		}
+	}
+
+	return append(args, "-json")
+}
+
+// Test2JSONFilter returns a "go tool test2json" command that converts
+// the test executable's output to a JSON event stream.
+func Test2JSONFilter(pkgName string) (string, []string) {
+	return "go", []string{"tool", "test2json", "-t", "-p", pkgName}
+}
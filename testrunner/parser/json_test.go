@@ -0,0 +1,408 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package parser
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/turbinelabs/test/assert"
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+const (
+	JSONParallelFailure = `{"Action":"start","Package":"foo/bar/baz"}
+{"Action":"run","Package":"foo/bar/baz","Test":"TestA"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestA","Output":"=== RUN   TestA\n"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestA","Output":"=== PAUSE TestA\n"}
+{"Action":"pause","Package":"foo/bar/baz","Test":"TestA"}
+{"Action":"run","Package":"foo/bar/baz","Test":"TestB"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestB","Output":"=== RUN   TestB\n"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestB","Output":"=== PAUSE TestB\n"}
+{"Action":"pause","Package":"foo/bar/baz","Test":"TestB"}
+{"Action":"cont","Package":"foo/bar/baz","Test":"TestA"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestA","Output":"=== CONT  TestA\n"}
+{"Action":"cont","Package":"foo/bar/baz","Test":"TestB"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestB","Output":"=== CONT  TestB\n"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestB","Output":"    b_test.go:16: b failed\n"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestA","Output":"    a_test.go:11: a log\n"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestB","Output":"--- FAIL: TestB (0.00s)\n"}
+{"Action":"fail","Package":"foo/bar/baz","Test":"TestB","Elapsed":0}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestA","Output":"--- PASS: TestA (0.02s)\n"}
+{"Action":"pass","Package":"foo/bar/baz","Test":"TestA","Elapsed":0.02}
+{"Action":"run","Package":"foo/bar/baz","Test":"TestC"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestC","Output":"=== RUN   TestC\n"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestC","Output":"--- SKIP: TestC (0.00s)\n"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestC","Output":"\tc_test.go:3: reason\n"}
+{"Action":"skip","Package":"foo/bar/baz","Test":"TestC","Elapsed":0}
+{"Action":"output","Package":"foo/bar/baz","Output":"FAIL\n"}
+{"Action":"fail","Package":"foo/bar/baz","Elapsed":0.022}
+`
+
+	JSONBuildFailures = `{"ImportPath":"foo/broken [foo/broken.test]","Action":"build-output","Output":"# foo/broken [foo/broken.test]\n"}
+{"ImportPath":"foo/broken [foo/broken.test]","Action":"build-output","Output":"./b.go:3:23: cannot use \"x\" (untyped string constant) as int value in return statement\n"}
+{"ImportPath":"foo/broken [foo/broken.test]","Action":"build-fail"}
+{"ImportPath":"foo/vetfail [foo/vetfail.test]","Action":"build-output","Output":"# foo/vetfail\n"}
+{"ImportPath":"foo/vetfail [foo/vetfail.test]","Action":"build-output","Output":"# [foo/vetfail]\n"}
+{"ImportPath":"foo/vetfail [foo/vetfail.test]","Action":"build-output","Output":"./v.go:5:39: fmt.Sprintf format %d has arg \"x\" of wrong type string\n"}
+{"ImportPath":"foo/vetfail [foo/vetfail.test]","Action":"build-fail"}
+{"Time":"2026-10-16T10:19:39.448127269Z","Action":"start","Package":"foo/broken"}
+{"Time":"2026-10-16T10:19:39.448268589Z","Action":"output","Package":"foo/broken","Output":"FAIL\tfoo/broken [build failed]\n","OutputType":"frame"}
+{"Time":"2026-10-16T10:19:39.448287414Z","Action":"fail","Package":"foo/broken","Elapsed":0,"FailedBuild":"foo/broken [foo/broken.test]"}
+{"Time":"2026-10-16T10:19:39.5615182Z","Action":"start","Package":"foo/a"}
+{"Time":"2026-10-16T10:19:39.563282379Z","Action":"run","Package":"foo/a","Test":"TestA"}
+{"Time":"2026-10-16T10:19:39.563612723Z","Action":"pass","Package":"foo/a","Test":"TestA","Elapsed":0}
+{"Time":"2026-10-16T10:19:39.563876402Z","Action":"pass","Package":"foo/a","Elapsed":0.002}
+{"Time":"2026-10-16T10:19:38.832910113Z","Action":"start","Package":"foo/vetfail"}
+{"Time":"2026-10-16T10:19:38.833012187Z","Action":"output","Package":"foo/vetfail","Output":"FAIL\tfoo/vetfail [build failed]\n","OutputType":"frame"}
+{"Time":"2026-10-16T10:19:38.833023094Z","Action":"fail","Package":"foo/vetfail","Elapsed":0,"FailedBuild":"foo/vetfail [foo/vetfail.test]"}
+`
+
+	JSONSubtests = `{"Action":"run","Package":"foo/bar/baz","Test":"TestC"}
+{"Action":"run","Package":"foo/bar/baz","Test":"TestC/sub1"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestC/sub1","Output":"    c_test.go:20: in sub1\n"}
+{"Action":"pass","Package":"foo/bar/baz","Test":"TestC/sub1","Elapsed":0.01}
+{"Action":"run","Package":"foo/bar/baz","Test":"TestC/sub2"}
+{"Action":"run","Package":"foo/bar/baz","Test":"TestC/sub2/deep"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestC/sub2/deep","Output":"    c_test.go:22: deep fail\n"}
+{"Action":"fail","Package":"foo/bar/baz","Test":"TestC/sub2/deep","Elapsed":0.02}
+{"Action":"fail","Package":"foo/bar/baz","Test":"TestC/sub2","Elapsed":0.02}
+{"Action":"fail","Package":"foo/bar/baz","Test":"TestC","Elapsed":0.03}
+{"Action":"fail","Package":"foo/bar/baz","Elapsed":0.04}
+`
+
+	JSONBenchmark = `{"Action":"start","Package":"foo/bar/baz"}
+{"Action":"run","Package":"foo/bar/baz","Test":"BenchmarkX"}
+{"Action":"output","Package":"foo/bar/baz","Test":"BenchmarkX","Output":"=== RUN   BenchmarkX\n"}
+{"Action":"output","Package":"foo/bar/baz","Test":"BenchmarkX","Output":"BenchmarkX\n"}
+{"Action":"output","Package":"foo/bar/baz","Test":"BenchmarkX","Output":"BenchmarkX \t     100\t         3.680 ns/op\n"}
+{"Action":"output","Package":"foo/bar/baz","Output":"PASS\n"}
+{"Action":"pass","Package":"foo/bar/baz"}
+`
+
+	JSONMultiplePackages = `{"Action":"run","Package":"a","Test":"TestA"}
+{"Action":"pass","Package":"a","Test":"TestA","Elapsed":0.5}
+{"Action":"pass","Package":"a","Elapsed":1.5}
+go: some diagnostic
+{"Action":"run","Package":"b","Test":"TestB"}
+{"Action":"fail","Package":"b","Test":"TestB","Elapsed":0.25}
+{"Action":"fail","Package":"b","Elapsed":2.5}
+`
+
+	JSONNoPackageResult = `{"Action":"run","Package":"foo/bar/baz","Test":"TestA"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestA","Output":"=== RUN   TestA\n"}
+`
+
+	// test executable sent SIGQUIT while TestHang/sub was running
+	JSONQuitDump = `{"Action":"start","Package":"foo/bar/baz"}
+{"Action":"run","Package":"foo/bar/baz","Test":"TestOK"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestOK","Output":"=== RUN   TestOK\n","OutputType":"frame"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestOK","Output":"    hang_test.go:8: fine\n"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestOK","Output":"--- PASS: TestOK (0.00s)\n","OutputType":"frame"}
+{"Action":"pass","Package":"foo/bar/baz","Test":"TestOK"}
+{"Action":"run","Package":"foo/bar/baz","Test":"TestHang"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestHang","Output":"=== RUN   TestHang\n","OutputType":"frame"}
+{"Action":"run","Package":"foo/bar/baz","Test":"TestHang/sub"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestHang/sub","Output":"=== RUN   TestHang/sub\n","OutputType":"frame"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestHang/sub","Output":"    hang_test.go:12: about to hang\n"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestHang/sub","Output":"SIGQUIT: quit\n"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestHang/sub","Output":"PC=0x40ee0e m=0 sigcode=0\n"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestHang/sub","Output":"\n"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestHang/sub","Output":"goroutine 8 gp=0x36eeb9d754a0 m=nil [sleep]:\n"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestHang/sub","Output":"time.Sleep(0x34630b8a000)\n"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestHang/sub","Output":"\t/usr/local/go/src/runtime/time.go:368 +0x165\n"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestHang/sub","Output":"\n"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestHang/sub","Output":"rip    0x40ee0e\n"}
+`
+
+	// TestPanic/sub panics; test2json reports TestPanic's result
+	// only once the stream ends
+	JSONPanic = `{"Action":"start","Package":"foo/bar/baz"}
+{"Action":"run","Package":"foo/bar/baz","Test":"TestOK"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestOK","Output":"=== RUN   TestOK\n"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestOK","Output":"--- PASS: TestOK (0.00s)\n"}
+{"Action":"pass","Package":"foo/bar/baz","Test":"TestOK"}
+{"Action":"run","Package":"foo/bar/baz","Test":"TestPanic"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestPanic","Output":"=== RUN   TestPanic\n"}
+{"Action":"run","Package":"foo/bar/baz","Test":"TestPanic/sub"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestPanic/sub","Output":"=== RUN   TestPanic/sub\n"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestPanic/sub","Output":"    pan_test.go:12: before\n"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestPanic/sub","Output":"--- FAIL: TestPanic/sub (0.00s)\n"}
+{"Action":"fail","Package":"foo/bar/baz","Test":"TestPanic/sub"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestPanic","Output":"--- FAIL: TestPanic (0.00s)\n"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestPanic","Output":"panic: boom [recovered, repanicked]\n"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestPanic","Output":"\n"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestPanic","Output":"goroutine 8 [running]:\n"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestPanic","Output":"example.com/pan.TestPanic.func1(0xbde77c88488?)\n"}
+{"Action":"output","Package":"foo/bar/baz","Test":"TestPanic","Output":"\t/tmp/pan/pan_test.go:13 +0x4c\n"}
+{"Action":"fail","Package":"foo/bar/baz","Test":"TestPanic","Elapsed":0}
+{"Action":"output","Package":"foo/bar/baz","Output":"FAIL\tfoo/bar/baz\t0.005s\n"}
+{"Action":"fail","Package":"foo/bar/baz","Elapsed":0.005}
+`
+)
+
+func parseJSON(t *testing.T, testdata string) []*results.TestPackage {
+	pkgs, err := ParseJSONOutput(
+		testPackageName,
+		11*time.Second,
+		bytes.NewBufferString(testdata),
+	)
+	assert.Nil(t, err)
+	return pkgs
+}
+
+func TestParseJSONOutputParallelFailure(t *testing.T) {
+	pkgs := parseJSON(t, JSONParallelFailure)
+	assert.Equal(t, len(pkgs), 1)
+
+	pkg := pkgs[0]
+	assert.Equal(t, pkg.Name, "foo/bar/baz")
+	assert.Equal(t, pkg.Result, results.Failed)
+	assert.Equal(t, pkg.Duration, 11.0)
+	assert.StringContains(t, pkg.Output, "=== CONT  TestB\n")
+	assert.StringContains(t, pkg.Output, "FAIL\n")
+	assert.Equal(t, len(pkg.Tests), 3)
+
+	testB := pkg.Tests[0]
+	assert.Equal(t, testB.Name, "TestB")
+	assert.Equal(t, testB.Result, results.Failed)
+	assert.Equal(t, testB.Output.String(), "    b_test.go:16: b failed\n")
+	assert.Equal(t, testB.Failure.String(), "    b_test.go:16: b failed\n")
+
+	testA := pkg.Tests[1]
+	assert.Equal(t, testA.Name, "TestA")
+	assert.Equal(t, testA.Result, results.Passed)
+	assert.Equal(t, testA.Duration, 0.02)
+	assert.Equal(t, testA.Output.String(), "    a_test.go:11: a log\n")
+	assert.Equal(t, testA.Failure.String(), "")
+
+	testC := pkg.Tests[2]
+	assert.Equal(t, testC.Name, "TestC")
+	assert.Equal(t, testC.Result, results.Skipped)
+	assert.Equal(t, testC.Output.String(), "")
+	assert.Equal(t, testC.Failure.String(), "\tc_test.go:3: reason\n")
+}
+
+func TestParseJSONOutputSubtests(t *testing.T) {
+	pkgs := parseJSON(t, JSONSubtests)
+	assert.Equal(t, len(pkgs), 1)
+
+	pkg := pkgs[0]
+	assert.Equal(t, len(pkg.Tests), 1)
+	assert.Equal(t, len(pkg.AllTests()), 4)
+
+	testC := pkg.Tests[0]
+	assert.Equal(t, testC.Name, "TestC")
+	assert.Equal(t, testC.Result, results.Failed)
+	assert.Equal(t, len(testC.Subtests), 2)
+
+	sub1 := testC.Subtests[0]
+	assert.Equal(t, sub1.Name, "TestC/sub1")
+	assert.Equal(t, sub1.Result, results.Passed)
+	assert.Equal(t, sub1.Output.String(), "    c_test.go:20: in sub1\n")
+
+	sub2 := testC.Subtests[1]
+	assert.Equal(t, sub2.Name, "TestC/sub2")
+	assert.Equal(t, len(sub2.Subtests), 1)
+	assert.Equal(t, sub2.Subtests[0].Name, "TestC/sub2/deep")
+	assert.Equal(t, sub2.Subtests[0].Failure.String(), "    c_test.go:22: deep fail\n")
+}
+
+func TestParseJSONOutputBenchmark(t *testing.T) {
+	pkgs := parseJSON(t, JSONBenchmark)
+	assert.Equal(t, len(pkgs), 1)
+
+	pkg := pkgs[0]
+	assert.Equal(t, pkg.Result, results.Passed)
+	assert.Equal(t, len(pkg.Tests), 1)
+
+	bench := pkg.Tests[0]
+	assert.Equal(t, bench.Name, "BenchmarkX")
+	assert.Equal(t, bench.Result, results.Passed)
+	assert.StringContains(t, bench.Output.String(), "3.680 ns/op")
+
+	assert.Equal(t, len(pkg.Benchmarks), 1)
+	assert.Equal(t, pkg.Benchmarks[0].Name, "BenchmarkX")
+	assert.Equal(t, pkg.Benchmarks[0].Iterations, int64(100))
+	assert.Equal(t, pkg.Benchmarks[0].NsPerOp, 3.68)
+}
+
+func TestParseJSONOutputMultiplePackages(t *testing.T) {
+	pkgs := parseJSON(t, JSONMultiplePackages)
+	assert.Equal(t, len(pkgs), 2)
+
+	a := pkgs[0]
+	assert.Equal(t, a.Name, "a")
+	assert.Equal(t, a.Result, results.Passed)
+	assert.Equal(t, a.Duration, 1.5)
+	assert.Equal(t, a.Output, "go: some diagnostic\n")
+	assert.Equal(t, len(a.Tests), 1)
+	assert.Equal(t, a.Tests[0].Duration, 0.5)
+
+	b := pkgs[1]
+	assert.Equal(t, b.Name, "b")
+	assert.Equal(t, b.Result, results.Failed)
+	assert.Equal(t, b.Duration, 2.5)
+	assert.Equal(t, len(b.Tests), 1)
+	assert.Equal(t, b.Tests[0].Result, results.Failed)
+}
+
+func TestParseJSONOutputNoPackageResult(t *testing.T) {
+	pkgs := parseJSON(t, JSONNoPackageResult)
+	assert.Equal(t, len(pkgs), 1)
+
+	pkg := pkgs[0]
+	assert.Equal(t, pkg.Result, results.Failed)
+	assert.StringContains(t, pkg.Output, "Did not find package result")
+	assert.Equal(t, len(pkg.Tests), 1)
+	assert.Equal(t, pkg.Tests[0].Result, results.Failed)
+}
+
+func TestParseJSONOutputBuildFailures(t *testing.T) {
+	pkgs := parseJSON(t, JSONBuildFailures)
+	assert.Equal(t, len(pkgs), 3)
+
+	broken := pkgs[0]
+	assert.Equal(t, broken.Name, "foo/broken")
+	assert.Equal(t, broken.Result, results.Failed)
+	assert.Equal(t, len(broken.Tests), 0)
+	assert.Equal(
+		t,
+		broken.Output,
+		"# foo/broken [foo/broken.test]\n"+
+			"./b.go:3:23: cannot use \"x\" (untyped string constant) as int value in return statement\n"+
+			"FAIL\tfoo/broken [build failed]\n",
+	)
+	assert.DeepEqual(t, broken.BuildErrors, []*results.BuildError{
+		{
+			File:    "./b.go",
+			Line:    3,
+			Column:  23,
+			Message: `cannot use "x" (untyped string constant) as int value in return statement`,
+		},
+	})
+
+	a := pkgs[1]
+	assert.Equal(t, a.Name, "foo/a")
+	assert.Equal(t, a.Result, results.Passed)
+	assert.Equal(t, len(a.BuildErrors), 0)
+
+	vetfail := pkgs[2]
+	assert.Equal(t, vetfail.Name, "foo/vetfail")
+	assert.Equal(t, len(vetfail.BuildErrors), 1)
+	assert.Equal(t, vetfail.BuildErrors[0].File, "./v.go")
+}
+
+func TestParseJSONOutputUnclaimedBuildOutput(t *testing.T) {
+	pkgs := parseJSON(t, `{"ImportPath":"foo/x_test [foo/x.test]","Action":"build-output","Output":"some output\n"}
+{"Action":"start","Package":"foo/x"}
+{"Action":"pass","Package":"foo/x","Elapsed":0.5}
+`)
+	assert.Equal(t, len(pkgs), 1)
+	assert.Equal(t, pkgs[0].Name, "foo/x")
+	assert.Equal(t, pkgs[0].Output, "some output\n")
+}
+
+func TestParseJSONOutputQuitDump(t *testing.T) {
+	pkgs := parseJSON(t, JSONQuitDump)
+	assert.Equal(t, len(pkgs), 1)
+
+	pkg := pkgs[0]
+	assert.Equal(t, pkg.Result, results.Failed)
+	assert.Equal(t, len(pkg.Tests), 2)
+	assert.Equal(t, pkg.Tests[0].Result, results.Passed)
+
+	testHang := pkg.Tests[1]
+	assert.Equal(t, testHang.Name, "TestHang")
+	assert.Equal(t, testHang.Result, results.Failed)
+	assert.Equal(t, testHang.FailureKind, results.Timeout)
+	assert.Equal(t, len(testHang.Subtests), 1)
+
+	sub := testHang.Subtests[0]
+	assert.Equal(t, sub.Result, results.Failed)
+	assert.Equal(t, sub.FailureKind, results.Timeout)
+	assert.Equal(t, sub.Output.String(), "    hang_test.go:12: about to hang\n")
+	assert.Equal(
+		t,
+		sub.Failure.String(),
+		"SIGQUIT: quit\nPC=0x40ee0e m=0 sigcode=0\n\n"+
+			"goroutine 8 gp=0x36eeb9d754a0 m=nil [sleep]:\n"+
+			"time.Sleep(0x34630b8a000)\n"+
+			"\t/usr/local/go/src/runtime/time.go:368 +0x165\n\n"+
+			"rip    0x40ee0e\n",
+	)
+}
+
+func TestParseJSONOutputPanic(t *testing.T) {
+	pkgs := parseJSON(t, JSONPanic)
+	assert.Equal(t, len(pkgs), 1)
+
+	pkg := pkgs[0]
+	assert.Equal(t, pkg.Result, results.Failed)
+	assert.Equal(t, len(pkg.Tests), 2)
+	assert.Equal(t, pkg.Tests[0].Result, results.Passed)
+
+	testPanic := pkg.Tests[1]
+	assert.Equal(t, testPanic.Result, results.Failed)
+	assert.Equal(t, testPanic.FailureKind, results.TestFailure)
+	assert.Equal(t, testPanic.Failure.String(), "")
+	assert.Equal(t, len(testPanic.Subtests), 1)
+
+	sub := testPanic.Subtests[0]
+	assert.Equal(t, sub.Result, results.Failed)
+	assert.Equal(t, sub.FailureKind, results.Panic)
+	assert.Equal(t, sub.Output.String(), "    pan_test.go:12: before\n")
+	assert.Equal(
+		t,
+		sub.Failure.String(),
+		"panic: boom [recovered, repanicked]\n\n"+
+			"goroutine 8 [running]:\n"+
+			"example.com/pan.TestPanic.func1(0xbde77c88488?)\n"+
+			"\t/tmp/pan/pan_test.go:13 +0x4c\n",
+	)
+}
+
+func TestParseJSONOutputEmpty(t *testing.T) {
+	pkgs := parseJSON(t, "")
+	assert.Equal(t, len(pkgs), 1)
+	assert.Equal(t, pkgs[0].Name, testPackageName)
+	assert.Equal(t, pkgs[0].Result, results.Failed)
+}
+
+func TestForceTest2JSONFlag(t *testing.T) {
+	result := ForceTest2JSONFlag([]string{"-test.timeout=4s"})
+	assert.DeepEqual(t, result, []string{"-test.timeout=4s", "-test.v=test2json"})
+
+	result = ForceTest2JSONFlag([]string{"-test.v=true", "-test.timeout=4s", "-test.v"})
+	assert.DeepEqual(t, result, []string{"-test.timeout=4s", "-test.v=test2json"})
+}
+
+func TestForceGoTestJSONFlag(t *testing.T) {
+	result := ForceGoTestJSONFlag([]string{"test", "./..."})
+	assert.DeepEqual(t, result, []string{"test", "./...", "-json"})
+
+	jsonArgs := []string{"test", "-json", "./..."}
+	result = ForceGoTestJSONFlag(jsonArgs)
+	assert.DeepEqual(t, result, jsonArgs)
+}
+
+func TestTest2JSONFilter(t *testing.T) {
+	cmd, args := Test2JSONFilter("a/b")
+	assert.Equal(t, cmd, "go")
+	assert.DeepEqual(t, args, []string{"tool", "test2json", "-t", "-p", "a/b"})
+}
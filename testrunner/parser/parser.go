@@ -14,7 +14,7 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
-// Package parser provides a test output parser for go test.
+// Package parser provides test output parsers for go test.
 package parser
 
 import (
@@ -25,10 +25,21 @@ import (
 )
 
 type Parser struct {
+	// Optionally returns a command (and its arguments) through which
+	// the test executable's output is filtered before parsing, given
+	// the package name. If nil, the output is parsed as is. The test
+	// executable is always run directly, so that it can be signaled.
+	FilterFn func(packageName string) (string, []string)
+
 	// Modifies command line arguments to include any test executable flags required by
 	// the parser.
 	FlagFn func([]string) []string
 
+	// Modifies go test command line arguments (when running go test
+	// against one or more packages) to include any flags required by
+	// the parser.
+	GoTestFlagFn func([]string) []string
+
 	// Parses the test executable's output, returning 1 or more test package results or
 	// an error if the output could not be parsed.
 	ParseFn func(
@@ -36,8 +47,35 @@ type Parser struct {
 		duration time.Duration,
 		testOutput *bytes.Buffer,
 	) ([]*results.TestPackage, error)
+
+	// Returns a Stream that parses the test executable's output as it
+	// is written, producing the same results as ParseFn.
+	StreamFn func(packageName string) *Stream
 }
 
 var (
-	GoLangParser = Parser{ForceVerboseFlag, ParseTestOutput}
+	// GoLangParser parses go test verbose output.
+	GoLangParser = Parser{
+		FlagFn:       ForceVerboseFlag,
+		GoTestFlagFn: ForceGoTestVerboseFlag,
+		ParseFn:      ParseTestOutput,
+		StreamFn:     NewGoStream,
+	}
+
+	// JSONParser parses the test2json event stream produced by
+	// filtering the test executable's output through "go tool
+	// test2json".
+	JSONParser = Parser{
+		FilterFn:     Test2JSONFilter,
+		FlagFn:       ForceTest2JSONFlag,
+		GoTestFlagFn: ForceGoTestJSONFlag,
+		ParseFn:      ParseJSONOutput,
+		StreamFn:     NewJSONStream,
+	}
+
+	// Parsers maps parser names to Parsers.
+	Parsers = map[string]Parser{
+		"golang": GoLangParser,
+		"json":   JSONParser,
+	}
 )
@@ -0,0 +1,197 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package parser
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+// lineParser parses test output a line at a time.
+type lineParser interface {
+	// parseLine parses a line of output, including its newline (if
+	// any).
+	parseLine(line []byte) error
+
+	// finish completes parsing, returning a TestPackage for each
+	// package found. The given duration is used for packages whose
+	// duration is not found in the output.
+	finish(duration time.Duration) []*results.TestPackage
+}
+
+// Progress describes the progress of the top-level tests found so far
+// in a Stream.
+type Progress struct {
+	Passed  int
+	Failed  int
+	Skipped int
+
+	// How long each running test has been running, since it started
+	// or (if it is a parallel test) last resumed. Paused tests are
+	// not running.
+	Running map[string]time.Duration
+
+	// The parallel tests waiting to resume, sorted by name.
+	Paused []string
+}
+
+// Longest returns the name of the longest running test, and how long
+// it has been running. The name is empty if no test is running.
+func (p Progress) Longest() (string, time.Duration) {
+	name := ""
+	var longest time.Duration
+	for n, d := range p.Running {
+		if name == "" || d > longest || (d == longest && n < name) {
+			name, longest = n, d
+		}
+	}
+	return name, longest
+}
+
+// Stream parses test output as it is written, rather than once the
+// test executable exits, tracking the progress of its tests. Once the
+// output is complete, Finish returns the same results as the
+// corresponding Parser's ParseFn would given the same output. A
+// Stream may be written to concurrently.
+type Stream struct {
+	lock    sync.Mutex
+	parser  lineParser
+	partial []byte
+	err     error
+	now     func() time.Time
+
+	passed, failed, skipped int
+	running                 map[string]time.Time
+	paused                  map[string]bool
+}
+
+func newStream(newParser func(testObserver) lineParser) *Stream {
+	s := &Stream{
+		running: map[string]time.Time{},
+		paused:  map[string]bool{},
+		now:     time.Now,
+	}
+	s.parser = newParser(s.observe)
+	return s
+}
+
+// Write parses each complete line of output. It never fails: a
+// parsing error is returned by Finish.
+func (s *Stream) Write(p []byte) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.partial = append(s.partial, p...)
+	for {
+		i := bytes.IndexByte(s.partial, '\n')
+		if i < 0 {
+			break
+		}
+		s.parseLine(s.partial[0 : i+1])
+		s.partial = s.partial[i+1:]
+	}
+
+	return len(p), nil
+}
+
+func (s *Stream) parseLine(line []byte) {
+	if s.err == nil {
+		s.err = s.parser.parseLine(line)
+	}
+}
+
+// Progress returns the progress of the tests parsed so far.
+func (s *Stream) Progress() Progress {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.now()
+	p := Progress{
+		Passed:  s.passed,
+		Failed:  s.failed,
+		Skipped: s.skipped,
+		Running: make(map[string]time.Duration, len(s.running)),
+		Paused:  make([]string, 0, len(s.paused)),
+	}
+	for name, since := range s.running {
+		p.Running[name] = now.Sub(since)
+	}
+	for name := range s.paused {
+		p.Paused = append(p.Paused, name)
+	}
+	sort.Strings(p.Paused)
+	return p
+}
+
+// Finish parses any remaining partial line of output and returns 1 or
+// more test package results, or an error if the output could not be
+// parsed. The Stream may not be written to afterwards.
+func (s *Stream) Finish(duration time.Duration) ([]*results.TestPackage, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.parseLine(s.partial)
+	s.partial = nil
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	return s.parser.finish(duration), nil
+}
+
+// observe tracks the top-level tests as they change state. It is
+// called while the lock is held.
+func (s *Stream) observe(t *results.Test, state testState) {
+	if strings.Contains(t.Name, "/") {
+		return
+	}
+
+	switch state {
+	case testRunning:
+		s.running[t.Name] = s.now()
+		delete(s.paused, t.Name)
+	case testPaused:
+		delete(s.running, t.Name)
+		s.paused[t.Name] = true
+	case testFinished:
+		delete(s.running, t.Name)
+		delete(s.paused, t.Name)
+		switch t.Result {
+		case results.Passed:
+			s.passed++
+		case results.Failed:
+			s.failed++
+		case results.Skipped:
+			s.skipped++
+		}
+	}
+}
+
+// parseAll parses the complete output of a test run.
+func parseAll(
+	s *Stream,
+	duration time.Duration,
+	output *bytes.Buffer,
+) ([]*results.TestPackage, error) {
+	output.WriteTo(s)
+	return s.Finish(duration)
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package parser
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/turbinelabs/test/assert"
+)
+
+func TestStreamMatchesParseFn(t *testing.T) {
+	fixtures := map[string][]string{
+		"golang": {
+			SinglePackageVerbose,
+			SinglePackageVerboseFailure,
+			Subtests,
+			GoTestMultiplePackages,
+			GoTestBuildFailures,
+			ParallelTests,
+			Benchmarks,
+			QuitDump,
+			Panic,
+			NoPackageResultFailure,
+		},
+		"json": {
+			JSONParallelFailure,
+			JSONBuildFailures,
+			JSONSubtests,
+			JSONBenchmark,
+			JSONMultiplePackages,
+			JSONNoPackageResult,
+			JSONPanic,
+		},
+	}
+
+	for name, outputs := range fixtures {
+		p := Parsers[name]
+		for _, output := range outputs {
+			expected, err := p.ParseFn(testPackageName, time.Second, bytes.NewBufferString(output))
+			assert.Nil(t, err)
+
+			// written in arbitrary chunks, as a test executable would
+			s := p.StreamFn(testPackageName)
+			for data := []byte(output); len(data) > 0; {
+				n := 7
+				if n > len(data) {
+					n = len(data)
+				}
+				s.Write(data[0:n])
+				data = data[n:]
+			}
+			got, err := s.Finish(time.Second)
+			assert.Nil(t, err)
+			assert.DeepEqual(t, got, expected)
+		}
+	}
+}
+
+func TestStreamProgress(t *testing.T) {
+	now := time.Unix(1000, 0)
+	s := NewGoStream(testPackageName)
+	s.now = func() time.Time { return now }
+
+	s.Write([]byte("=== RUN   TestA\n=== RUN   TestA/sub\n=== PAUSE TestA/sub\n"))
+	s.Write([]byte("=== RUN   TestB\n    b_test.go:3: log\n=== RU"))
+
+	now = now.Add(time.Second)
+	s.Write([]byte("N   TestC\n=== PAUSE TestC\n"))
+
+	now = now.Add(time.Second)
+	p := s.Progress()
+	assert.MapEqual(t, p.Running, map[string]time.Duration{
+		"TestA": 2 * time.Second,
+		"TestB": 2 * time.Second,
+	})
+	assert.ArrayEqual(t, p.Paused, []string{"TestC"})
+	name, d := p.Longest()
+	assert.Equal(t, name, "TestA")
+	assert.Equal(t, d, 2*time.Second)
+
+	s.Write([]byte("    --- PASS: TestA/sub (0.00s)\n--- PASS: TestA (2.00s)\n"))
+	s.Write([]byte("--- FAIL: TestB (2.00s)\n=== CONT  TestC\n"))
+	p = s.Progress()
+	assert.Equal(t, p.Passed, 1)
+	assert.Equal(t, p.Failed, 1)
+	assert.MapEqual(t, p.Running, map[string]time.Duration{"TestC": 0})
+	assert.Equal(t, len(p.Paused), 0)
+
+	now = now.Add(2 * time.Second)
+	name, d = s.Progress().Longest()
+	assert.Equal(t, name, "TestC")
+	assert.Equal(t, d, 2*time.Second)
+
+	s.Write([]byte("--- SKIP: TestC (2.00s)\nPASS\n"))
+	p = s.Progress()
+	assert.Equal(t, p.Skipped, 1)
+	assert.Equal(t, len(p.Running), 0)
+
+	name, _ = p.Longest()
+	assert.Equal(t, name, "")
+}
+
+func TestStreamProgressJSON(t *testing.T) {
+	lines := strings.SplitAfter(JSONParallelFailure, "\n")
+
+	s := NewJSONStream(testPackageName)
+	s.Write([]byte(strings.Join(lines[0:6], "")))
+	p := s.Progress()
+	assert.Equal(t, len(p.Running), 1)
+
+	// TestA and TestB have both paused
+	s.Write([]byte(strings.Join(lines[6:9], "")))
+	p = s.Progress()
+	assert.Equal(t, len(p.Running), 0)
+	assert.ArrayEqual(t, p.Paused, []string{"TestA", "TestB"})
+
+	s.Write([]byte(strings.Join(lines[9:11], "")))
+	p = s.Progress()
+	assert.HasSameElements(t, runningTests(p), []string{"TestA"})
+
+	s.Write([]byte(strings.Join(lines[11:], "")))
+	p = s.Progress()
+	assert.Equal(t, p.Passed, 1)
+	assert.Equal(t, p.Failed, 1)
+	assert.Equal(t, p.Skipped, 1)
+	assert.Equal(t, len(p.Running), 0)
+}
+
+func runningTests(p Progress) []string {
+	names := []string{}
+	for name := range p.Running {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,233 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package parser
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+// testState is the state of a test, as reported to a testObserver.
+type testState int
+
+const (
+	testRunning  testState = iota // started or resumed
+	testPaused                    // a parallel test waiting to resume
+	testFinished                  // the test's result is known
+)
+
+// A testObserver is notified as the tests in a testTree change state.
+type testObserver func(t *results.Test, state testState)
+
+// testTree tracks the tests of a single package by name. Completed
+// subtests are attached to their parent test; completed top-level
+// tests are added to the package.
+type testTree struct {
+	pkg     *results.TestPackage
+	tests   map[string]*results.Test
+	running []*results.Test
+	observe testObserver // may be nil
+
+	// tests whose "--- PASS/FAIL/SKIP" line has been seen
+	reported map[*results.Test]bool
+
+	// the most deeply nested of the tests that most recently
+	// reported failure, provided no other test has since started or
+	// reported a different result; a panicking test reports failure
+	// (along with its parents) just before the panic
+	lastFailed *results.Test
+
+	// set once the test executable panics or otherwise crashes: the
+	// cause, the tests considered responsible, and the crash output
+	// (e.g. a goroutine dump)
+	crashKind  results.FailureKind
+	crashTests []*results.Test
+	crash      *bytes.Buffer
+}
+
+func newTestTree(pkg *results.TestPackage, observe testObserver) *testTree {
+	return &testTree{
+		pkg:      pkg,
+		tests:    map[string]*results.Test{},
+		observe:  observe,
+		reported: map[*results.Test]bool{},
+	}
+}
+
+func (tt *testTree) notify(t *results.Test, state testState) {
+	if tt.observe != nil {
+		tt.observe(t, state)
+	}
+}
+
+// start returns the named test, creating it and marking it running
+// if it has not been seen before.
+func (tt *testTree) start(name string) *results.Test {
+	if t, ok := tt.tests[name]; ok {
+		return t
+	}
+
+	t := &results.Test{Name: name}
+	tt.tests[name] = t
+	tt.running = append(tt.running, t)
+	tt.lastFailed = nil
+	tt.notify(t, testRunning)
+	return t
+}
+
+// pause records that a parallel test is waiting to resume.
+func (tt *testTree) pause(t *results.Test) {
+	tt.notify(t, testPaused)
+}
+
+// resume records that a paused parallel test is running again.
+func (tt *testTree) resume(t *results.Test) {
+	tt.notify(t, testRunning)
+}
+
+// parent returns the closest ancestor of the named test, or nil if
+// the test is a top-level test.
+func (tt *testTree) parent(name string) *results.Test {
+	for i := strings.LastIndex(name, "/"); i > 0; i = strings.LastIndex(name, "/") {
+		name = name[0:i]
+		if t, ok := tt.tests[name]; ok {
+			return t
+		}
+	}
+	return nil
+}
+
+// reportResult records that the test's result line has been seen.
+// The result itself is recorded separately, via finish.
+func (tt *testTree) reportResult(t *results.Test, result results.TestResult) {
+	tt.reported[t] = true
+
+	switch {
+	case result != results.Failed:
+		tt.lastFailed = nil
+	case tt.lastFailed == nil || !strings.HasPrefix(tt.lastFailed.Name, t.Name+"/"):
+		tt.lastFailed = t
+	}
+}
+
+func (tt *testTree) isRunning(t *results.Test) bool {
+	for _, r := range tt.running {
+		if r == t {
+			return true
+		}
+	}
+	return false
+}
+
+// finish records the test's result and attaches it to its parent or
+// the package.
+func (tt *testTree) finish(t *results.Test, result results.TestResult, duration float64) {
+	for i, r := range tt.running {
+		if r == t {
+			tt.running = append(tt.running[0:i], tt.running[i+1:]...)
+			break
+		}
+	}
+
+	t.Result = result
+	t.Duration = duration
+
+	if parent := tt.parent(t.Name); parent != nil {
+		parent.Subtests = append(parent.Subtests, t)
+	} else {
+		tt.pkg.Tests = append(tt.pkg.Tests, t)
+	}
+
+	tt.notify(t, testFinished)
+}
+
+// crashKind returns the kind of failure indicated by the first line
+// of output printed by the go runtime when a process crashes: a
+// panic, a fatal runtime error, or exit due to SIGQUIT. SIGQUIT and
+// the panic raised by -test.timeout both indicate a timeout.
+func crashKind(line string) (results.FailureKind, bool) {
+	switch {
+	case line == "SIGQUIT: quit":
+		return results.Timeout, true
+	case strings.HasPrefix(line, "panic: test timed out after "):
+		return results.Timeout, true
+	case strings.HasPrefix(line, "panic: "), strings.HasPrefix(line, "fatal error: "):
+		return results.Panic, true
+	default:
+		return results.TestFailure, false
+	}
+}
+
+// crashed records that the test executable crashed, given the first
+// line (including its newline) of the crash output. The tests running
+// at this point that have not reported a result are held
+// responsible; if there are none, the test that just reported failure
+// is. Subsequent output should be written to the returned buffer.
+func (tt *testTree) crashed(kind results.FailureKind, line string) *bytes.Buffer {
+	if tt.crash != nil {
+		return tt.crash
+	}
+
+	tt.crashKind = kind
+	for _, t := range tt.running {
+		if !tt.reported[t] {
+			tt.crashTests = append(tt.crashTests, t)
+		}
+	}
+	if len(tt.crashTests) == 0 && tt.lastFailed != nil {
+		tt.crashTests = []*results.Test{tt.lastFailed}
+	}
+
+	tt.crash = &bytes.Buffer{}
+	tt.crash.WriteString(line)
+	return tt.crash
+}
+
+// complete is called once the package's result is known. It
+// finishes any tests that never reported a result. Tests held
+// responsible for a crash fail, with the crash output as their
+// failure. Benchmarks only report a result if they fail or log, so
+// they take on the package's result. Any other test did not complete
+// and is marked as failed.
+func (tt *testTree) complete() {
+	for _, t := range tt.crashTests {
+		t.FailureKind = tt.crashKind
+		t.Failure.Write(tt.crash.Bytes())
+		if tt.isRunning(t) {
+			tt.finish(t, results.Failed, 0)
+		}
+	}
+
+	for len(tt.running) > 0 {
+		t := tt.running[0]
+		result := results.Failed
+		if tt.pkg.Result == results.Passed && strings.HasPrefix(t.Name, "Benchmark") {
+			result = results.Passed
+		}
+		tt.finish(t, result, 0)
+	}
+
+	for _, t := range tt.tests {
+		if t.Result == results.Failed && t.Failure.Len() == 0 {
+			// Recent versions of go test report failure messages
+			// before the test result rather than after.
+			t.Failure.Write(t.Output.Bytes())
+		}
+	}
+}
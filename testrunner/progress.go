@@ -0,0 +1,214 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/turbinelabs/test/testrunner/parser"
+)
+
+const (
+	// progressInterval is how often a test run's progress is
+	// checked.
+	progressInterval = 250 * time.Millisecond
+
+	// statusLogInterval is the minimum time between status lines
+	// when they cannot be redrawn in place (i.e. standard error is
+	// not a terminal).
+	statusLogInterval = 10 * time.Second
+
+	progressOutput = "output"
+	progressStatus = "status"
+)
+
+// display configures how the progress of a test run is shown.
+type display struct {
+	status bool          // a status line rather than the test output
+	slow   time.Duration // warn of tests running longer; 0 if disabled
+}
+
+// getDisplay returns the configured display.
+func getDisplay() (display, error) {
+	slow, err := parseDuration(ENV_SLOW_TEST, SlowTest)
+	if err != nil {
+		return display{}, err
+	}
+
+	d := display{slow: slow}
+	switch ProgressMode {
+	case progressOutput:
+	case progressStatus:
+		d.status = true
+	default:
+		return display{}, invalidSetting(
+			ENV_PROGRESS,
+			ProgressMode,
+			fmt.Sprintf("is not %q or %q", progressOutput, progressStatus),
+		)
+	}
+	return d, nil
+}
+
+// progressReporter shows the progress of a test run.
+type progressReporter struct {
+	out      io.Writer
+	terminal bool // status lines are redrawn in place
+	display  display
+
+	warned map[string]bool // tests reported as slow
+	status string          // the status line last shown
+	shown  time.Time       // when it was shown
+}
+
+// watchProgress shows the progress of the tests parsed by the stream,
+// as configured, until the returned function is called.
+func watchProgress(stream *parser.Stream, d display) func() {
+	if !d.status && d.slow == 0 {
+		return func() {}
+	}
+
+	r := &progressReporter{
+		out:      os.Stderr,
+		terminal: isTerminal(os.Stderr),
+		display:  d,
+		warned:   map[string]bool{},
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				r.report(stream.Progress(), now)
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+		r.finish(stream.Progress())
+	}
+}
+
+// report warns of newly slow tests and shows the status line, if
+// enabled.
+func (r *progressReporter) report(p parser.Progress, now time.Time) {
+	if r.display.slow > 0 {
+		names := make([]string, 0, len(p.Running))
+		for name := range p.Running {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			d := p.Running[name]
+			if d <= r.display.slow || r.warned[name] {
+				continue
+			}
+			r.warned[name] = true
+			r.clearStatus()
+			fmt.Fprintf(
+				r.out,
+				"[slow test: %s has been running for %s]\n",
+				name,
+				roundDuration(d),
+			)
+		}
+	}
+
+	if !r.display.status {
+		return
+	}
+
+	status := statusLine(p)
+	switch {
+	case r.terminal:
+		fmt.Fprintf(r.out, "\r%s\033[K", status)
+	case status != r.status && now.Sub(r.shown) >= statusLogInterval:
+		fmt.Fprintln(r.out, status)
+		r.shown = now
+	default:
+		return
+	}
+	r.status = status
+}
+
+// finish shows the final status line, if enabled.
+func (r *progressReporter) finish(p parser.Progress) {
+	if !r.display.status {
+		return
+	}
+
+	if r.terminal {
+		fmt.Fprintf(r.out, "\r%s\033[K\n", statusLine(p))
+	} else {
+		fmt.Fprintln(r.out, statusLine(p))
+	}
+}
+
+// clearStatus erases a status line drawn in place, so that other
+// output may be written.
+func (r *progressReporter) clearStatus() {
+	if r.terminal && r.status != "" {
+		fmt.Fprint(r.out, "\r\033[K")
+		r.status = ""
+	}
+}
+
+// statusLine summarizes the progress of a test run, naming the
+// longest running test.
+func statusLine(p parser.Progress) string {
+	status := fmt.Sprintf(
+		"[%d passed, %d failed, %d skipped, %d running",
+		p.Passed,
+		p.Failed,
+		p.Skipped,
+		len(p.Running),
+	)
+	if name, d := p.Longest(); name != "" {
+		status += fmt.Sprintf(": %s %s", name, roundDuration(d))
+	}
+	return status + "]"
+}
+
+// roundDuration rounds a duration for display: to the second, or to
+// the hundredth of a second if less than a second.
+func roundDuration(d time.Duration) time.Duration {
+	if d < time.Second {
+		return d.Round(10 * time.Millisecond)
+	}
+	return d.Round(time.Second)
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/turbinelabs/test/assert"
+	"github.com/turbinelabs/test/testrunner/parser"
+)
+
+func TestGetDisplay(t *testing.T) {
+	savedMode, savedSlow := ProgressMode, SlowTest
+	defer func() {
+		ProgressMode, SlowTest = savedMode, savedSlow
+	}()
+
+	ProgressMode, SlowTest = "output", ""
+	d, err := getDisplay()
+	assert.Nil(t, err)
+	assert.Equal(t, d, display{})
+
+	ProgressMode, SlowTest = "status", "30s"
+	d, err = getDisplay()
+	assert.Nil(t, err)
+	assert.Equal(t, d, display{status: true, slow: 30 * time.Second})
+
+	ProgressMode, SlowTest = "quiet", ""
+	_, err = getDisplay()
+	assert.ErrorContains(t, err, `TEST_RUNNER_PROGRESS=quiet is not "output" or "status"`)
+
+	ProgressMode, SlowTest = "status", "-1s"
+	_, err = getDisplay()
+	assert.ErrorContains(t, err, "TEST_RUNNER_SLOW_TEST=-1s is not a non-negative duration")
+}
+
+func TestStatusLine(t *testing.T) {
+	p := parser.Progress{Passed: 3, Failed: 1, Running: map[string]time.Duration{}}
+	assert.Equal(t, statusLine(p), "[3 passed, 1 failed, 0 skipped, 0 running]")
+
+	p.Running["TestA"] = 1400 * time.Millisecond
+	p.Running["TestB"] = 12 * time.Second
+	assert.Equal(t, statusLine(p), "[3 passed, 1 failed, 0 skipped, 2 running: TestB 12s]")
+}
+
+func TestProgressReporterSlowTests(t *testing.T) {
+	out := &bytes.Buffer{}
+	r := &progressReporter{
+		out:     out,
+		display: display{slow: 10 * time.Second},
+		warned:  map[string]bool{},
+	}
+
+	now := time.Unix(1000, 0)
+	p := parser.Progress{
+		Running: map[string]time.Duration{
+			"TestA": 5 * time.Second,
+			"TestB": 11 * time.Second,
+			"TestC": 20 * time.Second,
+		},
+	}
+	r.report(p, now)
+	assert.Equal(
+		t,
+		out.String(),
+		"[slow test: TestB has been running for 11s]\n"+
+			"[slow test: TestC has been running for 20s]\n",
+	)
+
+	// each test is reported once
+	out.Reset()
+	p.Running["TestA"] = 15 * time.Second
+	p.Running["TestB"] = 16 * time.Second
+	r.report(p, now.Add(time.Second))
+	assert.Equal(t, out.String(), "[slow test: TestA has been running for 15s]\n")
+
+	r.finish(p)
+	assert.Equal(t, out.String(), "[slow test: TestA has been running for 15s]\n")
+}
+
+func TestProgressReporterStatus(t *testing.T) {
+	out := &bytes.Buffer{}
+	r := &progressReporter{
+		out:     out,
+		display: display{status: true},
+		warned:  map[string]bool{},
+	}
+
+	now := time.Unix(1000, 0)
+	p := parser.Progress{Passed: 1, Running: map[string]time.Duration{}}
+	r.report(p, now)
+	assert.Equal(t, out.String(), "[1 passed, 0 failed, 0 skipped, 0 running]\n")
+
+	// without a terminal, status lines are logged periodically
+	p.Passed = 2
+	r.report(p, now.Add(time.Second))
+	assert.Equal(t, out.String(), "[1 passed, 0 failed, 0 skipped, 0 running]\n")
+
+	r.report(p, now.Add(statusLogInterval))
+	assert.Equal(
+		t,
+		out.String(),
+		"[1 passed, 0 failed, 0 skipped, 0 running]\n"+
+			"[2 passed, 0 failed, 0 skipped, 0 running]\n",
+	)
+
+	// on a terminal, the status line is redrawn in place
+	out.Reset()
+	r.terminal = true
+	r.display.slow = time.Second
+	p.Running["TestA"] = 2 * time.Second
+	r.report(p, now)
+	r.finish(p)
+	assert.Equal(
+		t,
+		out.String(),
+		"\r\033[K[slow test: TestA has been running for 2s]\n"+
+			"\r[2 passed, 0 failed, 0 skipped, 1 running: TestA 2s]\033[K"+
+			"\r[2 passed, 0 failed, 0 skipped, 1 running: TestA 2s]\033[K\n",
+	)
+}
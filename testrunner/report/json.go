@@ -0,0 +1,229 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+// JSONReportVersion is the version of the JSON report schema. It is
+// incremented whenever a change to the schema would break existing
+// readers; adding fields does not.
+const JSONReportVersion = 1
+
+// JSONReport is the document written by JSONReporter.
+type JSONReport struct {
+	Version  int            `json:"version"`
+	Packages []*JSONPackage `json:"packages"`
+}
+
+type JSONPackage struct {
+	Name       string           `json:"name"`
+	Result     string           `json:"result"`   // "pass", "fail", or "skip"
+	Duration   float64          `json:"duration"` // seconds
+	Tests      []*JSONTest      `json:"tests"`    // top-level tests only
+	Benchmarks []*JSONBenchmark `json:"benchmarks"`
+	Coverage   *JSONCoverage    `json:"coverage,omitempty"`
+	Usage      *JSONUsage       `json:"usage,omitempty"`
+
+	// For packages that did not build, the compiler or vet
+	// diagnostics.
+	BuildErrors []*JSONBuildError `json:"buildErrors,omitempty"`
+
+	Output string `json:"output"`
+}
+
+type JSONBuildError struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+type JSONTest struct {
+	Name     string  `json:"name"`
+	Result   string  `json:"result"`   // "pass", "fail", "skip", or "flaky"
+	Duration float64 `json:"duration"` // seconds
+
+	// For failed and flaky tests, the failure message and kind:
+	// "failure", "timeout", "panic", or "interrupted".
+	Failure     string `json:"failure,omitempty"`
+	FailureKind string `json:"failureKind,omitempty"`
+
+	// The number of times the test was rerun after failing.
+	Retries int `json:"retries,omitempty"`
+
+	Output   string      `json:"output"`
+	Subtests []*JSONTest `json:"subtests"`
+}
+
+type JSONBenchmark struct {
+	Name        string             `json:"name"`
+	Procs       int                `json:"procs"`
+	Iterations  int64              `json:"iterations"`
+	NsPerOp     float64            `json:"nsPerOp"`
+	BytesPerOp  *float64           `json:"bytesPerOp,omitempty"`
+	AllocsPerOp *float64           `json:"allocsPerOp,omitempty"`
+	Metrics     map[string]float64 `json:"metrics,omitempty"`
+}
+
+type JSONCoverage struct {
+	Statements int                 `json:"statements"`
+	Covered    int                 `json:"covered"`
+	Percent    float64             `json:"percent"`
+	Files      []*JSONFileCoverage `json:"files"`
+}
+
+type JSONFileCoverage struct {
+	Name       string  `json:"name"`
+	Statements int     `json:"statements"`
+	Covered    int     `json:"covered"`
+	Percent    float64 `json:"percent"`
+}
+
+// JSONUsage describes the resources used by a package's test
+// executable. Times are in seconds.
+type JSONUsage struct {
+	UserTime   float64 `json:"userTime"`
+	SystemTime float64 `json:"systemTime"`
+	WallTime   float64 `json:"wallTime"`
+	MaxRSS     int64   `json:"maxRSS,omitempty"` // bytes
+}
+
+var jsonResults = map[results.TestResult]string{
+	results.Passed:  "pass",
+	results.Failed:  "fail",
+	results.Skipped: "skip",
+	results.Flaky:   "flaky",
+}
+
+type jsonReporter struct{}
+
+func (jsonReporter) Extension() string { return ".json" }
+
+func (jsonReporter) WriteReport(out io.Writer, pkgs []*results.TestPackage) {
+	data, err := json.MarshalIndent(GenerateJSONReport(pkgs), "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	out.Write(data)
+	out.Write([]byte{'\n'})
+}
+
+// GenerateJSONReport converts a []*results.TestPackage into a
+// JSONReport.
+func GenerateJSONReport(pkgs []*results.TestPackage) JSONReport {
+	report := JSONReport{
+		Version:  JSONReportVersion,
+		Packages: make([]*JSONPackage, 0, len(pkgs)),
+	}
+
+	for _, pkg := range pkgs {
+		jsonPkg := &JSONPackage{
+			Name:       pkg.Name,
+			Result:     jsonResults[pkg.Result],
+			Duration:   pkg.Duration,
+			Tests:      jsonTests(pkg.Tests),
+			Benchmarks: make([]*JSONBenchmark, 0, len(pkg.Benchmarks)),
+			Output:     pkg.Output,
+		}
+
+		for _, b := range pkg.Benchmarks {
+			jsonBench := &JSONBenchmark{
+				Name:       b.Name,
+				Procs:      b.Procs,
+				Iterations: b.Iterations,
+				NsPerOp:    b.NsPerOp,
+				Metrics:    b.Metrics,
+			}
+			if b.HasMemStats {
+				bytesPerOp, allocsPerOp := b.BytesPerOp, b.AllocsPerOp
+				jsonBench.BytesPerOp = &bytesPerOp
+				jsonBench.AllocsPerOp = &allocsPerOp
+			}
+			jsonPkg.Benchmarks = append(jsonPkg.Benchmarks, jsonBench)
+		}
+
+		if c := pkg.Coverage; c != nil {
+			jsonPkg.Coverage = &JSONCoverage{
+				Statements: c.Statements,
+				Covered:    c.Covered,
+				Percent:    c.Percent(),
+				Files:      make([]*JSONFileCoverage, 0, len(c.Files)),
+			}
+			for _, f := range c.Files {
+				jsonPkg.Coverage.Files = append(
+					jsonPkg.Coverage.Files,
+					&JSONFileCoverage{
+						Name:       f.Name,
+						Statements: f.Statements,
+						Covered:    f.Covered,
+						Percent:    f.Percent(),
+					},
+				)
+			}
+		}
+
+		if u := pkg.Usage; u != nil {
+			jsonPkg.Usage = &JSONUsage{
+				UserTime:   u.UserTime,
+				SystemTime: u.SystemTime,
+				WallTime:   u.WallTime,
+				MaxRSS:     u.MaxRSS,
+			}
+		}
+
+		for _, e := range pkg.BuildErrors {
+			jsonPkg.BuildErrors = append(
+				jsonPkg.BuildErrors,
+				&JSONBuildError{
+					File:    e.File,
+					Line:    e.Line,
+					Column:  e.Column,
+					Message: e.Message,
+				},
+			)
+		}
+
+		report.Packages = append(report.Packages, jsonPkg)
+	}
+
+	return report
+}
+
+func jsonTests(tests []*results.Test) []*JSONTest {
+	converted := make([]*JSONTest, 0, len(tests))
+	for _, t := range tests {
+		jsonTest := &JSONTest{
+			Name:     t.Name,
+			Result:   jsonResults[t.Result],
+			Duration: t.Duration,
+			Output:   t.Output.String(),
+			Subtests: jsonTests(t.Subtests),
+			Retries:  t.Retries,
+		}
+		if t.Result == results.Failed || t.Result == results.Flaky {
+			jsonTest.Failure = t.Failure.String()
+			jsonTest.FailureKind = failureKindNames[t.FailureKind]
+		}
+		converted = append(converted, jsonTest)
+	}
+	return converted
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/turbinelabs/test/assert"
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+func TestJSONReporter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	JSONReporter.WriteReport(buf, testPackages())
+
+	var report JSONReport
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &report))
+	assert.Equal(t, report.Version, JSONReportVersion)
+	assert.Equal(t, len(report.Packages), 3)
+
+	a := report.Packages[0]
+	assert.Equal(t, a.Name, "foo/a")
+	assert.Equal(t, a.Result, "pass")
+	assert.Equal(t, a.Duration, 1.5)
+	assert.Equal(t, a.Output, "PASS\n")
+	assert.Equal(t, len(a.Tests), 2)
+	assert.Equal(t, len(a.Benchmarks), 1)
+
+	bench := a.Benchmarks[0]
+	assert.Equal(t, bench.Name, "BenchmarkA")
+	assert.Equal(t, bench.Procs, 8)
+	assert.Equal(t, bench.Iterations, int64(100))
+	assert.Equal(t, bench.NsPerOp, 12.5)
+	assert.Equal(t, *bench.BytesPerOp, 16.0)
+	assert.Equal(t, *bench.AllocsPerOp, 1.0)
+
+	b := report.Packages[1]
+	assert.Equal(t, b.Result, "fail")
+	assert.Equal(t, len(b.Tests), 2)
+
+	testB := b.Tests[0]
+	assert.Equal(t, testB.Result, "fail")
+	assert.Equal(t, testB.Output, "b log\n")
+	assert.Equal(t, testB.FailureKind, "failure")
+	assert.Equal(t, len(testB.Subtests), 1)
+
+	sub := testB.Subtests[0]
+	assert.Equal(t, sub.Name, "TestB/sub")
+	assert.Equal(t, sub.Failure, "panic: boom\n\ngoroutine 7 [running]:\n")
+	assert.Equal(t, sub.FailureKind, "panic")
+	assert.Equal(t, len(sub.Subtests), 0)
+
+	skipped := b.Tests[1]
+	assert.Equal(t, skipped.Result, "skip")
+	assert.Equal(t, skipped.Failure, "")
+	assert.Equal(t, skipped.FailureKind, "")
+
+	// flaky tests report their original failure
+	flaky := &results.Test{Name: "TestF", Result: results.Flaky, Retries: 1}
+	flaky.Failure.WriteString("flaked")
+	jsonFlaky := GenerateJSONReport([]*results.TestPackage{{Tests: []*results.Test{flaky}}})
+	assert.DeepEqual(t, jsonFlaky.Packages[0].Tests[0], &JSONTest{
+		Name:        "TestF",
+		Result:      "flaky",
+		Failure:     "flaked",
+		FailureKind: "failure",
+		Retries:     1,
+		Subtests:    []*JSONTest{},
+	})
+
+	c := report.Packages[2]
+	assert.Equal(t, c.Result, "fail")
+	assert.Equal(t, len(c.Tests), 0)
+	assert.Equal(t, len(c.Benchmarks), 0)
+	assert.Nil(t, c.Coverage)
+	assert.Nil(t, c.Usage)
+
+	// build errors are included when present
+	broken := GenerateJSONReport([]*results.TestPackage{
+		{
+			Result: results.Failed,
+			BuildErrors: []*results.BuildError{
+				{File: "./d.go", Line: 3, Column: 1, Message: "syntax error"},
+			},
+		},
+	})
+	assert.DeepEqual(t, broken.Packages[0].BuildErrors, []*JSONBuildError{
+		{File: "./d.go", Line: 3, Column: 1, Message: "syntax error"},
+	})
+
+	// coverage is included when present
+	covered := GenerateJSONReport([]*results.TestPackage{
+		{
+			Coverage: &results.Coverage{
+				Statements: 4,
+				Covered:    1,
+				Files: []*results.FileCoverage{
+					{Name: "foo/d/d.go", Statements: 4, Covered: 1},
+				},
+			},
+		},
+	})
+	assert.DeepEqual(t, covered.Packages[0].Coverage, &JSONCoverage{
+		Statements: 4,
+		Covered:    1,
+		Percent:    25,
+		Files: []*JSONFileCoverage{
+			{Name: "foo/d/d.go", Statements: 4, Covered: 1, Percent: 25},
+		},
+	})
+
+	// resource usage is included when present
+	used := GenerateJSONReport([]*results.TestPackage{
+		{
+			Usage: &results.ResourceUsage{
+				UserTime:   1.5,
+				SystemTime: 0.25,
+				WallTime:   2,
+				MaxRSS:     1 << 20,
+			},
+		},
+	})
+	assert.DeepEqual(t, used.Packages[0].Usage, &JSONUsage{
+		UserTime:   1.5,
+		SystemTime: 0.25,
+		WallTime:   2,
+		MaxRSS:     1 << 20,
+	})
+}
+
+func TestJSONReporterSchema(t *testing.T) {
+	buf := &bytes.Buffer{}
+	JSONReporter.WriteReport(buf, testPackages()[0:1])
+
+	// Field names are part of the schema: renaming them breaks
+	// readers.
+	var doc map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(t, doc["version"], 1.0)
+
+	pkg := doc["packages"].([]interface{})[0].(map[string]interface{})
+	for _, key := range []string{"name", "result", "duration", "tests", "benchmarks", "output"} {
+		_, ok := pkg[key]
+		assert.True(t, ok)
+	}
+
+	test := pkg["tests"].([]interface{})[0].(map[string]interface{})
+	for _, key := range []string{"name", "result", "duration", "output", "subtests"} {
+		_, ok := test[key]
+		assert.True(t, ok)
+	}
+
+	bench := pkg["benchmarks"].([]interface{})[0].(map[string]interface{})
+	for _, key := range []string{"name", "procs", "iterations", "nsPerOp", "bytesPerOp", "allocsPerOp"} {
+		_, ok := bench[key]
+		assert.True(t, ok)
+	}
+}
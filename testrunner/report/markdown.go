@@ -0,0 +1,181 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+// SlowestTests is the number of tests listed by MarkdownReporter as
+// the slowest tests.
+const SlowestTests = 10
+
+var markdownResults = map[results.TestResult]string{
+	results.Passed:  "PASS",
+	results.Failed:  "FAIL",
+	results.Skipped: "SKIP",
+	results.Flaky:   "FLAKY",
+}
+
+type markdownReporter struct{}
+
+func (markdownReporter) Extension() string { return ".md" }
+
+// WriteReport writes a summary of each package, followed by the
+// failure messages of failed tests (and the output of failed packages
+// with no failed tests), flaky tests, and finally the slowest tests.
+func (markdownReporter) WriteReport(out io.Writer, pkgs []*results.TestPackage) {
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+
+	type pkgTest struct {
+		pkg  *results.TestPackage
+		test *results.Test
+	}
+
+	fmt.Fprintln(w, "# Test Results")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "| Package | Result | Tests | Failed | Skipped | Time |")
+	fmt.Fprintln(w, "| --- | --- | ---: | ---: | ---: | ---: |")
+
+	failures := []pkgTest{}
+	flaky := []pkgTest{}
+	all := []pkgTest{}
+	for _, pkg := range pkgs {
+		tests := pkg.AllTests()
+		skipped := 0
+		for _, t := range tests {
+			all = append(all, pkgTest{pkg, t})
+			switch t.Result {
+			case results.Skipped:
+				skipped++
+			case results.Flaky:
+				flaky = append(flaky, pkgTest{pkg, t})
+			}
+		}
+
+		failedTests := failed(pkg)
+		for _, t := range failedTests {
+			failures = append(failures, pkgTest{pkg, t})
+		}
+		if pkg.Result == results.Failed && len(failedTests) == 0 {
+			failures = append(failures, pkgTest{pkg, nil})
+		}
+
+		fmt.Fprintf(
+			w,
+			"| %s | %s | %d | %d | %d | %.3fs |\n",
+			markdownCell(pkg.Name),
+			markdownResults[pkg.Result],
+			len(tests),
+			len(failedTests),
+			skipped,
+			pkg.Duration,
+		)
+	}
+
+	if len(failures) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "## Failures")
+
+		for _, f := range failures {
+			fmt.Fprintln(w)
+			if f.test == nil {
+				fmt.Fprintf(w, "### %s\n\n", f.pkg.Name)
+				writeMarkdownCode(w, f.pkg.Output)
+				continue
+			}
+
+			heading := fmt.Sprintf("%s: %s", f.pkg.Name, f.test.Name)
+			if f.test.FailureKind != results.TestFailure {
+				heading = fmt.Sprintf("%s (%s)", heading, failureKindNames[f.test.FailureKind])
+			}
+			fmt.Fprintf(w, "### %s\n\n", heading)
+			writeMarkdownCode(w, f.test.Failure.String())
+		}
+	}
+
+	if len(flaky) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "## Flaky Tests")
+		fmt.Fprintln(w)
+		for _, f := range flaky {
+			fmt.Fprintf(
+				w,
+				"- %s: %s (retries: %d)\n",
+				f.pkg.Name,
+				f.test.Name,
+				f.test.Retries,
+			)
+		}
+	}
+
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].test.Duration > all[j].test.Duration
+	})
+	slowest := all
+	if len(slowest) > SlowestTests {
+		slowest = slowest[0:SlowestTests]
+	}
+	for len(slowest) > 0 && slowest[len(slowest)-1].test.Duration <= 0 {
+		slowest = slowest[0 : len(slowest)-1]
+	}
+
+	if len(slowest) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "## Slowest Tests")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "| Test | Package | Result | Time |")
+		fmt.Fprintln(w, "| --- | --- | --- | ---: |")
+		for _, s := range slowest {
+			fmt.Fprintf(
+				w,
+				"| %s | %s | %s | %.3fs |\n",
+				markdownCell(s.test.Name),
+				markdownCell(s.pkg.Name),
+				markdownResults[s.test.Result],
+				s.test.Duration,
+			)
+		}
+	}
+}
+
+// markdownCell escapes text for use in a table cell.
+func markdownCell(s string) string {
+	return strings.Replace(s, "|", `\|`, -1)
+}
+
+// writeMarkdownCode writes text as a fenced code block. The fence is
+// made longer than any run of backticks in the text.
+func writeMarkdownCode(w io.Writer, text string) {
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+
+	text = strings.TrimRight(text, "\n")
+	if text == "" {
+		text = "(no output)"
+	}
+	fmt.Fprintf(w, "%s\n%s\n%s\n", fence, text, fence)
+}
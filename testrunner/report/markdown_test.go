@@ -0,0 +1,94 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/turbinelabs/test/assert"
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+func TestMarkdownReporter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	MarkdownReporter.WriteReport(buf, testPackages())
+
+	// code fences are written as ~~~ for readability
+	expected := strings.Replace(`# Test Results
+
+| Package | Result | Tests | Failed | Skipped | Time |
+| --- | --- | ---: | ---: | ---: | ---: |
+| foo/a | PASS | 2 | 0 | 0 | 1.500s |
+| foo/b | FAIL | 3 | 2 | 1 | 0.500s |
+| foo/c | FAIL | 0 | 0 | 0 | 0.000s |
+
+## Failures
+
+### foo/b: TestB
+
+~~~
+(no output)
+~~~
+
+### foo/b: TestB/sub (panic)
+
+~~~
+panic: boom
+
+goroutine 7 [running]:
+~~~
+
+### foo/c
+
+~~~
+c.go:3:1: syntax error
+~~~
+
+## Slowest Tests
+
+| Test | Package | Result | Time |
+| --- | --- | --- | ---: |
+| TestA2 | foo/a | PASS | 1.100s |
+| TestB | foo/b | FAIL | 0.300s |
+| TestB/sub | foo/b | FAIL | 0.300s |
+| TestA1 | foo/a | PASS | 0.200s |
+`, "~~~", "```", -1)
+
+	assert.Equal(t, buf.String(), expected)
+}
+
+func TestMarkdownReporterPassing(t *testing.T) {
+	buf := &bytes.Buffer{}
+	MarkdownReporter.WriteReport(buf, []*results.TestPackage{
+		{Name: "foo|bar", Result: results.Passed, Tests: []*results.Test{}},
+	})
+
+	assert.Equal(t, buf.String(), `# Test Results
+
+| Package | Result | Tests | Failed | Skipped | Time |
+| --- | --- | ---: | ---: | ---: | ---: |
+| foo\|bar | PASS | 0 | 0 | 0 | 0.000s |
+`)
+}
+
+func TestWriteMarkdownCode(t *testing.T) {
+	buf := &bytes.Buffer{}
+	writeMarkdownCode(buf, "a ```b```` c\n")
+	assert.Equal(t, buf.String(), "`````\na ```b```` c\n`````\n")
+}
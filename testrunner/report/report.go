@@ -0,0 +1,87 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package report provides writers for test results in several
+// formats.
+package report
+
+import (
+	"io"
+
+	"github.com/turbinelabs/test/testrunner/junit"
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+// Reporter writes test results in a particular format.
+type Reporter interface {
+	// Extension returns the file extension used for reports in this
+	// format, including the leading dot.
+	Extension() string
+
+	// WriteReport writes a report of the given packages.
+	WriteReport(out io.Writer, pkgs []*results.TestPackage)
+}
+
+var (
+	// JUnitReporter writes junit-style XML reports. See the junit
+	// package.
+	JUnitReporter Reporter = junitReporter{}
+
+	// TAPReporter writes Test Anything Protocol (version 13)
+	// reports.
+	TAPReporter Reporter = tapReporter{}
+
+	// JSONReporter writes JSON reports. See JSONReport.
+	JSONReporter Reporter = jsonReporter{}
+
+	// MarkdownReporter writes human-readable summaries in Markdown.
+	MarkdownReporter Reporter = markdownReporter{}
+
+	// Reporters maps format names to Reporters.
+	Reporters = map[string]Reporter{
+		"junit":    JUnitReporter,
+		"tap":      TAPReporter,
+		"json":     JSONReporter,
+		"markdown": MarkdownReporter,
+	}
+)
+
+type junitReporter struct{}
+
+func (junitReporter) Extension() string { return ".xml" }
+
+func (junitReporter) WriteReport(out io.Writer, pkgs []*results.TestPackage) {
+	junit.WriteReport(out, pkgs)
+}
+
+// failureKindNames names the kinds of test failure.
+var failureKindNames = map[results.FailureKind]string{
+	results.TestFailure: "failure",
+	results.Timeout:     "timeout",
+	results.Panic:       "panic",
+	results.Interrupted: "interrupted",
+}
+
+// failed returns the package's failed tests, including subtests.
+func failed(pkg *results.TestPackage) []*results.Test {
+	tests := []*results.Test{}
+	for _, t := range pkg.AllTests() {
+		if t.Result == results.Failed {
+			tests = append(tests, t)
+		}
+	}
+	return tests
+}
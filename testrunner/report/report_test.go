@@ -0,0 +1,115 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/turbinelabs/test/assert"
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+func makeBuffer(s string) bytes.Buffer {
+	b := bytes.NewBufferString(s)
+	return *b
+}
+
+// testPackages returns a passing package with a benchmark, a package
+// with a panicking subtest and a skipped test, and a package that
+// failed without running any tests.
+func testPackages() []*results.TestPackage {
+	return []*results.TestPackage{
+		{
+			Name:     "foo/a",
+			Result:   results.Passed,
+			Duration: 1.5,
+			Tests: []*results.Test{
+				{Name: "TestA1", Result: results.Passed, Duration: 0.2},
+				{Name: "TestA2", Result: results.Passed, Duration: 1.1},
+			},
+			Benchmarks: []*results.Benchmark{
+				{
+					Name:        "BenchmarkA",
+					Procs:       8,
+					Iterations:  100,
+					NsPerOp:     12.5,
+					HasMemStats: true,
+					BytesPerOp:  16,
+					AllocsPerOp: 1,
+				},
+			},
+			Output: "PASS\n",
+		},
+		{
+			Name:     "foo/b",
+			Result:   results.Failed,
+			Duration: 0.5,
+			Tests: []*results.Test{
+				{
+					Name:     "TestB",
+					Result:   results.Failed,
+					Duration: 0.3,
+					Output:   makeBuffer("b log\n"),
+					Subtests: []*results.Test{
+						{
+							Name:        "TestB/sub",
+							Result:      results.Failed,
+							Duration:    0.3,
+							Failure:     makeBuffer("panic: boom\n\ngoroutine 7 [running]:\n"),
+							FailureKind: results.Panic,
+						},
+					},
+				},
+				{Name: "TestSkip", Result: results.Skipped},
+			},
+			Output: "...\n",
+		},
+		{
+			Name:   "foo/c",
+			Result: results.Failed,
+			Tests:  []*results.Test{},
+			Output: "c.go:3:1: syntax error\n",
+		},
+	}
+}
+
+func TestReporters(t *testing.T) {
+	extensions := map[string]string{}
+	for name, r := range Reporters {
+		extensions[name] = r.Extension()
+
+		buf := &bytes.Buffer{}
+		r.WriteReport(buf, testPackages())
+		assert.True(t, buf.Len() > 0)
+	}
+
+	assert.DeepEqual(t, extensions, map[string]string{
+		"junit":    ".xml",
+		"tap":      ".tap",
+		"json":     ".json",
+		"markdown": ".md",
+	})
+}
+
+func TestJUnitReporter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	JUnitReporter.WriteReport(buf, testPackages())
+	assert.True(t, strings.HasPrefix(buf.String(), "<testsuites "))
+	assert.StringContains(t, buf.String(), `<failure message="Panicked" type="panic">`)
+}
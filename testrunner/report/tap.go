@@ -0,0 +1,125 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+var tapMessages = map[results.FailureKind]string{
+	results.TestFailure: "Failed",
+	results.Timeout:     "Timed out",
+	results.Panic:       "Panicked",
+	results.Interrupted: "Interrupted",
+}
+
+// tapEscaper escapes a test point's description, in which "#" would
+// otherwise begin a directive.
+var tapEscaper = strings.NewReplacer(`\`, `\\`, "#", `\#`)
+
+type tapReporter struct{}
+
+func (tapReporter) Extension() string { return ".tap" }
+
+// WriteReport writes a single TAP version 13 document covering all of
+// the packages. Each test and subtest is a test point, described by
+// its package and name, with "#" and "\" escaped. Flaky tests are
+// reported as passing, noting their retries. A failed package with no
+// failed tests (e.g. one that did not build) is reported as a failed
+// test point of its own. Failures are described by a YAML block.
+func (tapReporter) WriteReport(out io.Writer, pkgs []*results.TestPackage) {
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+
+	type point struct {
+		pkg  *results.TestPackage
+		test *results.Test // nil for a failed package
+	}
+
+	points := []point{}
+	for _, pkg := range pkgs {
+		for _, t := range pkg.AllTests() {
+			points = append(points, point{pkg, t})
+		}
+		if pkg.Result == results.Failed && len(failed(pkg)) == 0 {
+			points = append(points, point{pkg, nil})
+		}
+	}
+
+	fmt.Fprintln(w, "TAP version 13")
+	fmt.Fprintf(w, "1..%d\n", len(points))
+
+	for i, p := range points {
+		if p.test == nil {
+			fmt.Fprintf(w, "not ok %d - %s\n", i+1, tapEscaper.Replace(p.pkg.Name))
+			writeTAPYAML(w, "Failed", "", p.pkg.Duration, "output", p.pkg.Output)
+			continue
+		}
+
+		t := p.test
+		desc := tapEscaper.Replace(fmt.Sprintf("%s %s", p.pkg.Name, t.Name))
+		switch t.Result {
+		case results.Passed:
+			fmt.Fprintf(w, "ok %d - %s\n", i+1, desc)
+		case results.Skipped:
+			fmt.Fprintf(w, "ok %d - %s # SKIP\n", i+1, desc)
+		case results.Flaky:
+			fmt.Fprintf(w, "ok %d - %s (flaky, retries: %d)\n", i+1, desc, t.Retries)
+		default:
+			fmt.Fprintf(w, "not ok %d - %s\n", i+1, desc)
+			writeTAPYAML(
+				w,
+				tapMessages[t.FailureKind],
+				failureKindNames[t.FailureKind],
+				t.Duration,
+				"failure",
+				t.Failure.String(),
+			)
+		}
+	}
+}
+
+// writeTAPYAML writes a YAML block describing a failed test point,
+// with the given text as a literal block.
+func writeTAPYAML(
+	w io.Writer,
+	message string,
+	kind string,
+	duration float64,
+	textKey string,
+	text string,
+) {
+	fmt.Fprintln(w, "  ---")
+	fmt.Fprintf(w, "  message: %q\n", message)
+	fmt.Fprintln(w, "  severity: fail")
+	if kind != "" {
+		fmt.Fprintf(w, "  kind: %s\n", kind)
+	}
+	fmt.Fprintf(w, "  duration_ms: %.3f\n", duration*1000)
+	if text = strings.TrimRight(text, "\n"); text != "" {
+		fmt.Fprintf(w, "  %s: |\n", textKey)
+		for _, line := range strings.Split(text, "\n") {
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+	}
+	fmt.Fprintln(w, "  ...")
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/turbinelabs/test/assert"
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+func TestTAPReporter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	TAPReporter.WriteReport(buf, testPackages())
+
+	assert.Equal(t, buf.String(), `TAP version 13
+1..6
+ok 1 - foo/a TestA1
+ok 2 - foo/a TestA2
+not ok 3 - foo/b TestB
+  ---
+  message: "Failed"
+  severity: fail
+  kind: failure
+  duration_ms: 300.000
+  ...
+not ok 4 - foo/b TestB/sub
+  ---
+  message: "Panicked"
+  severity: fail
+  kind: panic
+  duration_ms: 300.000
+  failure: |
+    panic: boom
+    
+    goroutine 7 [running]:
+  ...
+ok 5 - foo/b TestSkip # SKIP
+not ok 6 - foo/c
+  ---
+  message: "Failed"
+  severity: fail
+  duration_ms: 0.000
+  output: |
+    c.go:3:1: syntax error
+  ...
+`)
+}
+
+func TestTAPReporterEscapesDescriptions(t *testing.T) {
+	buf := &bytes.Buffer{}
+	TAPReporter.WriteReport(buf, []*results.TestPackage{
+		{
+			Name:   "foo/a",
+			Result: results.Passed,
+			Tests: []*results.Test{
+				{
+					Name:   "TestTable",
+					Result: results.Passed,
+					Subtests: []*results.Test{
+						{Name: "TestTable/#00", Result: results.Passed},
+						{Name: `TestTable/a_\_#_SKIP`, Result: results.Passed},
+					},
+				},
+			},
+		},
+	})
+
+	assert.Equal(t, buf.String(), `TAP version 13
+1..3
+ok 1 - foo/a TestTable
+ok 2 - foo/a TestTable/\#00
+ok 3 - foo/a TestTable/a_\\_\#_SKIP
+`)
+}
@@ -20,6 +20,8 @@ package results
 
 import (
 	"bytes"
+	"fmt"
+	"time"
 )
 
 // TestResult is a pseudo-enum representing passed/skipped/failed
@@ -29,20 +31,170 @@ const (
 	Passed TestResult = iota
 	Failed
 	Skipped
+
+	// The test failed but passed when retried (see Test.Retries).
+	Flaky
+)
+
+// FailureKind is a pseudo-enum representing the cause of a test
+// failure.
+type FailureKind int
+
+const (
+	// The test reported its own failure, e.g. via testing.T.Error.
+	TestFailure FailureKind = iota
+
+	// The test was still running when the test executable timed
+	// out, either via -test.timeout or SIGQUIT.
+	Timeout
+
+	// The test executable panicked or crashed while the test was
+	// running.
+	Panic
+
+	// The test was still running when testrunner was interrupted
+	// (e.g. by SIGINT).
+	Interrupted
 )
 
 type TestPackage struct {
-	Name     string
-	Result   TestResult
-	Duration float64
-	Tests    []*Test
-	Output   string
+	Name        string
+	Result      TestResult
+	Start       time.Time // zero if unknown
+	Duration    float64
+	Tests       []*Test // top-level tests only; see AllTests
+	Benchmarks  []*Benchmark
+	Coverage    *Coverage      // nil unless a coverage profile was captured
+	Environment *Environment   // nil if unknown
+	Usage       *ResourceUsage // nil unless a test executable was run
+	BuildFailed bool           // the package or its tests did not build
+	BuildErrors []*BuildError
+	Output      string
+}
+
+// AllTests returns the package's tests and their subtests, depth
+// first, with each parent preceding its subtests.
+func (p *TestPackage) AllTests() []*Test {
+	return flatten(p.Tests, make([]*Test, 0, len(p.Tests)))
 }
 
 type Test struct {
-	Name     string
-	Result   TestResult
-	Duration float64
-	Failure  bytes.Buffer
-	Output   bytes.Buffer
+	Name        string
+	Result      TestResult
+	Duration    float64
+	Failure     bytes.Buffer
+	FailureKind FailureKind // meaningful only if Result is Failed or Flaky
+	Output      bytes.Buffer
+	Subtests    []*Test // subtests started via testing.T.Run
+
+	// The number of times the test was rerun after failing. Failure
+	// and Output are those of the original run.
+	Retries int
+}
+
+func flatten(tests []*Test, into []*Test) []*Test {
+	for _, t := range tests {
+		into = append(into, t)
+		into = flatten(t.Subtests, into)
+	}
+	return into
+}
+
+// Benchmark is the result of a single run of a benchmark. Benchmarks
+// run repeatedly (e.g. via -test.count) produce one Benchmark per
+// run.
+type Benchmark struct {
+	Name       string // e.g. "BenchmarkFoo/case_1"
+	Procs      int    // GOMAXPROCS, or 0 if not reported
+	Iterations int64
+	NsPerOp    float64
+
+	// Memory statistics are only reported if enabled via
+	// -test.benchmem or testing.B.ReportAllocs.
+	HasMemStats bool
+	BytesPerOp  float64
+	AllocsPerOp float64
+
+	// Other metrics, such as MB/s or those reported via
+	// testing.B.ReportMetric, keyed by unit.
+	Metrics map[string]float64
+}
+
+// FullName returns the benchmark's name as reported by go test,
+// including its GOMAXPROCS suffix (e.g. "BenchmarkFoo-8").
+func (b *Benchmark) FullName() string {
+	if b.Procs > 0 {
+		return fmt.Sprintf("%s-%d", b.Name, b.Procs)
+	}
+	return b.Name
+}
+
+// BuildError is a compiler or vet diagnostic reported while building
+// a package's tests.
+type BuildError struct {
+	File    string // as reported, e.g. "./foo.go"
+	Line    int
+	Column  int // 0 if not reported
+	Message string
+}
+
+func (e *BuildError) String() string {
+	if e.Column > 0 {
+		return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message)
+}
+
+// Environment describes the machine on which a package's tests ran.
+type Environment struct {
+	Hostname  string
+	GOOS      string
+	GOARCH    string
+	GoVersion string
+
+	// Selected environment variables (e.g. CI build identifiers),
+	// keyed by name.
+	Vars map[string]string
+}
+
+// ResourceUsage describes the resources used by a test executable.
+// Times are in seconds.
+type ResourceUsage struct {
+	UserTime   float64
+	SystemTime float64
+	WallTime   float64
+	MaxRSS     int64 // peak resident set size in bytes; 0 if unknown
+}
+
+// Coverage summarizes statement coverage of a package's source files.
+type Coverage struct {
+	Statements int
+	Covered    int
+	Files      []*FileCoverage // ordered by name
+}
+
+// FileCoverage summarizes statement coverage of a single source file.
+type FileCoverage struct {
+	Name       string // e.g. "github.com/foo/bar/bar.go"
+	Statements int
+	Covered    int
+}
+
+// Percent returns the percentage of statements covered, or 0 if
+// there are no statements.
+func (c *Coverage) Percent() float64 {
+	return percent(c.Covered, c.Statements)
+}
+
+// Percent returns the percentage of statements covered, or 0 if
+// there are no statements.
+func (f *FileCoverage) Percent() float64 {
+	return percent(f.Covered, f.Statements)
+}
+
+func percent(covered, statements int) float64 {
+	if statements == 0 {
+		return 0
+	}
+	return 100 * float64(covered) / float64(statements)
 }
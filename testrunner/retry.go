@@ -0,0 +1,219 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/turbinelabs/test/testrunner/parser"
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+// getRetries returns the configured number of retries.
+func getRetries() (int, error) {
+	retries, err := strconv.Atoi(Retries)
+	if err != nil || retries < 0 {
+		return 0, invalidSetting(ENV_RETRIES, Retries, "is not a non-negative integer")
+	}
+	return retries, nil
+}
+
+// retryFailedTests reruns the package's failed top-level tests, up to
+// the given number of times, until they pass. Tests that pass when
+// retried are marked flaky. If no failed tests, retryable or not,
+// remain, the package passes. The resources used by each run are
+// added to the package's. Retrying stops if a run is interrupted or
+// its output cannot be parsed. Returns the last run of the test
+// executable (initially the given run), or an error if it could not
+// be run.
+func retryFailedTests(
+	testParser parser.Parser,
+	pkg *results.TestPackage,
+	testExecutable string,
+	args []string,
+	limits timeouts,
+	progress display,
+	interrupts *interrupts,
+	retries int,
+	run testRun,
+) (testRun, error) {
+	lastPassed := false
+	for attempt := 1; attempt <= retries; attempt++ {
+		failed := retryableTests(pkg)
+		if len(failed) == 0 {
+			break
+		}
+
+		retryArgs := retryTestArgs(args, failed)
+		note := fmt.Sprintf(
+			"\n[retry %d of %d: %s]\n",
+			attempt,
+			retries,
+			retryArgs[len(retryArgs)-1],
+		)
+		fmt.Fprint(os.Stderr, note)
+
+		stream := testParser.StreamFn(pkg.Name)
+		retryRun, err := runTestExecutable(
+			testParser,
+			pkg.Name,
+			testExecutable,
+			retryArgs,
+			limits,
+			progress,
+			stream,
+			interrupts,
+		)
+		if err != nil {
+			return run, err
+		}
+		run = retryRun
+		addUsage(pkg, run.usage)
+
+		retryPkgs, err := stream.Finish(run.duration)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "testrunner: parsing retry output: %v\n", err)
+			lastPassed = false
+			break
+		}
+
+		pkg.Output += note
+		retried := map[string]*results.Test{}
+		lastPassed = true
+		for _, retryPkg := range retryPkgs {
+			pkg.Output += retryPkg.Output
+			for _, t := range retryPkg.Tests {
+				retried[t.Name] = t
+			}
+			if retryPkg.Result != results.Passed {
+				lastPassed = false
+			}
+		}
+
+		for _, t := range failed {
+			t.Retries++
+			if r, ok := retried[t.Name]; ok && r.Result == results.Passed {
+				markFlaky(t, t.Retries)
+			}
+		}
+
+		if run.signal != nil {
+			break
+		}
+	}
+
+	if lastPassed && !hasFailedTests(pkg) {
+		pkg.Result = results.Passed
+	}
+
+	return run, nil
+}
+
+// hasFailedTests returns true if any of the package's top-level tests,
+// including those that cannot be retried, failed.
+func hasFailedTests(pkg *results.TestPackage) bool {
+	for _, t := range pkg.Tests {
+		if t.Result == results.Failed {
+			return true
+		}
+	}
+	return false
+}
+
+// retryableTests returns the package's failed top-level tests that
+// can be rerun via -test.run. Benchmarks and tests synthesized by
+// testrunner (e.g. for benchmark regressions) are not retried.
+func retryableTests(pkg *results.TestPackage) []*results.Test {
+	tests := []*results.Test{}
+	for _, t := range pkg.Tests {
+		if t.Result == results.Failed && isRunnable(t.Name) {
+			tests = append(tests, t)
+		}
+	}
+	return tests
+}
+
+// retryTestArgs replaces any -test.run and -test.bench flags in the
+// given test executable arguments with a -test.run flag that runs
+// only the given tests. The -test.run flag is always last. Any
+// -test.coverprofile flag is removed, so that the original run's
+// profile is not overwritten.
+func retryTestArgs(args []string, tests []*results.Test) []string {
+	names := make([]string, len(tests))
+	for i, t := range tests {
+		names[i] = t.Name
+	}
+
+	return append(
+		withoutFlags(args, "-test.run", "-test.bench", "-test.coverprofile"),
+		runFlag(names),
+	)
+}
+
+// withoutFlags returns a copy of args without the given flags, given
+// as either "-flag=value" or "-flag value".
+func withoutFlags(args []string, flags ...string) []string {
+	result := make([]string, 0, len(args)+1)
+	skipValue := false
+	for _, arg := range args {
+		if skipValue {
+			skipValue = false
+			continue
+		}
+
+		removed := false
+		for _, flag := range flags {
+			if arg == flag {
+				skipValue = true
+				removed = true
+			} else if strings.HasPrefix(arg, flag+"=") {
+				removed = true
+			}
+		}
+		if !removed {
+			result = append(result, arg)
+		}
+	}
+	return result
+}
+
+// runFlag returns a -test.run flag that runs exactly the named
+// top-level tests.
+func runFlag(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = regexp.QuoteMeta(name)
+	}
+
+	return fmt.Sprintf("-test.run=^(%s)$", strings.Join(quoted, "|"))
+}
+
+// markFlaky marks the test, and those of its subtests that failed, as
+// flaky after the given number of retries.
+func markFlaky(t *results.Test, retries int) {
+	if t.Result == results.Failed {
+		t.Result = results.Flaky
+		t.Retries = retries
+	}
+	for _, sub := range t.Subtests {
+		markFlaky(sub, retries)
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/turbinelabs/test/assert"
+	"github.com/turbinelabs/test/testrunner/parser"
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+func TestRetryTestArgs(t *testing.T) {
+	tests := []*results.Test{{Name: "TestA"}, {Name: "TestB.c"}}
+
+	result := retryTestArgs(
+		[]string{
+			"-test.run", "Test",
+			"-test.v",
+			"-test.bench=.",
+			"-test.coverprofile=c.out",
+			"-test.run=X",
+			"-test.count=2",
+		},
+		tests,
+	)
+	assert.DeepEqual(t, result, []string{
+		"-test.v",
+		"-test.count=2",
+		`-test.run=^(TestA|TestB\.c)$`,
+	})
+}
+
+func TestRetryableTests(t *testing.T) {
+	pkg := &results.TestPackage{
+		Tests: []*results.Test{
+			{Name: "TestA", Result: results.Failed},
+			{Name: "TestB", Result: results.Passed},
+			{Name: "ExampleC", Result: results.Failed},
+			{Name: "BenchmarkD", Result: results.Failed},
+			{Name: "[regression] BenchmarkE ns/op", Result: results.Failed},
+		},
+	}
+
+	tests := retryableTests(pkg)
+	assert.Equal(t, len(tests), 2)
+	assert.Equal(t, tests[0].Name, "TestA")
+	assert.Equal(t, tests[1].Name, "ExampleC")
+}
+
+func TestMarkFlaky(t *testing.T) {
+	test := &results.Test{
+		Name:    "TestA",
+		Result:  results.Failed,
+		Retries: 2,
+		Subtests: []*results.Test{
+			{Name: "TestA/x", Result: results.Passed},
+			{Name: "TestA/y", Result: results.Failed},
+		},
+	}
+
+	markFlaky(test, 2)
+	assert.Equal(t, test.Result, results.Flaky)
+	assert.Equal(t, test.Subtests[0].Result, results.Passed)
+	assert.Equal(t, test.Subtests[0].Retries, 0)
+	assert.Equal(t, test.Subtests[1].Result, results.Flaky)
+	assert.Equal(t, test.Subtests[1].Retries, 2)
+}
+
+func TestRetryFailedTests(t *testing.T) {
+	// the test executable is this one: retrying TestMarkFlaky passes
+	for _, failedBenchmark := range []bool{false, true} {
+		pkg := &results.TestPackage{
+			Name:   "github.com/turbinelabs/test/testrunner",
+			Result: results.Failed,
+			Tests:  []*results.Test{{Name: "TestMarkFlaky", Result: results.Failed}},
+		}
+		if failedBenchmark {
+			pkg.Tests = append(pkg.Tests, &results.Test{Name: "BenchmarkX", Result: results.Failed})
+		}
+
+		run, err := retryFailedTests(
+			parser.GoLangParser,
+			pkg,
+			os.Args[0],
+			nil,
+			timeouts{},
+			display{},
+			nil,
+			2,
+			testRun{exitStatus: exitFailed},
+		)
+		assert.Nil(t, err)
+		assert.Equal(t, run.exitStatus, exitPassed)
+		assert.Equal(t, pkg.Tests[0].Result, results.Flaky)
+		assert.Equal(t, pkg.Tests[0].Retries, 1)
+
+		if failedBenchmark {
+			// the benchmark was not retried, so the package still fails
+			assert.Equal(t, pkg.Tests[1].Result, results.Failed)
+			assert.Equal(t, pkg.Tests[1].Retries, 0)
+			assert.Equal(t, pkg.Result, results.Failed)
+		} else {
+			assert.Equal(t, pkg.Result, results.Passed)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+)
+
+// shard identifies the subset of a package's tests run by one of
+// several workers.
+type shard struct {
+	index int // zero-based
+	count int
+}
+
+// getShard returns the configured shard. A count of 1 runs all tests.
+func getShard() (shard, error) {
+	count, err := strconv.Atoi(ShardCount)
+	if err != nil || count < 1 {
+		return shard{}, invalidSetting(ENV_SHARD_COUNT, ShardCount, "is not a positive integer")
+	}
+
+	index, err := strconv.Atoi(ShardIndex)
+	if err != nil || index < 0 || index >= count {
+		return shard{}, invalidSetting(
+			ENV_SHARD_INDEX,
+			ShardIndex,
+			fmt.Sprintf("is not an integer between 0 and %d", count-1),
+		)
+	}
+
+	return shard{index: index, count: count}, nil
+}
+
+// shardTestArgs returns the test executable arguments that run only
+// the shard's share of the named package's tests, as listed by the
+// test executable. Tests are assigned to shards using their durations
+// in the merged report in the output directory. Returns an error if
+// the tests could not be listed.
+func shardTestArgs(s shard, pkgName, testExecutable string, args []string) ([]string, error) {
+	tests, err := listTests(testExecutable, args)
+	if err != nil {
+		return nil, err
+	}
+	durations := testDurations(TestOutput, pkgName)
+
+	shardTests := assignShards(tests, durations, s.count)[s.index]
+	fmt.Fprintf(
+		os.Stderr,
+		"[shard %d of %d: %d of %d tests]\n",
+		s.index+1,
+		s.count,
+		len(shardTests),
+		len(tests),
+	)
+
+	return append(withoutFlags(args, "-test.run"), runFlag(shardTests)), nil
+}
+
+// listTests returns the names of the top-level tests, examples and
+// fuzz targets the test executable would run with the given
+// arguments, or an error if they could not be listed (e.g. because
+// the executable or the -test.run pattern is invalid).
+func listTests(testExecutable string, args []string) ([]string, error) {
+	pattern, ok := flagValue(args, "-test.run")
+	if !ok {
+		pattern = "."
+	}
+
+	listArgs := append(withoutFlags(args, "-test.run"), "-test.list="+pattern)
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(testExecutable, listArgs...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimRight(stderr.String(), "\n"); msg != "" {
+			err = fmt.Errorf("%v\n%s", err, msg)
+		}
+		return nil, fmt.Errorf("listing tests in %s: %v", testExecutable, err)
+	}
+
+	tests := []string{}
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if isRunnable(line) {
+			tests = append(tests, line)
+		}
+	}
+	return tests, nil
+}
+
+// isRunnable returns whether the name is that of a top-level test
+// selected by -test.run (rather than, e.g., a benchmark).
+func isRunnable(name string) bool {
+	for _, prefix := range []string{"Test", "Example", "Fuzz"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// testDurations returns the durations, in seconds, of the named
+// package's top-level tests in the merged report (MergedReportFile)
+// in the given directory, or none if it cannot be read. Package
+// reports are ignored: each worker's covers only its own shard, so
+// workers would see different durations and assign tests differently.
+func testDurations(dir, pkgName string) map[string]float64 {
+	durations := map[string]float64{}
+	suites, err := readReport(filepath.Join(dir, MergedReportFile))
+	if err != nil {
+		return durations
+	}
+
+	for _, suite := range suites.Suites {
+		if suite.Name != pkgName {
+			continue
+		}
+		for _, testCase := range suite.TestCases {
+			if strings.Contains(testCase.Name, "/") {
+				continue
+			}
+			if d, err := strconv.ParseFloat(testCase.Duration, 64); err == nil {
+				durations[testCase.Name] = d
+			}
+		}
+	}
+	return durations
+}
+
+// assignShards divides the tests into the given number of shards of
+// roughly equal total duration, assigning the longest remaining test
+// to the shard with the least total duration so far. Tests without a
+// known duration are assumed to take the mean known duration (or one
+// second, if none is known). The assignment depends only on its
+// inputs, so that each worker computes the same one.
+func assignShards(tests []string, durations map[string]float64, count int) [][]string {
+	known, total := 0, 0.0
+	for _, name := range tests {
+		if d, ok := durations[name]; ok {
+			known++
+			total += d
+		}
+	}
+	defaultDuration := 1.0
+	if known > 0 {
+		defaultDuration = total / float64(known)
+	}
+
+	type weighted struct {
+		name     string
+		duration float64
+	}
+
+	byDuration := make([]weighted, len(tests))
+	for i, name := range tests {
+		d, ok := durations[name]
+		if !ok {
+			d = defaultDuration
+		}
+		byDuration[i] = weighted{name, d}
+	}
+	sort.Slice(byDuration, func(i, j int) bool {
+		if byDuration[i].duration != byDuration[j].duration {
+			return byDuration[i].duration > byDuration[j].duration
+		}
+		return byDuration[i].name < byDuration[j].name
+	})
+
+	shards := make([][]string, count)
+	totals := make([]float64, count)
+	for _, t := range byDuration {
+		least := 0
+		for i := 1; i < count; i++ {
+			if totals[i] < totals[least] {
+				least = i
+			}
+		}
+		shards[least] = append(shards[least], t.name)
+		totals[least] += t.duration
+	}
+
+	for _, names := range shards {
+		sort.Strings(names)
+	}
+	return shards
+}
+
+// requireUnsharded returns an error if sharding is configured, since
+// it is only supported when running a test executable.
+func requireUnsharded(s shard, mode string) error {
+	if s.count > 1 {
+		return invalidSetting(ENV_SHARD_COUNT, ShardCount, "is not supported when "+mode)
+	}
+	return nil
+}
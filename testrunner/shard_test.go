@@ -0,0 +1,154 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/turbinelabs/test/assert"
+	"github.com/turbinelabs/test/tempfile"
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+func TestGetShard(t *testing.T) {
+	savedIndex, savedCount := ShardIndex, ShardCount
+	defer func() {
+		ShardIndex, ShardCount = savedIndex, savedCount
+	}()
+
+	ShardIndex, ShardCount = "0", "1"
+	s, err := getShard()
+	assert.Nil(t, err)
+	assert.Equal(t, s, shard{index: 0, count: 1})
+
+	ShardIndex, ShardCount = "2", "3"
+	s, err = getShard()
+	assert.Nil(t, err)
+	assert.Equal(t, s, shard{index: 2, count: 3})
+
+	ShardIndex, ShardCount = "3", "3"
+	_, err = getShard()
+	assert.ErrorContains(t, err, "TEST_RUNNER_SHARD_INDEX=3 is not an integer between 0 and 2")
+
+	ShardIndex, ShardCount = "0", "0"
+	_, err = getShard()
+	assert.ErrorContains(t, err, "TEST_RUNNER_SHARD_COUNT=0 is not a positive integer")
+}
+
+func TestAssignShards(t *testing.T) {
+	tests := []string{"TestA", "TestB", "TestC", "TestD", "TestE"}
+	durations := map[string]float64{
+		"TestA": 5,
+		"TestB": 3,
+		"TestC": 3,
+		"TestD": 2,
+		// TestE takes the mean, 3.25
+	}
+
+	assert.DeepEqual(t, assignShards(tests, durations, 2), [][]string{
+		{"TestA", "TestC"},
+		{"TestB", "TestD", "TestE"},
+	})
+
+	// without history, tests are distributed evenly
+	assert.DeepEqual(t, assignShards(tests, nil, 3), [][]string{
+		{"TestA", "TestD"},
+		{"TestB", "TestE"},
+		{"TestC"},
+	})
+
+	assert.DeepEqual(t, assignShards(tests[:1], durations, 2), [][]string{{"TestA"}, nil})
+}
+
+func TestTestDurations(t *testing.T) {
+	dir := tempfile.TempDir(t, "testrunner-shard")
+	defer dir.Cleanup()
+
+	// no merged report
+	assert.MapEqual(t, testDurations(dir.Path(), "example.com/a"), map[string]float64{})
+
+	start := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	writeJunitReport(
+		t,
+		filepath.Join(dir.Path(), MergedReportFile),
+		&results.TestPackage{
+			Name:   "example.com/a",
+			Result: results.Passed,
+			Start:  start,
+			Tests: []*results.Test{
+				{
+					Name:     "TestA",
+					Result:   results.Passed,
+					Duration: 2,
+					Subtests: []*results.Test{
+						{Name: "TestA/sub", Result: results.Passed, Duration: 1},
+					},
+				},
+				{Name: "TestB", Result: results.Passed, Duration: 1},
+			},
+		},
+		&results.TestPackage{
+			Name:   "example.com/b",
+			Result: results.Passed,
+			Start:  start,
+			Tests:  []*results.Test{{Name: "TestC", Result: results.Passed, Duration: 3}},
+		},
+	)
+
+	// a later run of one shard's tests is ignored
+	writeJunitReport(
+		t,
+		filepath.Join(dir.Path(), "b.xml"),
+		&results.TestPackage{
+			Name:   "example.com/a",
+			Result: results.Passed,
+			Start:  start.Add(time.Hour),
+			Tests:  []*results.Test{{Name: "TestB", Result: results.Passed, Duration: 4}},
+		},
+	)
+
+	assert.MapEqual(t, testDurations(dir.Path(), "example.com/a"), map[string]float64{
+		"TestA": 2,
+		"TestB": 1,
+	})
+	assert.MapEqual(t, testDurations(dir.Path(), "example.com/c"), map[string]float64{})
+}
+
+func TestShardRunFlag(t *testing.T) {
+	assert.ArrayEqual(
+		t,
+		withoutFlags([]string{"-test.run", "X", "-test.v", "-test.run=Y", "-test.count=2"}, "-test.run"),
+		[]string{"-test.v", "-test.count=2"},
+	)
+	assert.Equal(t, runFlag([]string{"TestA", "TestB"}), "-test.run=^(TestA|TestB)$")
+}
+
+func TestListTests(t *testing.T) {
+	// the test executable is this one
+	tests, err := listTests(os.Args[0], []string{"-test.run", "^TestListTests$"})
+	assert.Nil(t, err)
+	assert.ArrayEqual(t, tests, []string{"TestListTests"})
+
+	_, err = listTests(os.Args[0], []string{"-test.run=("})
+	assert.ErrorContains(t, err, "listing tests in "+os.Args[0])
+
+	_, err = listTests(filepath.Join(os.TempDir(), "no-such-test-executable"), nil)
+	assert.ErrorContains(t, err, "no-such-test-executable")
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+
+	"github.com/turbinelabs/test/testrunner/parser"
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+// forwardedSignals are the signals testrunner forwards to the running
+// test command.
+var forwardedSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// interrupts forwards the signals testrunner receives to the process
+// group of the running test command, recording the first. A nil
+// *interrupts forwards nothing.
+type interrupts struct {
+	lock    sync.Mutex
+	process *os.Process    // the running test command, if any
+	stream  *parser.Stream // parsing its output
+	signal  os.Signal      // the first signal received; nil if none
+	running []string       // the top-level tests unfinished at the time
+}
+
+// handleInterrupts starts forwarding signals. Once it is called,
+// testrunner is no longer stopped by them: it writes its reports once
+// the test command exits.
+func handleInterrupts() *interrupts {
+	i := &interrupts{}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, forwardedSignals...)
+	go func() {
+		for sig := range signals {
+			i.interrupt(sig)
+		}
+	}()
+
+	return i
+}
+
+func (i *interrupts) interrupt(sig os.Signal) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if i.signal == nil {
+		i.signal = sig
+		if i.stream != nil {
+			i.running = unfinishedTests(i.stream.Progress())
+		}
+	}
+
+	if i.process != nil {
+		fmt.Fprintf(os.Stderr, "\n[received %s: forwarding to tests]\n", sig)
+		signalProcessGroup(i.process, sig)
+	}
+}
+
+// start records that the test command is running, with its output
+// parsed by the stream. If a signal was already received, it is
+// forwarded immediately.
+func (i *interrupts) start(process *os.Process, stream *parser.Stream) {
+	if i == nil {
+		return
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	i.process, i.stream = process, stream
+	if i.signal != nil {
+		signalProcessGroup(process, i.signal)
+	}
+}
+
+// stop records that the test command has exited.
+func (i *interrupts) stop() {
+	if i == nil {
+		return
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	i.process, i.stream = nil, nil
+}
+
+// received returns the first signal received, if any, and the
+// top-level tests running or paused when it was.
+func (i *interrupts) received() (os.Signal, []string) {
+	if i == nil {
+		return nil, nil
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	return i.signal, i.running
+}
+
+// unfinishedTests returns the names of the running and paused tests,
+// sorted.
+func unfinishedTests(p parser.Progress) []string {
+	names := append([]string{}, p.Paused...)
+	for name := range p.Running {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// markInterrupted marks the named top-level tests, which were
+// unfinished when testrunner was interrupted, as interrupted, unless
+// they went on to pass.
+func markInterrupted(pkgs []*results.TestPackage, names []string) {
+	running := map[string]bool{}
+	for _, name := range names {
+		running[name] = true
+	}
+
+	for _, pkg := range pkgs {
+		for _, t := range pkg.Tests {
+			if running[t.Name] && t.Result == results.Failed {
+				t.FailureKind = results.Interrupted
+			}
+		}
+	}
+}
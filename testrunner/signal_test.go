@@ -0,0 +1,93 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os/exec"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/turbinelabs/test/assert"
+	"github.com/turbinelabs/test/testrunner/parser"
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+func TestInterruptsForwardToProcessGroup(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("process groups are not supported on windows")
+	}
+
+	// the shell's child is in its process group, so it is signaled
+	// too, and the shell does not outlive it
+	cmd := exec.Command("sh", "-c", "sleep 30; exit 0")
+	setProcessGroup(cmd)
+	assert.Nil(t, cmd.Start())
+
+	stream := parser.NewGoStream("example.com/a")
+	stream.Write([]byte("=== RUN   TestA\n=== RUN   TestB\n=== PAUSE TestB\n"))
+
+	i := &interrupts{}
+	i.start(cmd.Process, stream)
+	i.interrupt(syscall.SIGTERM)
+	i.interrupt(syscall.SIGINT)
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		status, err := exitStatusOf(err)
+		assert.Nil(t, err)
+		assert.Equal(t, status, 128+int(syscall.SIGTERM))
+	case <-time.After(10 * time.Second):
+		cmd.Process.Kill()
+		t.Fatal("process group was not signaled")
+	}
+	i.stop()
+
+	// the first signal is the one recorded
+	sig, unfinished := i.received()
+	assert.Equal(t, sig, syscall.SIGTERM)
+	assert.ArrayEqual(t, unfinished, []string{"TestA", "TestB"})
+}
+
+func TestInterruptsNil(t *testing.T) {
+	var i *interrupts
+	i.start(nil, nil)
+	i.stop()
+
+	sig, unfinished := i.received()
+	assert.Nil(t, sig)
+	assert.Nil(t, unfinished)
+}
+
+func TestMarkInterrupted(t *testing.T) {
+	pkg := &results.TestPackage{
+		Tests: []*results.Test{
+			{Name: "TestA", Result: results.Failed},
+			{Name: "TestB", Result: results.Passed},
+			{Name: "TestC", Result: results.Failed},
+		},
+	}
+
+	markInterrupted([]*results.TestPackage{pkg}, []string{"TestA", "TestB"})
+	assert.Equal(t, pkg.Tests[0].FailureKind, results.Interrupted)
+	assert.Equal(t, pkg.Tests[1].Result, results.Passed)
+	assert.Equal(t, pkg.Tests[1].FailureKind, results.TestFailure)
+	assert.Equal(t, pkg.Tests[2].FailureKind, results.TestFailure)
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/turbinelabs/test/testrunner/parser"
+)
+
+// quitGracePeriod is how long a test executable has to write its
+// goroutine dump and exit after SIGQUIT before it is killed.
+const quitGracePeriod = 10 * time.Second
+
+type timeouts struct {
+	pkg  time.Duration // limit on the test executable's run time
+	test time.Duration // limit on any single test's run time
+}
+
+// getTimeouts returns the configured timeouts. A zero timeout is not
+// enforced.
+func getTimeouts() (timeouts, error) {
+	pkg, err := parseDuration(ENV_TIMEOUT, PackageTimeout)
+	if err != nil {
+		return timeouts{}, err
+	}
+
+	test, err := parseDuration(ENV_TEST_TIMEOUT, TestTimeout)
+	if err != nil {
+		return timeouts{}, err
+	}
+
+	return timeouts{pkg: pkg, test: test}, nil
+}
+
+// parseDuration parses the value of the named duration setting. An
+// empty value is zero.
+func parseDuration(name, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, invalidSetting(name, value, "is not a non-negative duration")
+	}
+	return d, nil
+}
+
+// enforceTimeouts sends SIGQUIT to the process if it runs longer
+// than the package timeout, or if, according to the stream parsing
+// its output, any test runs longer than the test timeout. A process
+// that does not exit within quitGracePeriod of SIGQUIT is killed. The
+// returned function must be called once the process has exited; it
+// returns a description of the timeout, if one was exceeded.
+func enforceTimeouts(
+	process *os.Process,
+	stream *parser.Stream,
+	limits timeouts,
+) func() string {
+	done := make(chan struct{})
+	result := make(chan string, 1)
+
+	go func() {
+		var deadline <-chan time.Time
+		if limits.pkg > 0 {
+			timer := time.NewTimer(limits.pkg)
+			defer timer.Stop()
+			deadline = timer.C
+		}
+
+		var check <-chan time.Time
+		if limits.test > 0 {
+			interval := limits.test / 10
+			if interval > time.Second {
+				interval = time.Second
+			} else if interval < time.Millisecond {
+				interval = time.Millisecond
+			}
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			check = ticker.C
+		}
+
+		reason := ""
+		for reason == "" {
+			select {
+			case <-done:
+				result <- ""
+				return
+
+			case <-deadline:
+				reason = fmt.Sprintf("test executable exceeded timeout of %s", limits.pkg)
+
+			case <-check:
+				if name, d := stream.Progress().Longest(); name != "" && d > limits.test {
+					reason = fmt.Sprintf("%s exceeded test timeout of %s", name, limits.test)
+				}
+			}
+		}
+
+		process.Signal(syscall.SIGQUIT)
+
+		select {
+		case <-done:
+		case <-time.After(quitGracePeriod):
+			process.Kill()
+			<-done
+		}
+		result <- reason
+	}()
+
+	return func() string {
+		close(done)
+		return <-result
+	}
+}
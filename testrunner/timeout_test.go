@@ -0,0 +1,97 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/turbinelabs/test/assert"
+	"github.com/turbinelabs/test/testrunner/parser"
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+// hangScript starts a test that hangs until SIGQUIT, upon which it
+// writes the start of a goroutine dump and exits, as a test
+// executable would.
+const hangScript = `
+trap 'kill $!; echo "SIGQUIT: quit"; echo "PC=0x0 m=0 sigcode=0"; exit 2' QUIT
+echo "=== RUN   TestOK"
+echo "--- PASS: TestOK (0.00s)"
+echo "=== RUN   TestHang"
+sleep 30 >/dev/null 2>&1 &
+wait
+`
+
+func TestEnforceTestTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SIGQUIT is not supported on windows")
+	}
+
+	stream := parser.NewGoStream("example.com/a")
+	start := time.Now()
+	run, err := runTest(
+		exec.Command("sh", "-c", hangScript),
+		nil,
+		timeouts{test: 100 * time.Millisecond},
+		display{status: true},
+		stream,
+		nil,
+	)
+	assert.Nil(t, err)
+	assert.LessThan(t, time.Since(start), 10*time.Second)
+	assert.Equal(t, run.timeout, "TestHang exceeded test timeout of 100ms")
+	assert.Equal(t, run.exitStatus, 2)
+
+	pkgs, err := stream.Finish(run.duration)
+	assert.Nil(t, err)
+	if assert.Equal(t, len(pkgs), 1) && assert.Equal(t, len(pkgs[0].Tests), 2) {
+		assert.Equal(t, pkgs[0].Tests[0].Result, results.Passed)
+		hang := pkgs[0].Tests[1]
+		assert.Equal(t, hang.Name, "TestHang")
+		assert.Equal(t, hang.Result, results.Failed)
+		assert.Equal(t, hang.FailureKind, results.Timeout)
+	}
+	assert.Equal(t, runExitStatus(run, pkgs), exitTimeout)
+}
+
+func TestEnforcePackageTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SIGQUIT is not supported on windows")
+	}
+
+	run, err := runTest(
+		exec.Command("sh", "-c", hangScript),
+		nil,
+		timeouts{pkg: 100 * time.Millisecond, test: time.Minute},
+		display{status: true},
+		parser.NewGoStream("example.com/a"),
+		nil,
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, run.timeout, "test executable exceeded timeout of 100ms")
+}
+
+func TestGoTestTimeoutFlag(t *testing.T) {
+	result := goTestTimeoutFlag([]string{"test", "./..."}, 90*time.Second)
+	assert.DeepEqual(t, result, []string{"test", "./...", "-timeout=1m30s"})
+
+	args := []string{"test", "-timeout", "5s", "./..."}
+	assert.DeepEqual(t, goTestTimeoutFlag(args, time.Minute), args)
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+// resourceUsage returns the resources used by an exited process that
+// ran for the given wall time.
+func resourceUsage(state *os.ProcessState, wall time.Duration) *results.ResourceUsage {
+	return &results.ResourceUsage{
+		UserTime:   state.UserTime().Seconds(),
+		SystemTime: state.SystemTime().Seconds(),
+		WallTime:   wall.Seconds(),
+		MaxRSS:     maxRSS(state),
+	}
+}
+
+// addUsage adds the resources used by another run of the package's
+// test executable (e.g. a retry) to the package's: times are summed
+// and the larger peak resident set size kept.
+func addUsage(pkg *results.TestPackage, usage *results.ResourceUsage) {
+	if pkg.Usage == nil {
+		pkg.Usage = usage
+		return
+	}
+
+	pkg.Usage.UserTime += usage.UserTime
+	pkg.Usage.SystemTime += usage.SystemTime
+	pkg.Usage.WallTime += usage.WallTime
+	if usage.MaxRSS > pkg.Usage.MaxRSS {
+		pkg.Usage.MaxRSS = usage.MaxRSS
+	}
+}
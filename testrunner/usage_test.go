@@ -0,0 +1,62 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/turbinelabs/test/assert"
+	"github.com/turbinelabs/test/testrunner/results"
+)
+
+func TestResourceUsage(t *testing.T) {
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	assert.Nil(t, cmd.Run())
+
+	usage := resourceUsage(cmd.ProcessState, 1500*time.Millisecond)
+	assert.Equal(t, usage.WallTime, 1.5)
+	assert.True(t, usage.UserTime >= 0)
+	assert.True(t, usage.SystemTime >= 0)
+	if runtime.GOOS == "linux" || runtime.GOOS == "darwin" {
+		// the test executable itself needs at least a megabyte
+		assert.True(t, usage.MaxRSS > 1<<20)
+	}
+}
+
+func TestAddUsage(t *testing.T) {
+	pkg := &results.TestPackage{}
+
+	first := &results.ResourceUsage{UserTime: 1, SystemTime: 0.5, WallTime: 2, MaxRSS: 2000}
+	addUsage(pkg, first)
+	assert.SameInstance(t, pkg.Usage, first)
+
+	addUsage(pkg, &results.ResourceUsage{UserTime: 0.5, SystemTime: 0.25, WallTime: 1, MaxRSS: 3000})
+	assert.DeepEqual(t, pkg.Usage, &results.ResourceUsage{
+		UserTime:   1.5,
+		SystemTime: 0.75,
+		WallTime:   3,
+		MaxRSS:     3000,
+	})
+
+	addUsage(pkg, &results.ResourceUsage{UserTime: 0.5, MaxRSS: 1000})
+	assert.Equal(t, pkg.Usage.UserTime, 2.0)
+	assert.Equal(t, pkg.Usage.MaxRSS, int64(3000))
+}